@@ -14,9 +14,28 @@ const (
 	defaultPollInterval   = 2
 	defaultReportInterval = 10
 	defaultSecretKey      = ""
+	defaultSecretKeyID    = ""
+	defaultHashVersion    = 2
 	defaultRateLimit      = 3
 	defaultPublicKeyPath  = ""
 	defaultConfigFilePath = ""
+	defaultInstance       = "" // hostname is used if empty
+	defaultTransport      = TransportHTTP
+	defaultCryptoMode     = CryptoModeTLS
+	defaultClientCertPath = ""
+	defaultClientKeyPath  = ""
+)
+
+// Transports of metrics to server.
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// Usages of PublicKeyPath.
+const (
+	CryptoModeTLS     = "tls"     // certificate is TLS root CA
+	CryptoModePayload = "payload" // key encrypts request bodies, certificate is TLS root CA too
 )
 
 type Config struct {
@@ -24,8 +43,15 @@ type Config struct {
 	PollInterval   int
 	ReportInterval int
 	SecretKey      string
+	SecretKeyID    string `json:"key_id"`       // ID of SecretKey in server keyring
+	HashVersion    int    `json:"hash_version"` // 1 signs body only, 2 signs method, path, query, body, timestamp and nonce
 	RateLimit      int
 	PublicKeyPath  string
+	Instance       string // value of "instance" label attached to every metric
+	Transport      string // TransportHTTP or TransportGRPC, Address is gRPC address of server for the latter
+	CryptoMode     string // CryptoModeTLS or CryptoModePayload
+	ClientCertPath string `json:"client_cert"` // certificate presented to server with mutual TLS
+	ClientKeyPath  string `json:"client_key"`
 }
 
 func parseConfigFile(config *Config) {
@@ -88,6 +114,14 @@ func parseEnvs(config *Config) {
 	if secretKey, ok := os.LookupEnv("KEY"); ok {
 		config.SecretKey = secretKey
 	}
+	if secretKeyID, ok := os.LookupEnv("KEY_ID"); ok {
+		config.SecretKeyID = secretKeyID
+	}
+	if hashVersion, ok := os.LookupEnv("HASH_VERSION"); ok {
+		if val, err := strconv.Atoi(hashVersion); err == nil {
+			config.HashVersion = val
+		}
+	}
 	if rateLimit, ok := os.LookupEnv("RATE_LIMIT"); ok {
 		if val, err := strconv.Atoi(rateLimit); err == nil {
 			config.RateLimit = val
@@ -96,6 +130,21 @@ func parseEnvs(config *Config) {
 	if publicKeyPath, ok := os.LookupEnv("CRYPTO_KEY"); ok {
 		config.PublicKeyPath = publicKeyPath
 	}
+	if instance, ok := os.LookupEnv("INSTANCE"); ok {
+		config.Instance = instance
+	}
+	if transport, ok := os.LookupEnv("TRANSPORT"); ok {
+		config.Transport = transport
+	}
+	if cryptoMode, ok := os.LookupEnv("CRYPTO_MODE"); ok {
+		config.CryptoMode = cryptoMode
+	}
+	if clientCertPath, ok := os.LookupEnv("CLIENT_CERT"); ok {
+		config.ClientCertPath = clientCertPath
+	}
+	if clientKeyPath, ok := os.LookupEnv("CLIENT_KEY"); ok {
+		config.ClientKeyPath = clientKeyPath
+	}
 }
 
 func parseFlags(config *Config) {
@@ -103,17 +152,32 @@ func parseFlags(config *Config) {
 	pollIntervalFlag := flag.Int("p", config.PollInterval, fmt.Sprintf("Poll interval in seconds (default: %d)", defaultPollInterval))
 	reportIntervalFlag := flag.Int("r", config.ReportInterval, fmt.Sprintf("Report interval in seconds (default: %d)", defaultReportInterval))
 	secretKeyFlag := flag.String("k", config.SecretKey, fmt.Sprintf("Secret key (default: %s)", defaultSecretKey))
+	secretKeyIDFlag := flag.String("key-id", config.SecretKeyID, fmt.Sprintf("ID of secret key in server keyring (default: %s)", defaultSecretKeyID))
+	hashVersionFlag := flag.Int("hash-version", config.HashVersion, fmt.Sprintf("Version of request signatures, 1 or 2 (default: %d)", defaultHashVersion))
 	rateLimitFlag := flag.Int("l", config.RateLimit, fmt.Sprintf("Rate limit key (default: %d)", defaultRateLimit))
 	publicKeyPath := flag.String("crypto-key", config.PublicKeyPath, fmt.Sprintf("Public key path (default: %s)", defaultPublicKeyPath))
 
+	instanceFlag := flag.String("instance", config.Instance, "Instance label of metrics (default: hostname)")
+	transportFlag := flag.String("transport", config.Transport, fmt.Sprintf("Transport to server, http or grpc (default: %s)", defaultTransport))
+	cryptoModeFlag := flag.String("crypto-mode", config.CryptoMode, fmt.Sprintf("Usage of crypto key, tls or payload (default: %s)", defaultCryptoMode))
+	clientCertPathFlag := flag.String("client-cert", config.ClientCertPath, fmt.Sprintf("Client certificate path for mutual TLS (default: %s)", defaultClientCertPath))
+	clientKeyPathFlag := flag.String("client-key", config.ClientKeyPath, fmt.Sprintf("Client private key path for mutual TLS (default: %s)", defaultClientKeyPath))
+
 	flag.Parse()
 
 	config.Address = *addressFlag
 	config.PollInterval = *pollIntervalFlag
 	config.ReportInterval = *reportIntervalFlag
 	config.SecretKey = *secretKeyFlag
+	config.SecretKeyID = *secretKeyIDFlag
+	config.HashVersion = *hashVersionFlag
 	config.RateLimit = *rateLimitFlag
 	config.PublicKeyPath = *publicKeyPath
+	config.Instance = *instanceFlag
+	config.Transport = *transportFlag
+	config.CryptoMode = *cryptoModeFlag
+	config.ClientCertPath = *clientCertPathFlag
+	config.ClientKeyPath = *clientKeyPathFlag
 }
 
 func getConfig() Config {
@@ -122,8 +186,15 @@ func getConfig() Config {
 		PollInterval:   defaultPollInterval,
 		ReportInterval: defaultReportInterval,
 		SecretKey:      defaultSecretKey,
+		SecretKeyID:    defaultSecretKeyID,
+		HashVersion:    defaultHashVersion,
 		RateLimit:      defaultRateLimit,
 		PublicKeyPath:  defaultPublicKeyPath,
+		Instance:       defaultInstance,
+		Transport:      defaultTransport,
+		CryptoMode:     defaultCryptoMode,
+		ClientCertPath: defaultClientCertPath,
+		ClientKeyPath:  defaultClientKeyPath,
 	}
 
 	parseConfigFile(&config)
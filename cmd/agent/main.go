@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"log"
@@ -10,9 +11,14 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/VOTONO/go-metrics/internal/agent/helpers"
 	"github.com/VOTONO/go-metrics/internal/agent/workers"
+	"github.com/VOTONO/go-metrics/internal/encryption"
+	pb "github.com/VOTONO/go-metrics/internal/proto"
 )
 
 var (
@@ -42,7 +48,14 @@ func main() {
 		"PollInterval", config.PollInterval,
 		"ReportInterval", config.ReportInterval,
 		"SecretKey", config.SecretKey,
+		"SecretKeyID", config.SecretKeyID,
+		"HashVersion", config.HashVersion,
 		"PublicKeyPath", config.PublicKeyPath,
+		"Instance", config.Instance,
+		"Transport", config.Transport,
+		"CryptoMode", config.CryptoMode,
+		"ClientCertPath", config.ClientCertPath,
+		"ClientKeyPath", config.ClientKeyPath,
 	)
 
 	stopChannel := helpers.CreateSystemStopChannel()
@@ -55,6 +68,8 @@ func main() {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
+	var tlsConfig *tls.Config
+	var payloadKey *rsa.PublicKey
 
 	// If public key provided, add TLS to client
 	if config.PublicKeyPath != "" {
@@ -66,16 +81,49 @@ func main() {
 		// Create a certificate pool and add the server's certificate
 		certPool := x509.NewCertPool()
 		certAdded := certPool.AppendCertsFromPEM(serverCert)
-		if !certAdded {
+		if certAdded {
+			// Configure the TLS settings for the client
+			tlsConfig = &tls.Config{
+				RootCAs: certPool,
+			}
+		} else if config.CryptoMode == CryptoModeTLS {
 			sugaredLogger.Fatal("failed to append server certificate to cert pool")
 		}
 
-		// Configure the TLS settings for the client
-		tlsConfig := &tls.Config{
-			RootCAs: certPool,
+		if config.CryptoMode == CryptoModePayload {
+			payloadKey, err = encryption.ParsePublicKey(serverCert)
+			if err != nil {
+				sugaredLogger.Fatalf("failed to parse public key: %v", err)
+			}
 		}
+	}
+
+	// If client certificate provided, present it to server with mutual TLS
+	if config.ClientCertPath != "" {
+		clientCert, err := tls.LoadX509KeyPair(config.ClientCertPath, config.ClientKeyPath)
+		if err != nil {
+			sugaredLogger.Fatalf("failed to load client certificate: %v", err)
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{} // server certificate is verified with system roots
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
 
+	grpcCredentials := insecure.NewCredentials()
+	if tlsConfig != nil {
 		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		grpcCredentials = credentials.NewTLS(tlsConfig)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		sugaredLogger.Errorw("failed to get hostname", "error", err)
+		host = "unknown"
+	}
+	instance := config.Instance
+	if instance == "" {
+		instance = host
 	}
 
 	sendWorker := workers.NewSendWorker(
@@ -86,7 +134,32 @@ func main() {
 		config.RateLimit,
 		config.Address,
 		config.SecretKey,
+		map[string]string{"host": host, "instance": instance},
 	)
+	if payloadKey != nil {
+		sendWorker.UseEncryption(payloadKey)
+	}
+	sendWorker.UseKeyID(config.SecretKeyID)
+	switch config.HashVersion {
+	case 1:
+		sendWorker.UseHashV1()
+	case 2:
+	default:
+		sugaredLogger.Fatalf("unknown hash version %d", config.HashVersion)
+	}
+
+	switch config.Transport {
+	case TransportHTTP:
+	case TransportGRPC:
+		conn, err := grpc.NewClient(config.Address, grpc.WithTransportCredentials(grpcCredentials))
+		if err != nil {
+			sugaredLogger.Fatalf("failed to create gRPC client: %v", err)
+		}
+		defer conn.Close()
+		sendWorker.UseGRPC(pb.NewMetricsClient(conn))
+	default:
+		sugaredLogger.Fatalf("unknown transport %q", config.Transport)
+	}
 
 	go func() {
 		err := http.ListenAndServe(":9191", nil)
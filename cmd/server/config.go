@@ -7,31 +7,118 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/VOTONO/go-metrics/internal/config"
+	"github.com/VOTONO/go-metrics/internal/mtls"
+	"github.com/VOTONO/go-metrics/internal/server/repo/logstore"
+	"github.com/VOTONO/go-metrics/internal/subnet"
 )
 
 const (
-	defaultAddress         = "localhost:8080"
-	defaultDSN             = ""
-	defaultStoreInterval   = 300
-	defaultFileStoragePath = "/tmp/metrics-db.json"
-	defaultRestore         = true
-	defaultSecretKey       = ""
-	defaultEnableHTTPS     = false
-	defaultPublicKeyPath   = ""
-	defaultPrivateKeyPath  = ""
-	defaultConfigFilePath  = ""
+	defaultAddress             = "localhost:8080"
+	defaultDSN                 = ""
+	defaultStoreInterval       = 300
+	defaultFileStoragePath     = "/tmp/metrics-db.json"
+	defaultRestore             = true
+	defaultSecretKey           = ""
+	defaultEnableHTTPS         = false
+	defaultPublicKeyPath       = ""
+	defaultPrivateKeyPath      = ""
+	defaultConfigFilePath      = ""
+	defaultAlertRulesPath      = ""
+	defaultAlertInterval       = 10
+	defaultReceiversPath       = ""
+	defaultHistorySize         = 1000
+	defaultRollupInterval      = 60
+	defaultRetentionRaw        = 24 * time.Hour
+	defaultRetentionMinute     = 7 * 24 * time.Hour
+	defaultRetentionHour       = 90 * 24 * time.Hour
+	defaultStatsdAddress       = ""
+	defaultStatsdFlushInterval = 10
+	defaultGraphiteAddress     = ""
+	defaultGRPCAddress         = ""
+	defaultTrustedSubnet       = ""
+	defaultTrustedIPSource     = subnet.SourceHeader
+	defaultClientCAPath        = ""
+	defaultClientAuth          = mtls.ClientAuthRequire
+	defaultKeyringPath         = ""
+	defaultHashMaxSkew         = 5 * time.Minute
+	defaultHashNonceCacheSize  = 100_000
+	defaultHashMaxBodySize     = 32 << 20
+	defaultAcceptHashV1        = true // deprecated, will be false in next release
+	defaultStrictAuth          = false
+	defaultWAL                 = false
+	defaultWALCompactInterval  = 5 * time.Minute
+	defaultSnapshotKeep        = 3
 )
 
 type Config struct {
-	Address         string
-	DSN             string
-	StoreInterval   int
-	FileStoragePath string
-	Restore         bool
-	SecretKey       string
-	EnableHTTPS     bool
-	PublicKeyPath   string
-	PrivateKeyPath  string
+	Address             string
+	DSN                 string
+	StoreInterval       int
+	FileStoragePath     string
+	Restore             bool
+	SecretKey           string
+	EnableHTTPS         bool
+	PublicKeyPath       string
+	PrivateKeyPath      string
+	AlertRulesPath      string
+	AlertInterval       int
+	ReceiversPath       string
+	HistorySize         int
+	RollupInterval      int
+	RetentionRaw        time.Duration
+	RetentionMinute     time.Duration
+	RetentionHour       time.Duration
+	StatsdAddress       string
+	StatsdFlushInterval int
+	GraphiteAddress     string
+	GRPCAddress         string
+	TrustedSubnet       string        `json:"trusted_subnet"` // comma separated CIDRs
+	TrustedIPSource     string        `json:"trusted_ip_source"`
+	ClientCAPath        string        `json:"client_ca"`
+	ClientAuth          string        `json:"client_auth"`
+	KeyringPath         string        `json:"keyring"`
+	HashMaxSkew         time.Duration `json:"hash_max_skew"`
+	HashNonceCacheSize  int           `json:"hash_nonce_cache"`
+	HashMaxBodySize     int64         `json:"hash_max_body"`
+	AcceptHashV1        bool          `json:"hash_v1"`
+	StrictAuth          bool          `json:"strict_auth"`
+	WAL                 bool          `json:"wal"`
+	WALCompactInterval  time.Duration `json:"wal_compact_interval"`
+	SnapshotKeep        int           `json:"snapshot_keep"`
+}
+
+// UnmarshalJSON decodes config file. Durations are strings like "5m" or numbers of seconds,
+// fields missing in file keep their values.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plainConfig Config
+	file := struct {
+		*plainConfig
+		RetentionRaw       config.Duration
+		RetentionMinute    config.Duration
+		RetentionHour      config.Duration
+		HashMaxSkew        config.Duration `json:"hash_max_skew"`
+		WALCompactInterval config.Duration `json:"wal_compact_interval"`
+	}{
+		plainConfig:        (*plainConfig)(c),
+		RetentionRaw:       config.Duration(c.RetentionRaw),
+		RetentionMinute:    config.Duration(c.RetentionMinute),
+		RetentionHour:      config.Duration(c.RetentionHour),
+		HashMaxSkew:        config.Duration(c.HashMaxSkew),
+		WALCompactInterval: config.Duration(c.WALCompactInterval),
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	c.RetentionRaw = time.Duration(file.RetentionRaw)
+	c.RetentionMinute = time.Duration(file.RetentionMinute)
+	c.RetentionHour = time.Duration(file.RetentionHour)
+	c.HashMaxSkew = time.Duration(file.HashMaxSkew)
+	c.WALCompactInterval = time.Duration(file.WALCompactInterval)
+	return nil
 }
 
 func parseEnvs(config *Config) {
@@ -68,11 +155,116 @@ func parseEnvs(config *Config) {
 	if privateKeyPath, ok := os.LookupEnv("CRYPTO_KEY"); ok {
 		config.PrivateKeyPath = privateKeyPath
 	}
+	if alertRulesPath, ok := os.LookupEnv("ALERT_RULES"); ok {
+		config.AlertRulesPath = alertRulesPath
+	}
+	if alertInterval, ok := os.LookupEnv("ALERT_INTERVAL"); ok {
+		if i, err := strconv.Atoi(alertInterval); err == nil {
+			config.AlertInterval = i
+		}
+	}
+	if receiversPath, ok := os.LookupEnv("ALERT_RECEIVERS"); ok {
+		config.ReceiversPath = receiversPath
+	}
+	if historySize, ok := os.LookupEnv("HISTORY_SIZE"); ok {
+		if i, err := strconv.Atoi(historySize); err == nil {
+			config.HistorySize = i
+		}
+	}
+	if rollupInterval, ok := os.LookupEnv("ROLLUP_INTERVAL"); ok {
+		if i, err := strconv.Atoi(rollupInterval); err == nil {
+			config.RollupInterval = i
+		}
+	}
+	if retentionRaw, ok := os.LookupEnv("RETENTION_RAW"); ok {
+		if d, err := time.ParseDuration(retentionRaw); err == nil {
+			config.RetentionRaw = d
+		}
+	}
+	if retentionMinute, ok := os.LookupEnv("RETENTION_1M"); ok {
+		if d, err := time.ParseDuration(retentionMinute); err == nil {
+			config.RetentionMinute = d
+		}
+	}
+	if retentionHour, ok := os.LookupEnv("RETENTION_1H"); ok {
+		if d, err := time.ParseDuration(retentionHour); err == nil {
+			config.RetentionHour = d
+		}
+	}
+	if statsdAddress, ok := os.LookupEnv("STATSD_ADDRESS"); ok {
+		config.StatsdAddress = statsdAddress
+	}
+	if statsdFlushInterval, ok := os.LookupEnv("STATSD_FLUSH_INTERVAL"); ok {
+		if i, err := strconv.Atoi(statsdFlushInterval); err == nil {
+			config.StatsdFlushInterval = i
+		}
+	}
+	if graphiteAddress, ok := os.LookupEnv("GRAPHITE_ADDRESS"); ok {
+		config.GraphiteAddress = graphiteAddress
+	}
+	if grpcAddress, ok := os.LookupEnv("GRPC_ADDRESS"); ok {
+		config.GRPCAddress = grpcAddress
+	}
+	if trustedSubnet, ok := os.LookupEnv("TRUSTED_SUBNET"); ok {
+		config.TrustedSubnet = trustedSubnet
+	}
+	if trustedIPSource, ok := os.LookupEnv("TRUSTED_IP_SOURCE"); ok {
+		config.TrustedIPSource = trustedIPSource
+	}
+	if clientCAPath, ok := os.LookupEnv("CLIENT_CA"); ok {
+		config.ClientCAPath = clientCAPath
+	}
+	if clientAuth, ok := os.LookupEnv("CLIENT_AUTH"); ok {
+		config.ClientAuth = clientAuth
+	}
+	if keyringPath, ok := os.LookupEnv("KEYRING"); ok {
+		config.KeyringPath = keyringPath
+	}
+	if hashMaxSkew, ok := os.LookupEnv("HASH_MAX_SKEW"); ok {
+		if d, err := time.ParseDuration(hashMaxSkew); err == nil {
+			config.HashMaxSkew = d
+		}
+	}
+	if hashNonceCacheSize, ok := os.LookupEnv("HASH_NONCE_CACHE"); ok {
+		if i, err := strconv.Atoi(hashNonceCacheSize); err == nil {
+			config.HashNonceCacheSize = i
+		}
+	}
+	if hashMaxBodySize, ok := os.LookupEnv("HASH_MAX_BODY"); ok {
+		if i, err := strconv.ParseInt(hashMaxBodySize, 10, 64); err == nil {
+			config.HashMaxBodySize = i
+		}
+	}
+	if acceptHashV1, ok := os.LookupEnv("HASH_V1"); ok {
+		if b, err := strconv.ParseBool(acceptHashV1); err == nil {
+			config.AcceptHashV1 = b
+		}
+	}
+	if strictAuth, ok := os.LookupEnv("STRICT_AUTH"); ok {
+		if b, err := strconv.ParseBool(strictAuth); err == nil {
+			config.StrictAuth = b
+		}
+	}
+	if wal, ok := os.LookupEnv("WAL"); ok {
+		if b, err := strconv.ParseBool(wal); err == nil {
+			config.WAL = b
+		}
+	}
+	if walCompactInterval, ok := os.LookupEnv("WAL_COMPACT_INTERVAL"); ok {
+		if d, err := time.ParseDuration(walCompactInterval); err == nil {
+			config.WALCompactInterval = d
+		}
+	}
+	if snapshotKeep, ok := os.LookupEnv("SNAPSHOT_KEEP"); ok {
+		if i, err := strconv.Atoi(snapshotKeep); err == nil {
+			config.SnapshotKeep = i
+		}
+	}
 }
 
 func parseFlags(config *Config) {
 	addressFlag := flag.String("a", config.Address, fmt.Sprintf("Address to bind to (default: %s)", defaultAddress))
-	dbAddressFlag := flag.String("d", config.DSN, fmt.Sprintf("Address to bind db (default: %s)", defaultDSN))
+	dbAddressFlag := flag.String("d", config.DSN, fmt.Sprintf("Postgres DSN, or %s://dir for embedded log store (default: %s)", logstore.Scheme, defaultDSN))
 	storeIntervalFlag := flag.Int("i", config.StoreInterval, fmt.Sprintf("Store interval in seconds (default: %d)", defaultStoreInterval))
 	fileStoragePathFlag := flag.String("f", config.FileStoragePath, fmt.Sprintf("File storage path (default: %s)", defaultFileStoragePath))
 	restoreFlag := flag.Bool("r", config.Restore, fmt.Sprintf("Restore from file storage (default: %t)", defaultRestore))
@@ -80,6 +272,31 @@ func parseFlags(config *Config) {
 	enableHTTPSFlag := flag.Bool("s", config.EnableHTTPS, fmt.Sprintf("Enable HTTPS support (default: %t)", defaultEnableHTTPS))
 	publicKeyPathFlag := flag.String("public-crypto-key", config.PublicKeyPath, fmt.Sprintf("Public key path (default: %s)", defaultPublicKeyPath))
 	privateKeyPathFlag := flag.String("crypto-key", config.PrivateKeyPath, fmt.Sprintf("Private key path (default: %s)", defaultPrivateKeyPath))
+	alertRulesPathFlag := flag.String("alert-rules", config.AlertRulesPath, fmt.Sprintf("Alert rules file path (default: %s)", defaultAlertRulesPath))
+	alertIntervalFlag := flag.Int("alert-interval", config.AlertInterval, fmt.Sprintf("Alert rules evaluation interval in seconds (default: %d)", defaultAlertInterval))
+	receiversPathFlag := flag.String("alert-receivers", config.ReceiversPath, fmt.Sprintf("Alert receivers file path (default: %s)", defaultReceiversPath))
+	historySizeFlag := flag.Int("history-size", config.HistorySize, fmt.Sprintf("Number of samples kept in memory per metric, 0 disables history (default: %d)", defaultHistorySize))
+	rollupIntervalFlag := flag.Int("rollup-interval", config.RollupInterval, fmt.Sprintf("History rollup interval in seconds (default: %d)", defaultRollupInterval))
+	retentionRawFlag := flag.Duration("retention-raw", config.RetentionRaw, fmt.Sprintf("Retention of raw history samples (default: %s)", defaultRetentionRaw))
+	retentionMinuteFlag := flag.Duration("retention-1m", config.RetentionMinute, fmt.Sprintf("Retention of 1-minute history buckets (default: %s)", defaultRetentionMinute))
+	retentionHourFlag := flag.Duration("retention-1h", config.RetentionHour, fmt.Sprintf("Retention of 1-hour history buckets (default: %s)", defaultRetentionHour))
+	statsdAddressFlag := flag.String("statsd-address", config.StatsdAddress, fmt.Sprintf("StatsD UDP listen address, empty disables listener (default: %s)", defaultStatsdAddress))
+	statsdFlushIntervalFlag := flag.Int("statsd-flush-interval", config.StatsdFlushInterval, fmt.Sprintf("StatsD aggregation window in seconds (default: %d)", defaultStatsdFlushInterval))
+	graphiteAddressFlag := flag.String("graphite-address", config.GraphiteAddress, fmt.Sprintf("Graphite plaintext TCP listen address, empty disables listener (default: %s)", defaultGraphiteAddress))
+	grpcAddressFlag := flag.String("grpc-address", config.GRPCAddress, fmt.Sprintf("gRPC listen address, empty disables gRPC server (default: %s)", defaultGRPCAddress))
+	trustedSubnetFlag := flag.String("t", config.TrustedSubnet, fmt.Sprintf("Trusted subnets of agents, comma separated IPv4 and IPv6 CIDRs, empty allows any agent (default: %s)", defaultTrustedSubnet))
+	trustedIPSourceFlag := flag.String("trusted-ip-source", config.TrustedIPSource, fmt.Sprintf("Source of agent IP for trusted subnet check, header (X-Real-IP) or peer (default: %s)", defaultTrustedIPSource))
+	clientCAPathFlag := flag.String("client-ca", config.ClientCAPath, fmt.Sprintf("Path to PEM bundle of CAs that sign agent certificates, enables mutual TLS with HTTPS (default: %s)", defaultClientCAPath))
+	clientAuthFlag := flag.String("client-auth", config.ClientAuth, fmt.Sprintf("Verification of agent certificates with mutual TLS: require, or verify only if presented (default: %s)", defaultClientAuth))
+	keyringPathFlag := flag.String("keyring", config.KeyringPath, fmt.Sprintf("Path to JSON keyring of HMAC keys with IDs and validity periods, reloaded on SIGHUP (default: %s)", defaultKeyringPath))
+	hashMaxSkewFlag := flag.Duration("hash-max-skew", config.HashMaxSkew, fmt.Sprintf("Allowed clock skew of signed requests (default: %s)", defaultHashMaxSkew))
+	hashNonceCacheSizeFlag := flag.Int("hash-nonce-cache", config.HashNonceCacheSize, fmt.Sprintf("Number of remembered nonces of signed requests (default: %d)", defaultHashNonceCacheSize))
+	hashMaxBodySizeFlag := flag.Int64("hash-max-body", config.HashMaxBodySize, fmt.Sprintf("Limit of request body in bytes, larger requests are rejected with 413, 0 disables limit (default: %d)", defaultHashMaxBodySize))
+	acceptHashV1Flag := flag.Bool("hash-v1", config.AcceptHashV1, fmt.Sprintf("Accept body-only signatures (version 1) without replay protection, deprecated: default will change to false in next release (default: %t)", defaultAcceptHashV1))
+	strictAuthFlag := flag.Bool("strict-auth", config.StrictAuth, fmt.Sprintf("Reject unsigned writes, requires secret key or keyring (default: %t)", defaultStrictAuth))
+	walFlag := flag.Bool("wal", config.WAL, fmt.Sprintf("Store metrics in file with write-ahead log, every update is durable and store interval is ignored (default: %t)", defaultWAL))
+	walCompactIntervalFlag := flag.Duration("wal-compact-interval", config.WALCompactInterval, fmt.Sprintf("Interval of writing snapshot and removing write-ahead log behind it (default: %s)", defaultWALCompactInterval))
+	snapshotKeepFlag := flag.Int("snapshot-keep", config.SnapshotKeep, fmt.Sprintf("Number of kept metric snapshots, older ones are used if newer are damaged (default: %d)", defaultSnapshotKeep))
 
 	flag.Parse()
 
@@ -92,6 +309,31 @@ func parseFlags(config *Config) {
 	config.EnableHTTPS = *enableHTTPSFlag
 	config.PublicKeyPath = *publicKeyPathFlag
 	config.PrivateKeyPath = *privateKeyPathFlag
+	config.AlertRulesPath = *alertRulesPathFlag
+	config.AlertInterval = *alertIntervalFlag
+	config.ReceiversPath = *receiversPathFlag
+	config.HistorySize = *historySizeFlag
+	config.RollupInterval = *rollupIntervalFlag
+	config.RetentionRaw = *retentionRawFlag
+	config.RetentionMinute = *retentionMinuteFlag
+	config.RetentionHour = *retentionHourFlag
+	config.StatsdAddress = *statsdAddressFlag
+	config.StatsdFlushInterval = *statsdFlushIntervalFlag
+	config.GraphiteAddress = *graphiteAddressFlag
+	config.GRPCAddress = *grpcAddressFlag
+	config.TrustedSubnet = *trustedSubnetFlag
+	config.TrustedIPSource = *trustedIPSourceFlag
+	config.ClientCAPath = *clientCAPathFlag
+	config.ClientAuth = *clientAuthFlag
+	config.KeyringPath = *keyringPathFlag
+	config.HashMaxSkew = *hashMaxSkewFlag
+	config.HashNonceCacheSize = *hashNonceCacheSizeFlag
+	config.HashMaxBodySize = *hashMaxBodySizeFlag
+	config.AcceptHashV1 = *acceptHashV1Flag
+	config.StrictAuth = *strictAuthFlag
+	config.WAL = *walFlag
+	config.WALCompactInterval = *walCompactIntervalFlag
+	config.SnapshotKeep = *snapshotKeepFlag
 }
 
 func parseConfigFile(config *Config) {
@@ -139,15 +381,40 @@ func parseConfigFile(config *Config) {
 
 func getConfig() Config {
 	config := Config{
-		Address:         defaultAddress,
-		DSN:             defaultDSN,
-		StoreInterval:   defaultStoreInterval,
-		FileStoragePath: defaultFileStoragePath,
-		Restore:         defaultRestore,
-		SecretKey:       defaultSecretKey,
-		EnableHTTPS:     defaultEnableHTTPS,
-		PublicKeyPath:   defaultPublicKeyPath,
-		PrivateKeyPath:  defaultPrivateKeyPath,
+		Address:             defaultAddress,
+		DSN:                 defaultDSN,
+		StoreInterval:       defaultStoreInterval,
+		FileStoragePath:     defaultFileStoragePath,
+		Restore:             defaultRestore,
+		SecretKey:           defaultSecretKey,
+		EnableHTTPS:         defaultEnableHTTPS,
+		PublicKeyPath:       defaultPublicKeyPath,
+		PrivateKeyPath:      defaultPrivateKeyPath,
+		AlertRulesPath:      defaultAlertRulesPath,
+		AlertInterval:       defaultAlertInterval,
+		ReceiversPath:       defaultReceiversPath,
+		HistorySize:         defaultHistorySize,
+		RollupInterval:      defaultRollupInterval,
+		RetentionRaw:        defaultRetentionRaw,
+		RetentionMinute:     defaultRetentionMinute,
+		RetentionHour:       defaultRetentionHour,
+		StatsdAddress:       defaultStatsdAddress,
+		StatsdFlushInterval: defaultStatsdFlushInterval,
+		GraphiteAddress:     defaultGraphiteAddress,
+		GRPCAddress:         defaultGRPCAddress,
+		TrustedSubnet:       defaultTrustedSubnet,
+		TrustedIPSource:     defaultTrustedIPSource,
+		ClientCAPath:        defaultClientCAPath,
+		ClientAuth:          defaultClientAuth,
+		KeyringPath:         defaultKeyringPath,
+		HashMaxSkew:         defaultHashMaxSkew,
+		HashNonceCacheSize:  defaultHashNonceCacheSize,
+		HashMaxBodySize:     defaultHashMaxBodySize,
+		AcceptHashV1:        defaultAcceptHashV1,
+		StrictAuth:          defaultStrictAuth,
+		WAL:                 defaultWAL,
+		WALCompactInterval:  defaultWALCompactInterval,
+		SnapshotKeep:        defaultSnapshotKeep,
 	}
 
 	parseConfigFile(&config)
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoadEnvConfig(t *testing.T) {
@@ -140,3 +142,30 @@ func TestGetConfig(t *testing.T) {
 		t.Errorf("Expected config: %+v, got: %+v", expected, config)
 	}
 }
+
+func TestConfigUnmarshalJSON(t *testing.T) {
+	config := Config{
+		Address:      defaultAddress,
+		RetentionRaw: defaultRetentionRaw,
+	}
+	data := []byte(`{"Address": "127.0.0.1:9090", "RetentionMinute": 3600, "RetentionHour": "720h", "hash_max_skew": "30s", "wal_compact_interval": "10m"}`)
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := Config{
+		Address:            "127.0.0.1:9090",
+		RetentionRaw:       defaultRetentionRaw,
+		RetentionMinute:    time.Hour,
+		RetentionHour:      720 * time.Hour,
+		HashMaxSkew:        30 * time.Second,
+		WALCompactInterval: 10 * time.Minute,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected config %v, got %v", expected, config)
+	}
+
+	if err := json.Unmarshal([]byte(`{"RetentionRaw": "soon"}`), &config); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
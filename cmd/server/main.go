@@ -3,19 +3,39 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"errors"
+	"flag"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/VOTONO/go-metrics/internal/agent/helpers"
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/encryption"
+	"github.com/VOTONO/go-metrics/internal/mtls"
+	"github.com/VOTONO/go-metrics/internal/server/alerting"
+	"github.com/VOTONO/go-metrics/internal/server/grpcserver"
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
+	"github.com/VOTONO/go-metrics/internal/server/notifier"
 	"github.com/VOTONO/go-metrics/internal/server/repo"
+	"github.com/VOTONO/go-metrics/internal/server/repo/logstore"
 	"github.com/VOTONO/go-metrics/internal/server/router"
+	"github.com/VOTONO/go-metrics/internal/server/statsd"
+	"github.com/VOTONO/go-metrics/internal/server/tcpreceiver"
+	"github.com/VOTONO/go-metrics/internal/subnet"
 )
 
 var (
@@ -34,12 +54,76 @@ func main() {
 	zapLogger := *logger.Sugar()
 	config := getConfig()
 
-	storer, db, err := createStorer(&zapLogger, config)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(&zapLogger, config, flag.Args()[1:]); err != nil {
+			log.Fatalf("migration failed: %v", err)
+		}
+		return
+	}
+
+	if config.WAL && !config.Restore {
+		log.Fatal("WAL mode always restores metrics, it can't be used with restore disabled")
+	}
+
+	storageCtx, stopStorage := context.WithCancel(context.Background())
+	storer, db, storageCloser, err := createStorer(storageCtx, &zapLogger, config)
 	if err != nil {
 		log.Fatalf("can't initialize metric storer: %v", err)
 	}
-	defer db.Close()
-	rout := router.Router(storer, db, &zapLogger, config.SecretKey)
+
+	trustedSubnet, err := subnet.NewChecker(config.TrustedSubnet, config.TrustedIPSource)
+	if err != nil {
+		log.Fatalf("invalid trusted subnet: %v", err)
+	}
+
+	keyring := loadKeyring(&zapLogger, config)
+	if config.StrictAuth && keyring.Empty() {
+		log.Fatal("strict authentication requires secret key or keyring")
+	}
+
+	if config.AcceptHashV1 && !keyring.Empty() {
+		zapLogger.Warnw("Accepting version 1 signatures is deprecated, they will be rejected by default in next release; switch agents to version 2 and set -hash-v1=false")
+	}
+
+	// HTTP and gRPC share nonce cache, so request can't be replayed over the other transport.
+	signatureConfig := auth.SignatureConfig{
+		Strict:         config.StrictAuth,
+		MaxSkew:        config.HashMaxSkew,
+		NonceCacheSize: config.HashNonceCacheSize,
+		AcceptV1:       config.AcceptHashV1,
+		MaxBodySize:    config.HashMaxBodySize,
+	}.WithNonceCache()
+
+	routerOptions := []router.Option{
+		router.WithTrustedSubnet(trustedSubnet),
+		router.WithKeyring(keyring),
+		router.WithSignatureConfig(signatureConfig),
+	}
+	if config.PrivateKeyPath != "" {
+		routerOptions = append(routerOptions, decryptionOption(&zapLogger, config))
+	}
+	if config.AlertRulesPath != "" {
+		rules, err := alerting.LoadRules(config.AlertRulesPath)
+		if err != nil {
+			log.Fatalf("can't load alert rules: %v", err)
+		}
+		engine := alerting.NewEngine(storer, rules, &zapLogger, config.AlertInterval)
+		if config.ReceiversPath != "" {
+			receivers, err := notifier.LoadReceivers(config.ReceiversPath)
+			if err != nil {
+				log.Fatalf("can't load alert receivers: %v", err)
+			}
+			dispatcher, err := notifier.NewDispatcher(receivers, &zapLogger)
+			if err != nil {
+				log.Fatalf("can't initialize alert receivers: %v", err)
+			}
+			engine.SetNotifier(dispatcher)
+		}
+		engine.Start(context.Background())
+		routerOptions = append(routerOptions, router.WithAlerts(engine))
+	}
+
+	rout := router.Router(storer, db, &zapLogger, config.SecretKey, routerOptions...)
 
 	zapLogger.Infow(
 		"Ldflags",
@@ -54,34 +138,92 @@ func main() {
 		"FileStoragePath", config.FileStoragePath,
 		"StoreInterval", config.StoreInterval,
 		"Restore", config.Restore,
+		"WAL", config.WAL,
+		"SnapshotKeep", config.SnapshotKeep,
 		"key", config.SecretKey,
 		"enableHttps", config.EnableHTTPS,
 		"PublicKeyPath", config.PublicKeyPath,
 		"PrivateKeyPath", config.PrivateKeyPath,
+		"AlertRulesPath", config.AlertRulesPath,
+		"AlertInterval", config.AlertInterval,
+		"ReceiversPath", config.ReceiversPath,
+		"HistorySize", config.HistorySize,
+		"RollupInterval", config.RollupInterval,
+		"StatsdAddress", config.StatsdAddress,
+		"StatsdFlushInterval", config.StatsdFlushInterval,
+		"GraphiteAddress", config.GraphiteAddress,
+		"GRPCAddress", config.GRPCAddress,
+		"TrustedSubnet", config.TrustedSubnet,
+		"TrustedIPSource", config.TrustedIPSource,
+		"KeyringPath", config.KeyringPath,
+		"HashMaxSkew", config.HashMaxSkew,
+		"HashNonceCacheSize", config.HashNonceCacheSize,
+		"HashMaxBodySize", config.HashMaxBodySize,
+		"AcceptHashV1", config.AcceptHashV1,
+		"StrictAuth", config.StrictAuth,
+		"ClientCAPath", config.ClientCAPath,
+		"ClientAuth", config.ClientAuth,
 	)
 
+	tlsConfig := serverTLSConfig(config)
+
+	var statsdListener *statsd.Listener
+	if config.StatsdAddress != "" {
+		statsdListener = statsd.NewListener(config.StatsdAddress, storer, &zapLogger, config.StatsdFlushInterval)
+		if err := statsdListener.Start(context.Background()); err != nil {
+			log.Fatalf("can't start statsd listener: %v", err)
+		}
+	}
+
+	if config.GraphiteAddress != "" {
+		graphiteReceiver := tcpreceiver.NewReceiver(config.GraphiteAddress, ingest.GraphiteDecoder{}, storer, &zapLogger)
+		if err := graphiteReceiver.Start(context.Background()); err != nil {
+			log.Fatalf("can't start graphite receiver: %v", err)
+		}
+	}
+
+	var grpcServer *grpc.Server
+	if config.GRPCAddress != "" {
+		grpcServer = startGRPCServer(storer, &zapLogger, config, tlsConfig, keyring, signatureConfig, trustedSubnet)
+	}
+
 	httpServer := &http.Server{
-		Addr:    config.Address,
-		Handler: rout,
+		Addr:      config.Address,
+		Handler:   rout,
+		TLSConfig: tlsConfig,
 	}
 
 	stopChannel := helpers.CreateSystemStopChannel()
+	shutdownDone := make(chan struct{})
 
 	go func() {
+		defer close(shutdownDone)
 		<-stopChannel
 		zapLogger.Infow("Shutting down server")
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
-		metrics, err := storer.All(shutdownCtx)
-		if err != nil {
-			zapLogger.Errorw(
-				"failed get metrics from storage before writing to file",
-				"filePath", config.FileStoragePath,
-				"startServerErr", err.Error())
-			// Ensure the server shutdown is attempted even if there's an error retrieving metrics
-		} else {
-			repo.RewriteFile(config.FileStoragePath, metrics, &zapLogger)
+		if statsdListener != nil {
+			if err := statsdListener.Flush(shutdownCtx); err != nil {
+				zapLogger.Errorw("failed to flush statsd metrics", "error", err.Error())
+			}
+		}
+
+		if writesSnapshots(config) {
+			metrics, err := storer.All(shutdownCtx)
+			if err != nil {
+				zapLogger.Errorw(
+					"failed get metrics from storage before writing to file",
+					"filePath", config.FileStoragePath,
+					"startServerErr", err.Error())
+				// Ensure the server shutdown is attempted even if there's an error retrieving metrics
+			} else {
+				repo.WriteSnapshot(config.FileStoragePath, metrics, config.SnapshotKeep, &zapLogger)
+			}
+		}
+
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
 		}
 
 		if err := httpServer.Shutdown(shutdownCtx); err != nil {
@@ -92,9 +234,26 @@ func main() {
 		} else {
 			zapLogger.Infow("Server gracefully stopped")
 		}
+
+		// Storage is closed after in-flight requests are done.
+		stopStorage()
+		if storageCloser != nil {
+			if err := storageCloser.Close(); err != nil {
+				zapLogger.Errorw("failed to close storage", "error", err.Error())
+			}
+		}
 	}()
 
-	repo.StartWriting(context.Background(), storer, &zapLogger, config.StoreInterval, config.FileStoragePath)
+	if writesSnapshots(config) {
+		repo.StartWriting(context.Background(), storer, &zapLogger, config.StoreInterval, config.FileStoragePath, config.SnapshotKeep)
+	}
+	if historyStorer, ok := storer.(repo.HistoryStorer); ok {
+		repo.StartRollup(context.Background(), historyStorer, &zapLogger, config.RollupInterval, repo.Retention{
+			Raw:    config.RetentionRaw,
+			Minute: config.RetentionMinute,
+			Hour:   config.RetentionHour,
+		})
+	}
 
 	var startServerErr error
 	if config.EnableHTTPS {
@@ -102,38 +261,172 @@ func main() {
 	} else {
 		startServerErr = httpServer.ListenAndServe()
 	}
-	if startServerErr != nil && !errors.Is(err, http.ErrServerClosed) {
+	if startServerErr != nil && !errors.Is(startServerErr, http.ErrServerClosed) {
 		zapLogger.Errorw(
 			"Fail start server",
-			"error", err,
+			"error", startServerErr,
 		)
+		return
 	}
+	<-shutdownDone
 }
 
-func createStorer(logger *zap.SugaredLogger, config Config) (repo.MetricStorer, *sql.DB, error) {
-	if config.DSN != "" {
+// createStorer creates storer selected by config. Background work of storer stops when ctx is done,
+// returned closer, if any, must be closed after that.
+func createStorer(ctx context.Context, logger *zap.SugaredLogger, config Config) (repo.MetricStorer, *sql.DB, io.Closer, error) {
+	logStoreDir, logStoreOptions, isLogStore, err := logstore.ParseDSN(config.DSN)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if config.DSN != "" && !isLogStore {
 		db, err := sql.Open("pgx", config.DSN)
 		if err != nil {
 			logger.Errorw(
 				"Fail open db",
 				"Address", config.DSN,
 			)
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		storer, err := repo.NewPostgresMetricStorer(logger, db)
-
 		if err != nil {
 			logger.Errorw(
 				"Fail create storer",
 				"error", err.Error(),
 			)
+			db.Close()
+			return nil, nil, nil, err
 		}
-		return storer, db, nil
+		return storer, db, db, nil
 	}
 
-	if config.StoreInterval == 0 {
-		return repo.NewFileMetricStorer(config.FileStoragePath, logger), nil, nil
+	var storer repo.MetricStorer
+	var closer io.Closer
+	if isLogStore {
+		logStorer, err := repo.NewLogMetricStorer(logStoreDir, logStoreOptions, logger)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		storer = logStorer
+		closer = logStorer
+	} else if config.WAL {
+		walStorer, err := repo.NewWALMetricStorer(config.FileStoragePath, logger)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		repo.StartCompaction(ctx, walStorer, logger, config.WALCompactInterval)
+		storer = walStorer
+		closer = walStorer
+	} else if config.StoreInterval == 0 {
+		storer = repo.NewFileMetricStorer(config.FileStoragePath, logger)
+	} else {
+		storer = repo.NewLocalMetricStorer(config.Restore, config.FileStoragePath, logger)
+	}
+
+	if config.HistorySize > 0 {
+		storer = repo.NewRingHistoryStorer(storer, config.HistorySize)
+	}
+
+	return storer, nil, closer, nil
+}
+
+// writesSnapshots reports whether storer selected by config keeps metrics in memory and they are written
+// to file storage periodically and on shutdown. WAL and log store put every update on disk themselves.
+func writesSnapshots(config Config) bool {
+	_, _, isLogStore, _ := logstore.ParseDSN(config.DSN)
+	return !config.WAL && !isLogStore
+}
+
+// startGRPCServer starts gRPC server in background, it uses the same TLS certificate and client verification as HTTP server.
+func startGRPCServer(storer repo.MetricStorer, logger *zap.SugaredLogger, config Config, tlsConfig *tls.Config, keyring *auth.Keyring, signatureConfig auth.SignatureConfig, trustedSubnet *subnet.Checker) *grpc.Server {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		cert, err := tls.LoadX509KeyPair(config.PublicKeyPath, config.PrivateKeyPath)
+		if err != nil {
+			log.Fatalf("can't load gRPC TLS certificate: %v", err)
+		}
+		grpcTLSConfig := tlsConfig.Clone()
+		grpcTLSConfig.Certificates = []tls.Certificate{cert}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(grpcTLSConfig)))
+	} else if config.EnableHTTPS {
+		creds, err := credentials.NewServerTLSFromFile(config.PublicKeyPath, config.PrivateKeyPath)
+		if err != nil {
+			log.Fatalf("can't load gRPC TLS certificate: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	listener, err := net.Listen("tcp", config.GRPCAddress)
+	if err != nil {
+		log.Fatalf("can't start gRPC server: %v", err)
+	}
+
+	server := grpcserver.NewServer(storer, logger, keyring, signatureConfig, trustedSubnet, opts...)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Errorw("gRPC server stopped", "error", err.Error())
+		}
+	}()
+	return server
+}
+
+// loadKeyring returns keyring of HMAC keys. Keyring file is reloaded on SIGHUP, so keys are rotated
+// without restart. Secret key stays valid for agents that don't send key ID.
+func loadKeyring(logger *zap.SugaredLogger, config Config) *auth.Keyring {
+	if config.KeyringPath == "" {
+		return auth.NewKeyring(config.SecretKey)
+	}
+
+	keyring, err := auth.LoadKeyring(config.KeyringPath, config.SecretKey)
+	if err != nil {
+		log.Fatalf("can't load keyring: %v", err)
 	}
 
-	return repo.NewLocalMetricStorer(config.Restore, config.FileStoragePath, logger), nil, nil
+	reloadChannel := make(chan os.Signal, 1)
+	signal.Notify(reloadChannel, syscall.SIGHUP)
+	go func() {
+		for range reloadChannel {
+			if err := keyring.Reload(); err != nil {
+				logger.Errorw("failed to reload keyring, previous keys are kept", "path", config.KeyringPath, "error", err.Error())
+				continue
+			}
+			logger.Infow("keyring reloaded", "path", config.KeyringPath)
+		}
+	}()
+	return keyring
+}
+
+// serverTLSConfig returns TLS config that verifies agent certificates, or nil if mutual TLS is disabled.
+func serverTLSConfig(config Config) *tls.Config {
+	if config.ClientCAPath == "" {
+		return nil
+	}
+	if !config.EnableHTTPS {
+		log.Fatal("mutual TLS requires HTTPS to be enabled")
+	}
+
+	tlsConfig, err := mtls.ServerConfig(config.ClientCAPath, config.ClientAuth)
+	if err != nil {
+		log.Fatalf("can't configure mutual TLS: %v", err)
+	}
+	return tlsConfig
+}
+
+// decryptionOption enables decryption of agent payloads with private key. TLS key may be not RSA key,
+// then payload decryption is disabled if HTTPS is enabled, otherwise key is useless and server fails.
+func decryptionOption(logger *zap.SugaredLogger, config Config) router.Option {
+	data, err := os.ReadFile(config.PrivateKeyPath)
+	if err != nil {
+		log.Fatalf("can't read private key: %v", err)
+	}
+
+	key, err := encryption.ParsePrivateKey(data)
+	if err != nil {
+		if !config.EnableHTTPS {
+			log.Fatalf("can't parse private key: %v", err)
+		}
+		logger.Infow("payload decryption disabled", "reason", err.Error())
+		return router.WithDecryption(nil)
+	}
+	return router.WithDecryption(key)
 }
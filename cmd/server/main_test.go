@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCreateStorerPostgresError(t *testing.T) {
+	config := Config{DSN: "postgres://user@127.0.0.1:1/metrics?connect_timeout=1"}
+
+	storer, db, closer, err := createStorer(context.Background(), zaptest.NewLogger(t).Sugar(), config)
+	assert.Error(t, err)
+	assert.Nil(t, storer)
+	assert.Nil(t, db)
+	assert.Nil(t, closer)
+}
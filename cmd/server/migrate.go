@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/server/repo/logstore"
+	"github.com/VOTONO/go-metrics/internal/server/repo/migrations"
+)
+
+// runMigrate is server migrate mode, it changes database schema without starting servers:
+//
+//	server -d DSN migrate [up | down [steps] | status]
+//
+// up applies pending migrations, down rolls back last steps migrations (1 by default),
+// status reports applied and pending migrations.
+func runMigrate(logger *zap.SugaredLogger, config Config, args []string) error {
+	if config.DSN == "" {
+		return errors.New("database DSN is required")
+	}
+	if _, _, isLogStore, _ := logstore.ParseDSN(config.DSN); isLogStore {
+		return errors.New("log store has no schema to migrate")
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	steps := 1
+	if command == "down" && len(args) > 1 {
+		var err error
+		if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+			return fmt.Errorf("invalid number of steps %q", args[1])
+		}
+	}
+
+	db, err := sql.Open("pgx", config.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Infow("schema is up to date", "applied", count)
+	case "down":
+		count, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		logger.Infow("rolled back migrations", "count", count)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		pending := 0
+		for _, status := range statuses {
+			if status.Pending() {
+				pending++
+				logger.Infow("pending migration", "version", status.Version, "name", status.Name)
+			} else {
+				logger.Infow("applied migration", "version", status.Version, "name", status.Name, "applied_at", status.AppliedAt)
+			}
+		}
+		logger.Infow("migration status", "total", len(statuses), "pending", pending)
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -17,3 +18,14 @@ func CreateTicker(seconds int) *time.Ticker {
 	duration := time.Duration(seconds) * time.Second
 	return time.NewTicker(duration)
 }
+
+// OutboundIP returns local IP used to reach address "host:port". No packets are sent.
+func OutboundIP(address string) (net.IP, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -2,26 +2,40 @@ package workers
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
+	"crypto/rsa"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/VOTONO/go-metrics/internal/agent/helpers"
 	"github.com/VOTONO/go-metrics/internal/agent/semaphore"
+	"github.com/VOTONO/go-metrics/internal/auth"
 	"github.com/VOTONO/go-metrics/internal/compressor"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/encryption"
 	"github.com/VOTONO/go-metrics/internal/models"
+	pb "github.com/VOTONO/go-metrics/internal/proto"
+	"github.com/VOTONO/go-metrics/internal/subnet"
 )
 
+// errUnverifiedResponse means server accepted batch, but its response signature can't be verified.
+// Batch is not resent, because it's already stored and would be counted twice.
+var errUnverifiedResponse = errors.New("response signature is not verified")
+
 // SendWorker sends metrics from inputChannel to the server.
 type SendWorker struct {
 	client       *http.Client
+	grpcClient   pb.MetricsClient // used instead of client if set
+	publicKey    *rsa.PublicKey   // HTTP request bodies are encrypted if set
 	logger       *zap.SugaredLogger
 	ticker       *time.Ticker
 	address      string
@@ -29,6 +43,9 @@ type SendWorker struct {
 	inputChannel <-chan []models.Metric
 	semaphore    *semaphore.Semaphore
 	secretKey    string
+	secretKeyID  string // ID of secretKey in server keyring, sent in HashKeyID header
+	hashV1       bool   // sign body only, see UseHashV1
+	labels       map[string]string
 	waitGroup    sync.WaitGroup
 }
 
@@ -39,7 +56,8 @@ func NewSendWorker(
 	inputChannel <-chan []models.Metric,
 	rateLimit int,
 	address string,
-	secretKey string) *SendWorker {
+	secretKey string,
+	labels map[string]string) *SendWorker {
 	return &SendWorker{
 		client:       client,
 		logger:       logger,
@@ -49,6 +67,7 @@ func NewSendWorker(
 		semaphore:    semaphore.NewSemaphore(rateLimit),
 		address:      address,
 		secretKey:    secretKey,
+		labels:       labels,
 		waitGroup:    sync.WaitGroup{},
 	}
 }
@@ -74,11 +93,35 @@ func (w *SendWorker) Stop() {
 	close(w.stopChannel)
 }
 
+// UseEncryption makes worker encrypt HTTP request bodies for server key. Must be called before Start.
+func (w *SendWorker) UseEncryption(key *rsa.PublicKey) {
+	w.publicKey = key
+}
+
+// UseKeyID makes worker name its secret key in HashKeyID header, so server with keyring
+// knows which key verifies the hash. Must be called before Start.
+func (w *SendWorker) UseKeyID(id string) {
+	w.secretKeyID = id
+}
+
+// UseHashV1 makes worker sign HTTP request body or gRPC request only, for servers that don't support
+// version 2 signatures of auth.SignRequest. Must be called before Start.
+func (w *SendWorker) UseHashV1() {
+	w.hashV1 = true
+}
+
+// UseGRPC makes worker send metrics with gRPC client instead of HTTP. Must be called before Start.
+func (w *SendWorker) UseGRPC(client pb.MetricsClient) {
+	w.grpcClient = client
+}
+
 // buildRequest creates a compressed HTTP request for a batch of metrics.
+// Body is JSON, compressed, signed and then encrypted if worker has public key,
+// server undoes these steps in reverse order.
 func (w *SendWorker) buildRequest(metrics []models.Metric) (*http.Request, error) {
 	url := fmt.Sprintf("https://%s/updates/", w.address)
 
-	body, err := json.Marshal(metrics)
+	body, err := json.Marshal(w.withLabels(metrics))
 	if err != nil {
 		return nil, err
 	}
@@ -88,7 +131,15 @@ func (w *SendWorker) buildRequest(metrics []models.Metric) (*http.Request, error
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(compressedBody))
+	requestBody := compressedBody
+	if w.publicKey != nil {
+		requestBody, err = encryption.Encrypt(w.publicKey, compressedBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, err
 	}
@@ -96,45 +147,100 @@ func (w *SendWorker) buildRequest(metrics []models.Metric) (*http.Request, error
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Encoding", "gzip")
+	if w.publicKey != nil {
+		req.Header.Set(encryption.Header, encryption.Scheme)
+	}
+	if ip := w.outboundIP(); ip != "" {
+		req.Header.Set(subnet.RealIPHeader, ip)
+	}
 
 	if w.secretKey != "" {
-		h := hmac.New(sha256.New, []byte(w.secretKey))
-		h.Write(compressedBody)
-		req.Header.Set("HashSHA256", hex.EncodeToString(h.Sum(nil)))
+		if w.hashV1 {
+			req.Header.Set(constants.HashSHA256, auth.Sign(w.secretKey, compressedBody))
+		} else if err := auth.SignRequest(req, w.secretKey, compressedBody); err != nil {
+			return nil, err
+		}
+		if w.secretKeyID != "" {
+			req.Header.Set(constants.HashKeyID, w.secretKeyID)
+		}
 	}
 
 	return req, nil
 }
 
+// outboundIP returns IP of host used to reach server, for trusted subnet check on server side.
+// Empty string is returned if it can't be determined.
+func (w *SendWorker) outboundIP() string {
+	ip, err := helpers.OutboundIP(w.address)
+	if err != nil {
+		w.logger.Errorw("Failed to get outbound IP", "error", err)
+		return ""
+	}
+	return ip.String()
+}
+
+// withLabels returns copy of metrics with worker labels attached, metric's own labels take precedence.
+func (w *SendWorker) withLabels(metrics []models.Metric) []models.Metric {
+	if len(w.labels) == 0 {
+		return metrics
+	}
+
+	labeled := make([]models.Metric, len(metrics))
+	for i, metric := range metrics {
+		labels := make(map[string]string, len(w.labels)+len(metric.Labels))
+		for name, value := range w.labels {
+			labels[name] = value
+		}
+		for name, value := range metric.Labels {
+			labels[name] = value
+		}
+		metric.Labels = labels
+		labeled[i] = metric
+	}
+	return labeled
+}
+
 // sendWithRetry metrics to the server.
 func (w *SendWorker) sendWithRetry(metrics []models.Metric) error {
 	w.waitGroup.Add(1)
 	defer w.waitGroup.Done()
 
-	req, buildReqErr := w.buildRequest(metrics)
-	if buildReqErr != nil {
-		return fmt.Errorf("failed to build batch request: %s", buildReqErr.Error())
+	var send func() error
+	if w.grpcClient != nil {
+		send = func() error { return w.sendGRPC(metrics) }
+	} else {
+		// Request is built for every attempt, its body is consumed and signature nonce must be unique.
+		send = func() error {
+			req, buildReqErr := w.buildRequest(metrics)
+			if buildReqErr != nil {
+				return fmt.Errorf("failed to build batch request: %s", buildReqErr.Error())
+			}
+			return w.sendRequest(req)
+		}
 	}
 
-	err := w.sendRequest(req)
+	err := send()
 	w.logger.Infow("sent batch", "count", len(metrics))
-	if err != nil {
+	if err != nil && !errors.Is(err, errUnverifiedResponse) {
 		retryCount := 3
 		retryPause := 1 * time.Second
 
 		for i := 0; i < retryCount; i++ {
 			time.Sleep(retryPause)
-			err = w.sendRequest(req)
+			err = send()
 
-			if err == nil {
-				return nil
+			if err == nil || errors.Is(err, errUnverifiedResponse) {
+				break
 			}
 
 			retryPause += 2
 		}
 	}
 
-	return nil
+	if err != nil {
+		w.logger.Errorw("Failed to send batch", "count", len(metrics), "error", err)
+	}
+	return err
 }
 
 func (w *SendWorker) sendRequest(req *http.Request) error {
@@ -157,5 +263,92 @@ func (w *SendWorker) sendRequest(req *http.Request) error {
 		return fmt.Errorf("batch request failed with status code %d", resp.StatusCode)
 	}
 
+	if err := w.verifyResponse(resp); err != nil {
+		w.logger.Errorw("Failed to verify response signature", "error", err)
+		return fmt.Errorf("%w: %w", errUnverifiedResponse, err)
+	}
+
 	return nil
 }
+
+// verifyResponse checks HMAC of response body signed by server, so agent detects responses
+// of man-in-the-middle or misconfigured proxy. Response must be signed if worker has secret key.
+func (w *SendWorker) verifyResponse(resp *http.Response) error {
+	if w.secretKey == "" {
+		return nil
+	}
+
+	hash := resp.Header.Get(constants.HashSHA256)
+	if hash == "" {
+		return errors.New("response is not signed")
+	}
+	if keyID := resp.Header.Get(constants.HashKeyID); w.secretKeyID != "" && keyID != w.secretKeyID {
+		return fmt.Errorf("response is signed with unknown key %q", keyID)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if !auth.Verify(w.secretKey, body, hash) {
+		return errors.New("invalid response signature")
+	}
+	return nil
+}
+
+// sendGRPC sends batch with UpdateMetrics call, signature of request is passed in HashSHA256 metadata
+// together with HashVersion, HashTimestamp and HashNonce, see auth.SignMessageV2.
+func (w *SendWorker) sendGRPC(metrics []models.Metric) error {
+	w.semaphore.Acquire()
+	defer w.semaphore.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req := &pb.UpdateMetricsRequest{Metrics: pb.FromModels(w.withLabels(metrics))}
+	if ip := w.outboundIP(); ip != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, subnet.RealIPMetadataKey, ip)
+	}
+	if w.secretKey != "" {
+		var err error
+		if ctx, err = w.signGRPC(ctx, req); err != nil {
+			return fmt.Errorf("failed to sign batch request: %w", err)
+		}
+	}
+
+	if _, err := w.grpcClient.UpdateMetrics(ctx, req); err != nil {
+		w.logger.Errorw("Failed to send batch with gRPC", "error", err)
+		return fmt.Errorf("error sending batch request for metrics: %w", err)
+	}
+	return nil
+}
+
+// signGRPC adds signature of UpdateMetrics request to outgoing metadata of ctx.
+func (w *SendWorker) signGRPC(ctx context.Context, req *pb.UpdateMetricsRequest) (context.Context, error) {
+	if w.secretKeyID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(constants.HashKeyID), w.secretKeyID)
+	}
+
+	if w.hashV1 {
+		hash, err := auth.SignMessage(w.secretKey, req)
+		if err != nil {
+			return nil, err
+		}
+		return metadata.AppendToOutgoingContext(ctx, strings.ToLower(constants.HashSHA256), hash), nil
+	}
+
+	timestamp, nonce, err := auth.NewSignatureParams()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := auth.SignMessageV2(w.secretKey, pb.Metrics_UpdateMetrics_FullMethodName, req, timestamp, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx,
+		strings.ToLower(constants.HashVersion), auth.SignatureV2,
+		strings.ToLower(constants.HashTimestamp), timestamp,
+		strings.ToLower(constants.HashNonce), nonce,
+		strings.ToLower(constants.HashSHA256), hash,
+	), nil
+}
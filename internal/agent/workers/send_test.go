@@ -0,0 +1,199 @@
+package workers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/encryption"
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+	"github.com/VOTONO/go-metrics/internal/subnet"
+)
+
+func TestBuildRequestRealIP(t *testing.T) {
+	worker := NewSendWorker(http.DefaultClient, zaptest.NewLogger(t).Sugar(), 1, nil, 1, "127.0.0.1:8080", "", nil)
+
+	value := 1.0
+	req, err := worker.buildRequest([]models.Metric{{ID: "temp", MType: "gauge", Value: &value}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1", req.Header.Get(subnet.RealIPHeader))
+}
+
+func TestBuildRequestKeyID(t *testing.T) {
+	worker := NewSendWorker(http.DefaultClient, zaptest.NewLogger(t).Sugar(), 1, nil, 1, "127.0.0.1:8080", "secret", nil)
+	worker.UseKeyID("2024-10")
+
+	value := 1.0
+	req, err := worker.buildRequest([]models.Metric{{ID: "temp", MType: constants.Gauge, Value: &value}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2024-10", req.Header.Get(constants.HashKeyID))
+	assert.NotEmpty(t, req.Header.Get(constants.HashSHA256))
+}
+
+// TestBuildRequestPipeline checks order of agent body processing: JSON, gzip, HMAC, encryption.
+// Server must undo it in reverse order.
+func TestBuildRequestPipeline(t *testing.T) {
+	const secretKey = "secret"
+	logger := zaptest.NewLogger(t).Sugar()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	value := 1.5
+	metrics := []models.Metric{{ID: "temp", MType: constants.Gauge, Value: &value}}
+
+	worker := NewSendWorker(http.DefaultClient, logger, 1, nil, 1, "127.0.0.1:8080", secretKey, nil)
+	worker.UseEncryption(&key.PublicKey)
+
+	req, err := worker.buildRequest(metrics)
+	require.NoError(t, err)
+	assert.Equal(t, encryption.Scheme, req.Header.Get(encryption.Header))
+
+	// Hash covers compressed body before encryption.
+	ciphertext, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	compressed, err := encryption.Decrypt(key, ciphertext)
+	require.NoError(t, err)
+	canonical := auth.CanonicalRequest(http.MethodPost, "/updates/", "", compressed,
+		req.Header.Get(constants.HashTimestamp), req.Header.Get(constants.HashNonce))
+	assert.Equal(t, auth.SignatureV2, req.Header.Get(constants.HashVersion))
+	assert.Equal(t, auth.Sign(secretKey, []byte(canonical)), req.Header.Get(constants.HashSHA256))
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	var decoded []models.Metric
+	require.NoError(t, json.NewDecoder(gz).Decode(&decoded))
+	assert.Equal(t, metrics, decoded)
+}
+
+// TestBuildRequestEncryptedRoundTrip sends encrypted request through server router.
+func TestBuildRequestEncryptedRoundTrip(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	value := 1.5
+	metrics := []models.Metric{{ID: "temp", MType: constants.Gauge, Value: &value}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storer := mocks.NewMockMetricStorer(ctrl)
+	storer.EXPECT().StoreSlice(gomock.Any(), metrics).Return(nil)
+
+	worker := NewSendWorker(http.DefaultClient, logger, 1, nil, 1, "127.0.0.1:8080", "", nil)
+	worker.UseEncryption(&key.PublicKey)
+
+	req, err := worker.buildRequest(metrics)
+	require.NoError(t, err)
+	res := httptest.NewRecorder()
+	router.Router(storer, &sql.DB{}, logger, "", router.WithDecryption(key)).ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	// Signed request passes signature check and replay of it is rejected.
+	worker = NewSendWorker(http.DefaultClient, logger, 1, nil, 1, "127.0.0.1:8080", "secret", nil)
+	worker.UseEncryption(&key.PublicKey)
+	signedRouter := router.Router(storer, &sql.DB{}, logger, "secret", router.WithDecryption(key))
+	storer.EXPECT().StoreSlice(gomock.Any(), metrics).Return(nil)
+
+	req, err = worker.buildRequest(metrics)
+	require.NoError(t, err)
+	replay := req.Clone(req.Context())
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	replay.Body = io.NopCloser(bytes.NewReader(body))
+
+	res = httptest.NewRecorder()
+	signedRouter.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = httptest.NewRecorder()
+	signedRouter.ServeHTTP(res, replay)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	// Server without private key rejects encrypted request.
+	req, err = worker.buildRequest(metrics)
+	require.NoError(t, err)
+	res = httptest.NewRecorder()
+	router.Router(storer, &sql.DB{}, logger, "").ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
+
+// TestSendRequestVerifiesResponse checks that response without valid server signature is a failed send.
+func TestSendRequestVerifiesResponse(t *testing.T) {
+	const secretKey = "secret"
+	logger := zaptest.NewLogger(t).Sugar()
+
+	value := 1.5
+	metrics := []models.Metric{{ID: "temp", MType: constants.Gauge, Value: &value}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storer := mocks.NewMockMetricStorer(ctrl)
+	storer.EXPECT().StoreSlice(gomock.Any(), metrics).Return(nil)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		wantErr bool
+	}{
+		{name: "server", handler: router.Router(storer, &sql.DB{}, logger, secretKey)},
+		{name: "unsigned response", handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), wantErr: true},
+		{name: "forged response", handler: auth.HashSigner("other")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		})), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(tt.handler)
+			defer server.Close()
+
+			worker := NewSendWorker(server.Client(), logger, 1, nil, 1, server.Listener.Addr().String(), secretKey, nil)
+			req, err := worker.buildRequest(metrics)
+			require.NoError(t, err)
+
+			err = worker.sendRequest(req)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSendWithRetryDoesNotResendUnverifiedResponse(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	value := 1.5
+	metrics := []models.Metric{{ID: "temp", MType: constants.Gauge, Value: &value}}
+
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	worker := NewSendWorker(server.Client(), logger, 1, nil, 1, server.Listener.Addr().String(), "secret", nil)
+	err := worker.sendWithRetry(metrics)
+	assert.ErrorIs(t, err, errUnverifiedResponse)
+	assert.Equal(t, 1, requests)
+}
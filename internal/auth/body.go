@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+)
+
+// maxMemoryBody is size of request body kept in memory during verification, larger bodies are spooled to temporary file.
+const maxMemoryBody = 1 << 20
+
+// spoolBody reads body to the end, writing it to hash, and returns reader of the same bytes.
+// Body is verified before handler reads it, so it's kept until handler is done: in memory
+// if it's small, otherwise in temporary file that is removed on Close.
+func spoolBody(body io.ReadCloser, hash io.Writer) (io.ReadCloser, error) {
+	defer body.Close()
+
+	var buffer bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(&buffer, hash), io.LimitReader(body, maxMemoryBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if n <= maxMemoryBody {
+		return io.NopCloser(&buffer), nil
+	}
+
+	file, err := os.CreateTemp("", "metrics-body-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledFile{File: file}
+	if _, err := buffer.WriteTo(file); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := io.Copy(io.MultiWriter(file, hash), body); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// spooledFile is temporary file that is removed on Close.
+type spooledFile struct {
+	*os.File
+}
+
+func (f *spooledFile) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// bodyError responds to failed read of request body: with 413 if body exceeds limit
+// of http.MaxBytesReader, otherwise with 400.
+func bodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "Failed to read request body", http.StatusBadRequest)
+}
@@ -0,0 +1,26 @@
+// Package auth contains middlewares and helpers for signing and verifying data with HMAC.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Sign returns hex encoded HMAC-SHA256 of data computed with given key.
+func Sign(key string, data []byte) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify reports whether hash is hex encoded HMAC-SHA256 of data computed with given key.
+func Verify(key string, data []byte, hash string) bool {
+	expected, err := hex.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(data)
+	return hmac.Equal(h.Sum(nil), expected)
+}
@@ -1,21 +1,29 @@
 package auth
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"io"
 	"net/http"
 
 	"github.com/VOTONO/go-metrics/internal/constants"
 )
 
+type verifiedKeyContext struct{}
+
 // HashChecker returns a middleware that checks sha256 hash using given key if HashSHA256 header exists.
 func HashChecker(key string) func(http.Handler) http.Handler {
+	return KeyringHashChecker(NewKeyring(key))
+}
+
+// KeyringHashChecker returns a middleware that checks sha256 hash if HashSHA256 header exists.
+// Hash is checked with key named in HashKeyID header, see Keyring.Lookup.
+// Malformed hash is rejected with 400, wrong hash or unknown key with 401.
+func KeyringHashChecker(keyring *Keyring) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if key == "" || r.Header.Get("HashSHA256") == "" {
+			if keyring.Empty() || r.Header.Get(constants.HashSHA256) == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -26,20 +34,60 @@ func HashChecker(key string) func(http.Handler) http.Handler {
 				return
 			}
 
-			h := hmac.New(sha256.New, []byte(key))
-			if _, err := io.Copy(h, io.LimitReader(r.Body, 1<<20)); err != nil {
-				http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+			key, ok := keyring.Lookup(r.Header.Get(constants.HashKeyID))
+			if !ok {
+				unauthorized(w, "Unknown or expired hash key")
 				return
 			}
-			computedHash := h.Sum(nil)
 
-			r.Body = io.NopCloser(bytes.NewReader(computedHash))
+			h := hmac.New(sha256.New, []byte(key.Secret))
+			body, err := spoolBody(r.Body, h)
+			if err != nil {
+				bodyError(w, err)
+				return
+			}
+			defer body.Close()
+			r.Body = body
 
-			if hmac.Equal(computedHash, hashData) {
-				next.ServeHTTP(w, r)
-			} else {
-				http.Error(w, "Invalid hash", http.StatusBadRequest)
+			if !hmac.Equal(h.Sum(nil), hashData) {
+				unauthorized(w, "Invalid hash")
+				return
 			}
+			next.ServeHTTP(w, markVerified(r, key))
 		})
 	}
 }
+
+// RequireSignature returns a middleware that rejects requests not verified by KeyringHashChecker
+// or SignatureChecker with 401. It's used for endpoints that change metrics in strict mode.
+func RequireSignature(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Verified(r.Context()) {
+			unauthorized(w, "Request must be signed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Verified reports whether signature of request was checked.
+func Verified(ctx context.Context) bool {
+	_, verified := verifiedKey(ctx)
+	return verified
+}
+
+// verifiedKey returns key that verified signature of request.
+func verifiedKey(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(verifiedKeyContext{}).(Key)
+	return key, ok
+}
+
+func markVerified(r *http.Request, key Key) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), verifiedKeyContext{}, key))
+}
+
+// unauthorized responds with 401, that means request is well-formed but not authenticated.
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", constants.HashSHA256)
+	http.Error(w, message, http.StatusUnauthorized)
+}
@@ -2,31 +2,61 @@ package auth
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"net/http"
 
 	"github.com/VOTONO/go-metrics/internal/constants"
 )
 
 // ResponseCapture wraps an http.ResponseWriter to capture response data.
+// Response is kept until Send, so headers can be set after handler has written the body.
 type ResponseCapture struct {
 	http.ResponseWriter
-	Body *bytes.Buffer
+	Body   *bytes.Buffer
+	Status int
+}
+
+// WriteHeader captures the response status code.
+func (rc *ResponseCapture) WriteHeader(status int) {
+	if rc.Status == 0 {
+		rc.Status = status
+	}
 }
 
 // Write captures the response body data.
 func (rc *ResponseCapture) Write(b []byte) (int, error) {
-	rc.Body.Write(b)                  // Write to buffer
-	return rc.ResponseWriter.Write(b) // Also write to the original ResponseWriter
+	if rc.Status == 0 {
+		rc.Status = http.StatusOK
+	}
+	return rc.Body.Write(b)
+}
+
+// Send writes captured status and body to the original ResponseWriter.
+func (rc *ResponseCapture) Send() error {
+	if rc.Status == 0 {
+		rc.Status = http.StatusOK
+	}
+	rc.ResponseWriter.WriteHeader(rc.Status)
+	_, err := rc.Body.WriteTo(rc.ResponseWriter)
+	return err
 }
 
 // HashSigner returns a middleware that signs the response body with an HMAC hash.
 func HashSigner(key string) func(http.Handler) http.Handler {
+	return KeyringHashSigner(NewKeyring(key))
+}
+
+// KeyringHashSigner returns a middleware that signs the response body in HashSHA256 header.
+// Response of signed request is signed with the key that verified it, so agent can check it
+// with its own key during rotation, other responses with the current key of keyring.
+// ID of the key is set in HashKeyID header. Response is buffered until it's signed.
+func KeyringHashSigner(keyring *Keyring) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if key == "" {
+			key, ok := verifiedKey(r.Context())
+			if !ok {
+				key, ok = keyring.Current()
+			}
+			if !ok {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -40,16 +70,14 @@ func HashSigner(key string) func(http.Handler) http.Handler {
 			// Serve the next handler with the captured response writer
 			next.ServeHTTP(capture, r)
 
-			// Compute HMAC of the response body
-			h := hmac.New(sha256.New, []byte(key))
-			h.Write(capture.Body.Bytes())
-			computedHash := h.Sum(nil)
-
-			// Convert hash to hexadecimal string
-			hashString := hex.EncodeToString(computedHash)
-
-			// Set the computed hash in the header of the original ResponseWriter
-			w.Header().Set(constants.HashSHA256, hashString)
+			// Set HMAC of the response body before it's sent
+			w.Header().Set(constants.HashSHA256, Sign(key.Secret, capture.Body.Bytes()))
+			if key.ID != "" {
+				w.Header().Set(constants.HashKeyID, key.ID)
+			} else {
+				w.Header().Del(constants.HashKeyID)
+			}
+			capture.Send()
 		})
 	}
 }
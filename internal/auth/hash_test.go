@@ -5,6 +5,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -102,3 +103,31 @@ func TestHashChecker_InvalidHash(t *testing.T) {
 		t.Errorf("Expected error message in response body, got %v", rec.Body.String())
 	}
 }
+
+func TestHashSignerReachesClient(t *testing.T) {
+	server := httptest.NewServer(HashSigner(testKey)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(testBody))
+	})))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %v, want %v", resp.StatusCode, http.StatusCreated)
+	}
+	if string(body) != testBody {
+		t.Errorf("body = %v, want %v", string(body), testBody)
+	}
+	if !Verify(testKey, body, resp.Header.Get(constants.HashSHA256)) {
+		t.Errorf("response hash %q doesn't match body", resp.Header.Get(constants.HashSHA256))
+	}
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Key is HMAC secret identified by ID. Zero NotBefore or NotAfter leaves the period open on that side.
+type Key struct {
+	ID        string    `json:"id"`
+	Secret    string    `json:"secret"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// ValidAt reports whether key may be used at t.
+func (k Key) ValidAt(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !t.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// keyringFile is format of keyring file:
+//
+//	{"keys": [{"id": "2024-10", "secret": "...", "not_before": "2024-10-01T00:00:00Z", "not_after": "2024-12-01T00:00:00Z"}]}
+type keyringFile struct {
+	Keys []Key `json:"keys"`
+}
+
+// Keyring is a set of HMAC keys that allows to rotate keys without downtime: requests are verified
+// with key named in HashKeyID header, responses are signed with the current key.
+// Keys of file keyring are replaced by Reload, it's safe to use keyring concurrently.
+type Keyring struct {
+	mu     sync.RWMutex
+	path   string
+	legacy string // secret without ID that is kept on reload, see NewKeyring
+	keys   []Key
+	now    func() time.Time
+}
+
+// NewKeyring returns keyring with single key without ID and validity period, like a plain secret key.
+// Empty secret gives empty keyring that disables signing and verification.
+func NewKeyring(secret string) *Keyring {
+	k := &Keyring{legacy: secret, now: time.Now}
+	k.keys = k.withLegacy(nil)
+	return k
+}
+
+// LoadKeyring reads keyring from JSON file at path. Non-empty legacySecret is added as key without ID,
+// it verifies requests of agents that don't send HashKeyID header.
+func LoadKeyring(path string, legacySecret string) (*Keyring, error) {
+	k := &Keyring{path: path, legacy: legacySecret, now: time.Now}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload reads keyring file again. Keys are kept if file is invalid.
+func (k *Keyring) Reload() error {
+	if k.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return err
+	}
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid keyring file: %w", err)
+	}
+
+	seen := make(map[string]bool, len(file.Keys))
+	for _, key := range file.Keys {
+		switch {
+		case key.ID == "":
+			return errors.New("key without id in keyring file")
+		case key.Secret == "":
+			return fmt.Errorf("key %q has empty secret", key.ID)
+		case seen[key.ID]:
+			return fmt.Errorf("duplicate key %q", key.ID)
+		case !key.NotAfter.IsZero() && !key.NotAfter.After(key.NotBefore):
+			return fmt.Errorf("key %q expires before it becomes valid", key.ID)
+		}
+		seen[key.ID] = true
+	}
+
+	keys := k.withLegacy(file.Keys)
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *Keyring) withLegacy(keys []Key) []Key {
+	if k.legacy == "" {
+		return keys
+	}
+	return append([]Key{{Secret: k.legacy}}, keys...)
+}
+
+// Empty reports whether keyring has no keys, then requests are neither verified nor signed.
+func (k *Keyring) Empty() bool {
+	if k == nil {
+		return true
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys) == 0
+}
+
+// Lookup returns key with given ID that is valid now. Empty ID means the key without ID if there is one,
+// otherwise the current key.
+func (k *Keyring) Lookup(id string) (Key, bool) {
+	if k == nil {
+		return Key{}, false
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := k.now()
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, key.ValidAt(now)
+		}
+	}
+	if id == "" {
+		return k.current(now)
+	}
+	return Key{}, false
+}
+
+// Current returns key used for signing: the valid key that became valid last.
+func (k *Keyring) Current() (Key, bool) {
+	if k == nil {
+		return Key{}, false
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current(k.now())
+}
+
+func (k *Keyring) current(now time.Time) (Key, bool) {
+	var current Key
+	found := false
+	for _, key := range k.keys {
+		if key.ValidAt(now) && (!found || !key.NotBefore.Before(current.NotBefore)) {
+			current = key
+			found = true
+		}
+	}
+	return current, found
+}
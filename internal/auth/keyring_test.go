@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+)
+
+const testKeyringFile = `{"keys": [
+	{"id": "old", "secret": "old-secret", "not_after": "2024-11-01T00:00:00Z"},
+	{"id": "new", "secret": "new-secret", "not_before": "2024-10-01T00:00:00Z"},
+	{"id": "next", "secret": "next-secret", "not_before": "2024-12-01T00:00:00Z"}
+]}`
+
+func writeKeyring(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func loadTestKeyring(t *testing.T, now time.Time) *Keyring {
+	keyring, err := LoadKeyring(writeKeyring(t, testKeyringFile), testKey)
+	require.NoError(t, err)
+	keyring.now = func() time.Time { return now }
+	return keyring
+}
+
+func TestKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		now         time.Time
+		id          string
+		wantFound   bool
+		wantSecret  string
+		wantCurrent string
+	}{
+		{name: "before rotation", now: time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC), id: "old", wantFound: true, wantSecret: "old-secret", wantCurrent: "old"},
+		{name: "not yet valid", now: time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC), id: "new", wantCurrent: "old"},
+		{name: "overlap accepts old key", now: time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC), id: "old", wantFound: true, wantSecret: "old-secret", wantCurrent: "new"},
+		{name: "overlap signs with new key", now: time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC), id: "new", wantFound: true, wantSecret: "new-secret", wantCurrent: "new"},
+		{name: "expired", now: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), id: "old", wantCurrent: "new"},
+		{name: "unknown", now: time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC), id: "missing", wantCurrent: "new"},
+		{name: "no id uses legacy secret", now: time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC), id: "", wantFound: true, wantSecret: testKey, wantCurrent: "new"},
+		{name: "next key", now: time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC), id: "next", wantFound: true, wantSecret: "next-secret", wantCurrent: "next"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring := loadTestKeyring(t, tt.now)
+
+			key, found := keyring.Lookup(tt.id)
+			assert.Equal(t, tt.wantFound, found)
+			if tt.wantFound {
+				assert.Equal(t, tt.wantSecret, key.Secret)
+			}
+
+			current, found := keyring.Current()
+			require.True(t, found)
+			assert.Equal(t, tt.wantCurrent, current.ID)
+		})
+	}
+}
+
+func TestKeyringReload(t *testing.T) {
+	path := writeKeyring(t, `{"keys": [{"id": "a", "secret": "a-secret"}]}`)
+	keyring, err := LoadKeyring(path, "")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [{"id": "b", "secret": "b-secret"}]}`), 0o600))
+	require.NoError(t, keyring.Reload())
+	_, found := keyring.Lookup("a")
+	assert.False(t, found)
+	key, found := keyring.Lookup("b")
+	assert.True(t, found)
+	assert.Equal(t, "b-secret", key.Secret)
+
+	// Invalid file keeps previous keys.
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [{"id": "c"}]}`), 0o600))
+	assert.Error(t, keyring.Reload())
+	_, found = keyring.Lookup("b")
+	assert.True(t, found)
+}
+
+func TestLoadKeyringErrors(t *testing.T) {
+	for _, content := range []string{
+		`not json`,
+		`{"keys": [{"secret": "s"}]}`,
+		`{"keys": [{"id": "a", "secret": "s"}, {"id": "a", "secret": "t"}]}`,
+		`{"keys": [{"id": "a", "secret": "s", "not_before": "2024-10-01T00:00:00Z", "not_after": "2024-09-01T00:00:00Z"}]}`,
+	} {
+		_, err := LoadKeyring(writeKeyring(t, content), "")
+		assert.Error(t, err, content)
+	}
+}
+
+func TestKeyringHashMiddlewares(t *testing.T) {
+	keyring := loadTestKeyring(t, time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC))
+	handler := KeyringHashChecker(keyring)(KeyringHashSigner(keyring)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testBody))
+	})))
+
+	tests := []struct {
+		name     string
+		keyID    string
+		secret   string
+		wantCode int
+	}{
+		{name: "current key", keyID: "new", secret: "new-secret", wantCode: http.StatusOK},
+		{name: "previous key", keyID: "old", secret: "old-secret", wantCode: http.StatusOK},
+		{name: "legacy key without id", secret: testKey, wantCode: http.StatusOK},
+		{name: "not yet valid key", keyID: "next", secret: "next-secret", wantCode: http.StatusUnauthorized},
+		{name: "wrong secret", keyID: "new", secret: "old-secret", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte("Request body content")
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(constants.HashSHA256, Sign(tt.secret, body))
+			if tt.keyID != "" {
+				req.Header.Set(constants.HashKeyID, tt.keyID)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				// Response is signed with the key of request.
+				assert.Equal(t, tt.keyID, rec.Header().Get(constants.HashKeyID))
+				assert.Equal(t, Sign(tt.secret, []byte(testBody)), rec.Header().Get(constants.HashSHA256))
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SignMessage returns HMAC-SHA256 of protobuf message. Message is serialized deterministically,
+// so the same message has the same hash on client and server.
+func SignMessage(key string, message proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	return Sign(key, data), nil
+}
+
+// SignMessageV2 returns v2 signature of gRPC call, see CanonicalRequest. Call is signed as POST
+// of deterministically serialized message to full method name, e.g. /metrics.Metrics/UpdateMetrics.
+func SignMessageV2(key, fullMethod string, message proto.Message, timestamp, nonce string) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	return Sign(key, []byte(CanonicalRequest(http.MethodPost, fullMethod, "", data, timestamp, nonce))), nil
+}
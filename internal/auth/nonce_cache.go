@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// NonceCache remembers nonces of signed requests until they expire. Size of cache is bounded:
+// when it's full, nonce that expires first is forgotten, so its request could be replayed.
+type NonceCache struct {
+	mu      sync.Mutex
+	size    int
+	expires map[string]time.Time
+	queue   nonceQueue
+}
+
+// NewNonceCache creates cache for at most size nonces.
+func NewNonceCache(size int) *NonceCache {
+	if size < 1 {
+		size = 1
+	}
+	return &NonceCache{size: size, expires: make(map[string]time.Time)}
+}
+
+// Add remembers nonce until expiresAt and reports whether nonce is new at now.
+func (c *NonceCache) Add(nonce string, expiresAt time.Time, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.queue) > 0 && !c.queue[0].expiresAt.After(now) {
+		delete(c.expires, heap.Pop(&c.queue).(nonceEntry).nonce)
+	}
+
+	if _, found := c.expires[nonce]; found {
+		return false
+	}
+
+	for len(c.queue) >= c.size {
+		delete(c.expires, heap.Pop(&c.queue).(nonceEntry).nonce)
+	}
+	c.expires[nonce] = expiresAt
+	heap.Push(&c.queue, nonceEntry{nonce: nonce, expiresAt: expiresAt})
+	return true
+}
+
+// Len returns number of remembered nonces.
+func (c *NonceCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
+
+type nonceEntry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// nonceQueue is min-heap of nonces by expiration time.
+type nonceQueue []nonceEntry
+
+func (q nonceQueue) Len() int           { return len(q) }
+func (q nonceQueue) Less(i, j int) bool { return q[i].expiresAt.Before(q[j].expiresAt) }
+func (q nonceQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *nonceQueue) Push(x any) { *q = append(*q, x.(nonceEntry)) }
+
+func (q *nonceQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+)
+
+// SignatureV2 is value of HashVersion header of requests signed with SignRequest.
+// Requests without the header are signed with body only (version 1), see HashChecker.
+const SignatureV2 = "2"
+
+// SignatureConfig configures verification of request signatures, see SignatureChecker.
+type SignatureConfig struct {
+	Strict         bool          // reject unsigned writes, see RequireSignature
+	MaxSkew        time.Duration // allowed difference between request timestamp and server clock
+	NonceCacheSize int           // number of remembered nonces, must exceed number of signed requests in 2*MaxSkew
+	AcceptV1       bool          // accept body-only signatures, they don't protect against replay
+	Nonces         *NonceCache   // seen nonces, shared by HTTP and gRPC; created from NonceCacheSize if nil
+	MaxBodySize    int64         // limit of request body, larger requests are rejected with 413; 0 disables limit
+}
+
+// Errors of v2 signature timestamp, see SignatureConfig.CheckTimestamp.
+var (
+	ErrMalformedTimestamp     = errors.New("missing signature timestamp or nonce")
+	ErrTimestampOutsideWindow = errors.New("signature timestamp is outside of allowed window")
+)
+
+// WithNonceCache returns config with nonce cache, so checkers created from it share seen nonces.
+func (c SignatureConfig) WithNonceCache() SignatureConfig {
+	if c.Nonces == nil {
+		c.Nonces = NewNonceCache(c.NonceCacheSize)
+	}
+	return c
+}
+
+// CheckTimestamp parses unix timestamp of v2 signature and checks that it's within MaxSkew of now.
+func (c SignatureConfig) CheckTimestamp(timestamp string, now time.Time) (time.Time, error) {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrMalformedTimestamp
+	}
+	signedAt := time.Unix(unix, 0)
+	if signedAt.Before(now.Add(-c.MaxSkew)) || signedAt.After(now.Add(c.MaxSkew)) {
+		return time.Time{}, ErrTimestampOutsideWindow
+	}
+	return signedAt, nil
+}
+
+// RememberNonce reports whether nonce of request signed at signedAt is seen first time.
+// Nonce is remembered until timestamp leaves the window, later the request is rejected by timestamp.
+func (c SignatureConfig) RememberNonce(nonce string, signedAt, now time.Time) bool {
+	return c.Nonces.Add(nonce, signedAt.Add(c.MaxSkew), now)
+}
+
+// NewSignatureParams returns current timestamp and random nonce of v2 signature.
+func NewSignatureParams() (timestamp, nonce string, err error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return strconv.FormatInt(time.Now().Unix(), 10), hex.EncodeToString(buf), nil
+}
+
+// DefaultSignatureConfig accepts v2 signatures only.
+var DefaultSignatureConfig = SignatureConfig{
+	MaxSkew:        5 * time.Minute,
+	NonceCacheSize: 100_000,
+	MaxBodySize:    32 << 20,
+}
+
+// CanonicalRequest returns string signed by v2 scheme: method, escaped path, canonical query,
+// hex SHA-256 of body, unix timestamp in seconds and nonce, separated by new lines.
+func CanonicalRequest(method, path, rawQuery string, body []byte, timestamp, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	return canonicalRequest(method, path, rawQuery, hex.EncodeToString(bodyHash[:]), timestamp, nonce)
+}
+
+func canonicalRequest(method, path, rawQuery, bodyHash, timestamp, nonce string) string {
+	return strings.Join([]string{method, path, canonicalQuery(rawQuery), bodyHash, timestamp, nonce}, "\n")
+}
+
+// canonicalQuery encodes query parameters sorted by name, so signature doesn't depend on their order
+// and escaping. Malformed query is signed as is.
+func canonicalQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// SignRequest signs req with v2 scheme: sets HashVersion, HashTimestamp, HashNonce and HashSHA256 headers.
+// Body is request body as sent, it's not read from req.
+func SignRequest(req *http.Request, key string, body []byte) error {
+	timestamp, nonce, err := NewSignatureParams()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(constants.HashVersion, SignatureV2)
+	req.Header.Set(constants.HashTimestamp, timestamp)
+	req.Header.Set(constants.HashNonce, nonce)
+	req.Header.Set(constants.HashSHA256, Sign(key, []byte(CanonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.RawQuery, body, timestamp, nonce))))
+	return nil
+}
+
+// SignatureChecker returns a middleware that checks signature of request if HashSHA256 header exists.
+// Version 2 signatures are checked against canonical request, requests with timestamp outside of
+// config.MaxSkew or with already seen nonce are rejected. Version 1 signatures are passed to
+// KeyringHashChecker if config.AcceptV1 is set. Malformed signature headers are rejected with 400,
+// signatures that don't authenticate request with 401. Body stays available to next handler.
+// Bodies of all requests are limited by config.MaxBodySize, so unauthenticated client can't make
+// server spool unbounded body before signature is checked.
+func SignatureChecker(keyring *Keyring, config SignatureConfig) func(http.Handler) http.Handler {
+	config = config.WithNonceCache()
+
+	return func(next http.Handler) http.Handler {
+		v1 := KeyringHashChecker(keyring)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.MaxBodySize > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, config.MaxBodySize)
+			}
+			if keyring.Empty() || r.Header.Get(constants.HashSHA256) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Header.Get(constants.HashVersion) != SignatureV2 {
+				if !config.AcceptV1 {
+					unauthorized(w, "Signature version 1 is not accepted")
+					return
+				}
+				v1.ServeHTTP(w, r)
+				return
+			}
+
+			hashData, err := hex.DecodeString(r.Header.Get(constants.HashSHA256))
+			if err != nil {
+				http.Error(w, "Invalid hash format", http.StatusBadRequest)
+				return
+			}
+
+			timestamp := r.Header.Get(constants.HashTimestamp)
+			nonce := r.Header.Get(constants.HashNonce)
+			current := time.Now()
+			signedAt, err := config.CheckTimestamp(timestamp, current)
+			if errors.Is(err, ErrMalformedTimestamp) || nonce == "" {
+				http.Error(w, "Missing signature timestamp or nonce", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				unauthorized(w, "Signature timestamp is outside of allowed window")
+				return
+			}
+
+			key, ok := keyring.Lookup(r.Header.Get(constants.HashKeyID))
+			if !ok {
+				unauthorized(w, "Unknown or expired hash key")
+				return
+			}
+
+			bodyHash := sha256.New()
+			body, err := spoolBody(r.Body, bodyHash)
+			if err != nil {
+				bodyError(w, err)
+				return
+			}
+			defer body.Close()
+			r.Body = body
+
+			h := hmac.New(sha256.New, []byte(key.Secret))
+			h.Write([]byte(canonicalRequest(r.Method, r.URL.EscapedPath(), r.URL.RawQuery, hex.EncodeToString(bodyHash.Sum(nil)), timestamp, nonce)))
+			if !hmac.Equal(h.Sum(nil), hashData) {
+				unauthorized(w, "Invalid hash")
+				return
+			}
+
+			if !config.RememberNonce(nonce, signedAt, current) {
+				unauthorized(w, "Replayed request")
+				return
+			}
+
+			next.ServeHTTP(w, markVerified(r, key))
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+)
+
+func newSignedRequest(t *testing.T, path string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	require.NoError(t, SignRequest(req, testKey, body))
+	return req
+}
+
+// resign replaces timestamp of request and signs it again with the same nonce.
+func resign(req *http.Request, body []byte, signedAt time.Time) {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	req.Header.Set(constants.HashTimestamp, timestamp)
+	canonical := CanonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.RawQuery, body, timestamp, req.Header.Get(constants.HashNonce))
+	req.Header.Set(constants.HashSHA256, Sign(testKey, []byte(canonical)))
+}
+
+func TestSignatureChecker(t *testing.T) {
+	body := []byte(`[{"id":"requests","type":"counter","delta":1}]`)
+
+	tests := []struct {
+		name     string
+		config   SignatureConfig
+		prepare  func(t *testing.T) *http.Request
+		wantCode int
+	}{
+		{
+			name:   "valid",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, "/updates/", body)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "unsigned",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "different path",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/updates/", body)
+				req.URL.Path = "/update/"
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "reordered query",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/update/?label=host%3Da&label=dc%3Db&instance=x", body)
+				req.URL.RawQuery = "instance=x&label=host=a&label=dc%3Db"
+				return req
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "different query",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/update/?label=host%3Da", body)
+				req.URL.RawQuery = "label=host%3Db"
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "added query",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/updates/", body)
+				req.URL.RawQuery = "label=host%3Db"
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "different method",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/updates/", body)
+				req.Method = http.MethodPut
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "tampered body",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/updates/", body)
+				req.Body = io.NopCloser(bytes.NewReader(bytes.Replace(body, []byte("1"), []byte("9"), 1)))
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "stale timestamp",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/updates/", body)
+				resign(req, body, time.Now().Add(-DefaultSignatureConfig.MaxSkew-time.Minute))
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "future timestamp",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/updates/", body)
+				resign(req, body, time.Now().Add(DefaultSignatureConfig.MaxSkew+time.Minute))
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "missing nonce",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := newSignedRequest(t, "/updates/", body)
+				req.Header.Del(constants.HashNonce)
+				return req
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:   "v1 rejected by default",
+			config: DefaultSignatureConfig,
+			prepare: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+				req.Header.Set(constants.HashSHA256, Sign(testKey, body))
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "v1 accepted if enabled",
+			config: SignatureConfig{MaxSkew: time.Minute, NonceCacheSize: 10, AcceptV1: true},
+			prepare: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+				req.Header.Set(constants.HashSHA256, Sign(testKey, body))
+				return req
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []byte
+			handler := SignatureChecker(NewKeyring(testKey), tt.config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ = io.ReadAll(r.Body)
+			}))
+
+			rec := httptest.NewRecorder()
+			req := tt.prepare(t)
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			if tt.wantCode == http.StatusOK && req.Header.Get(constants.HashVersion) == SignatureV2 {
+				assert.Equal(t, body, received)
+			}
+		})
+	}
+}
+
+func TestSignatureCheckerReplay(t *testing.T) {
+	body := []byte("body")
+	handler := SignatureChecker(NewKeyring(testKey), DefaultSignatureConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := newSignedRequest(t, "/updates/", body)
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, replay)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Replayed")
+}
+
+func TestSignatureCheckerBodyLimit(t *testing.T) {
+	config := SignatureConfig{MaxSkew: time.Minute, NonceCacheSize: 10, AcceptV1: true, MaxBodySize: maxMemoryBody}
+	body := bytes.Repeat([]byte("a"), 2*maxMemoryBody)
+	called := false
+	handler := SignatureChecker(NewKeyring(testKey), config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	// Body with forged signature is rejected before it's spooled.
+	for _, version := range []string{"", SignatureV2} {
+		req := newSignedRequest(t, "/updates/", []byte("other"))
+		req.Header.Set(constants.HashVersion, version)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "version %q", version)
+		assert.False(t, called)
+	}
+
+	// Unsigned body is limited too.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.True(t, called)
+
+	req := newSignedRequest(t, "/updates/", body[:maxMemoryBody])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestNonceCache(t *testing.T) {
+	now := time.Now()
+	cache := NewNonceCache(2)
+
+	assert.True(t, cache.Add("a", now.Add(time.Minute), now))
+	assert.False(t, cache.Add("a", now.Add(time.Minute), now))
+	assert.True(t, cache.Add("b", now.Add(2*time.Minute), now))
+
+	// Full cache forgets nonce that expires first.
+	assert.True(t, cache.Add("c", now.Add(3*time.Minute), now))
+	assert.Equal(t, 2, cache.Len())
+	assert.True(t, cache.Add("a", now.Add(time.Minute), now))
+	assert.Equal(t, 2, cache.Len())
+
+	// Expired nonces are dropped.
+	later := now.Add(10 * time.Minute)
+	assert.True(t, cache.Add("d", later.Add(time.Minute), later))
+	assert.Equal(t, 1, cache.Len())
+}
@@ -0,0 +1,38 @@
+// Package config contains types shared by configuration files of server and its components.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is decoded from strings like "30s" or "5m".
+type Duration time.Duration
+
+// UnmarshalJSON parses duration from string or from number of seconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(time.Duration(v * float64(time.Second)))
+		return nil
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+		return nil
+	default:
+		return fmt.Errorf("invalid duration: %s", string(b))
+	}
+}
+
+// MarshalJSON writes duration as string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
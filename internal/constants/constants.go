@@ -1,10 +1,16 @@
 package constants
 
 const (
-	Counter = "counter"
-	Gauge   = "gauge"
+	Counter   = "counter"
+	Gauge     = "gauge"
+	Histogram = "histogram"
+	Summary   = "summary"
 )
 
 const (
-	HashSHA256 = "HashSHA256"
+	HashSHA256    = "HashSHA256"
+	HashKeyID     = "HashKeyID"     // ID of keyring key used for HashSHA256
+	HashVersion   = "HashVersion"   // version of signature scheme, body-only signature if missing
+	HashTimestamp = "HashTimestamp" // unix time of signature, version 2
+	HashNonce     = "HashNonce"     // unique value of signed request, version 2
 )
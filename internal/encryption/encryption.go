@@ -0,0 +1,192 @@
+// Package encryption implements hybrid encryption of request bodies: random AES-256-GCM key
+// encrypts the body and is itself encrypted with RSA-OAEP public key of server.
+//
+// Agent prepares body in this order: JSON, gzip, signature over compressed body, encryption.
+// Server undoes it in reverse order: Decrypter, auth.SignatureChecker, compressor.Decompressor.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Header marks encrypted request, its value is Scheme.
+const Header = "X-Encryption"
+
+// Scheme is the only supported encryption scheme.
+const Scheme = "rsa-oaep-sha256+aes-256-gcm"
+
+const aesKeySize = 32
+
+// Encrypt encrypts plaintext for owner of key. Result is RSA-OAEP encrypted AES key,
+// followed by GCM nonce and AES-GCM ciphertext with tag.
+func Encrypt(key *rsa.PublicKey, plaintext []byte) ([]byte, error) {
+	aesKey := make([]byte, aesKeySize)
+	if _, err := rand.Read(aesKey); err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, aesKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt AES key: %w", err)
+	}
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(wrappedKey)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, wrappedKey...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts ciphertext produced by Encrypt with public part of key.
+func Decrypt(key *rsa.PrivateKey, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < key.Size() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), nil, key, ciphertext[:key.Size()], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt AES key: %w", err)
+	}
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := ciphertext[key.Size():]
+	if len(rest) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ParsePublicKey parses RSA public key from PEM: certificate, PKIX or PKCS #1 public key.
+// So the same certificate can be used by agent as TLS root CA and for encryption.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("key is not RSA key")
+	}
+	return rsaKey, nil
+}
+
+// ParsePrivateKey parses RSA private key from PEM: PKCS #1 or PKCS #8.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("key is not RSA key")
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// maxEncryptedBodySize limits body read by Decrypter.
+const maxEncryptedBodySize = 32 << 20
+
+// Decrypter returns a middleware that decrypts body of requests with Header.
+// Encrypted requests are rejected if key is nil. Other requests are passed as is.
+func Decrypter(key *rsa.PrivateKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := r.Header.Get(Header)
+			if scheme == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if key == nil {
+				http.Error(w, "Encrypted requests are not supported", http.StatusBadRequest)
+				return
+			}
+			if scheme != Scheme {
+				http.Error(w, "Unsupported encryption scheme", http.StatusBadRequest)
+				return
+			}
+
+			ciphertext, err := io.ReadAll(io.LimitReader(r.Body, maxEncryptedBodySize))
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+
+			plaintext, err := Decrypt(key, ciphertext)
+			if err != nil {
+				http.Error(w, "Failed to decrypt request body", http.StatusBadRequest)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(plaintext))
+			r.ContentLength = int64(len(plaintext))
+			r.Header.Del(Header)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := generateKey(t)
+	plaintext := []byte(`[{"id":"temp","type":"gauge","value":1}]`)
+
+	ciphertext, err := Encrypt(&key.PublicKey, plaintext)
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "temp")
+
+	decrypted, err := Decrypt(key, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// Ciphertext is authenticated.
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = Decrypt(key, ciphertext)
+	assert.Error(t, err)
+
+	// Data encrypted for another key can't be decrypted.
+	ciphertext, err = Encrypt(&generateKey(t).PublicKey, plaintext)
+	require.NoError(t, err)
+	_, err = Decrypt(key, ciphertext)
+	assert.Error(t, err)
+
+	_, err = Decrypt(key, []byte("short"))
+	assert.Error(t, err)
+}
+
+func TestParseKeys(t *testing.T) {
+	key := generateKey(t)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "server"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	publicPEMs := map[string][]byte{
+		"CERTIFICATE":    cert,
+		"PUBLIC KEY":     pkix,
+		"RSA PUBLIC KEY": x509.MarshalPKCS1PublicKey(&key.PublicKey),
+	}
+	for blockType, der := range publicPEMs {
+		t.Run(blockType, func(t *testing.T) {
+			parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+			require.NoError(t, err)
+			assert.True(t, key.PublicKey.Equal(parsed))
+		})
+	}
+
+	privatePEMs := map[string][]byte{
+		"RSA PRIVATE KEY": x509.MarshalPKCS1PrivateKey(key),
+		"PRIVATE KEY":     pkcs8,
+	}
+	for blockType, der := range privatePEMs {
+		t.Run(blockType, func(t *testing.T) {
+			parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+			require.NoError(t, err)
+			assert.True(t, key.Equal(parsed))
+		})
+	}
+
+	_, err = ParsePublicKey([]byte("not pem"))
+	assert.Error(t, err)
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{1}}))
+	assert.Error(t, err)
+}
+
+func TestDecrypter(t *testing.T) {
+	key := generateKey(t)
+	plaintext := []byte("body")
+	ciphertext, err := Encrypt(&key.PublicKey, plaintext)
+	require.NoError(t, err)
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+
+	tests := []struct {
+		name         string
+		key          *rsa.PrivateKey
+		scheme       string
+		body         []byte
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "encrypted", key: key, scheme: Scheme, body: ciphertext, expectedCode: http.StatusOK, expectedBody: "body"},
+		{name: "not encrypted", key: key, body: plaintext, expectedCode: http.StatusOK, expectedBody: "body"},
+		{name: "not encrypted without key", body: plaintext, expectedCode: http.StatusOK, expectedBody: "body"},
+		{name: "encrypted without key", scheme: Scheme, body: ciphertext, expectedCode: http.StatusBadRequest},
+		{name: "unknown scheme", key: key, scheme: "rot13", body: ciphertext, expectedCode: http.StatusBadRequest},
+		{name: "corrupted", key: key, scheme: Scheme, body: plaintext, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(tt.body))
+			if tt.scheme != "" {
+				req.Header.Set(Header, tt.scheme)
+			}
+			res := httptest.NewRecorder()
+
+			Decrypter(tt.key)(echo).ServeHTTP(res, req)
+
+			assert.Equal(t, tt.expectedCode, res.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, res.Body.String())
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"html"
+	"math"
 	"os"
 	"sort"
 	"strconv"
@@ -32,23 +33,77 @@ func ExtractValue(m models.Metric) (string, error) {
 			return strconv.FormatInt(*m.Delta, 10), nil
 		}
 		return "", errors.New("metric delta not found")
+	case constants.Histogram, constants.Summary:
+		quantiles, err := Quantiles(m)
+		if err != nil {
+			return "", err
+		}
+		parts := make([]string, 0, len(models.ReportedQuantiles))
+		for _, q := range models.ReportedQuantiles {
+			name := QuantileName(q)
+			parts = append(parts, name+"="+strconv.FormatFloat(quantiles[name], 'f', -1, 64))
+		}
+		return strings.Join(parts, " "), nil
 	default:
 		return "", errors.New("unknown metric type")
 	}
 }
 
+// Quantiles returns models.ReportedQuantiles of histogram or summary metric by their names, e.g. "p50".
+func Quantiles(m models.Metric) (map[string]float64, error) {
+	var quantile func(q float64) float64
+	switch {
+	case m.MType == constants.Histogram && m.Histogram != nil:
+		quantile = m.Histogram.Quantile
+	case m.MType == constants.Summary && m.Summary != nil:
+		quantile = m.Summary.Quantile
+	default:
+		return nil, errors.New("metric distribution not found")
+	}
+
+	quantiles := make(map[string]float64, len(models.ReportedQuantiles))
+	for _, q := range models.ReportedQuantiles {
+		value := quantile(q)
+		if math.IsNaN(value) {
+			value = 0
+		}
+		quantiles[QuantileName(q)] = value
+	}
+	return quantiles, nil
+}
+
+// QuantileName formats quantile as percentile name, e.g. 0.99 -> "p99".
+func QuantileName(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'f', -1, 64)
+}
+
 // ValidateMetric checks if a metric is valid based on its type and fields.
 func ValidateMetric(m models.Metric) bool {
 	if m.ID == "" {
 		return false
 	}
-	return (m.MType == constants.Gauge && m.Value != nil) ||
-		(m.MType == constants.Counter && m.Delta != nil)
+	for name := range m.Labels {
+		if !models.ValidLabelName(name) {
+			return false
+		}
+	}
+	switch m.MType {
+	case constants.Gauge:
+		return m.Value != nil
+	case constants.Counter:
+		return m.Delta != nil
+	case constants.Histogram:
+		return m.Histogram != nil && m.Histogram.Validate() == nil
+	case constants.Summary:
+		return m.Summary != nil && m.Summary.Validate() == nil
+	default:
+		return false
+	}
 }
 
 // UpdateCounterMetric aggregates Counter metrics
 func UpdateCounterMetric(old, new models.Metric) (models.Metric, error) {
-	if old.ID != new.ID || old.MType != constants.Counter || new.MType != constants.Counter {
+	if old.Key() != new.Key() || old.MType != constants.Counter || new.MType != constants.Counter {
 		return old, errors.New("metric mismatch")
 	}
 	if old.Delta == nil || new.Delta == nil {
@@ -59,30 +114,66 @@ func UpdateCounterMetric(old, new models.Metric) (models.Metric, error) {
 	return new, nil
 }
 
-// UpdateMetricInMap updates a metric in the given map, creating or updating as needed.
-func UpdateMetricInMap(metrics map[string]models.Metric, metric models.Metric, logger *zap.SugaredLogger) (models.Metric, error) {
-	if metric.MType == constants.Gauge {
-		metrics[metric.ID] = metric
-		return metric, nil
+// MergeMetric combines stored metric with new one: gauge is replaced, counter, histogram and summary are accumulated.
+func MergeMetric(old, new models.Metric) (models.Metric, error) {
+	if old.Key() != new.Key() {
+		return old, errors.New("metric mismatch")
 	}
 
-	if metric.MType == constants.Counter {
-		if existingMetric, found := metrics[metric.ID]; found {
-			updatedMetric, err := UpdateCounterMetric(existingMetric, metric)
-			if err != nil {
-				logger.Errorw("fail to update counter Metric", "metric_id", metric.ID, "error", err.Error())
-				return models.Metric{}, err
-			}
-			metrics[metric.ID] = updatedMetric
-			return updatedMetric, nil
+	switch new.MType {
+	case constants.Gauge:
+		return new, nil
+	case constants.Counter:
+		return UpdateCounterMetric(old, new)
+	case constants.Histogram:
+		if old.Histogram == nil || new.Histogram == nil {
+			return old, errors.New("metric histogram missing")
+		}
+		merged, err := old.Histogram.Merge(new.Histogram)
+		if err != nil {
+			return old, err
+		}
+		new.Histogram = merged
+		return new, nil
+	case constants.Summary:
+		if old.Summary == nil || new.Summary == nil {
+			return old, errors.New("metric summary missing")
+		}
+		merged, err := old.Summary.Merge(new.Summary)
+		if err != nil {
+			return old, err
 		}
-		metrics[metric.ID] = metric
+		new.Summary = merged
+		return new, nil
+	default:
+		return old, errors.New("unknown metric type")
+	}
+}
+
+// UpdateMetricInMap updates a metric in the given map keyed by models.Metric.Key, creating or updating as needed.
+func UpdateMetricInMap(metrics map[string]models.Metric, metric models.Metric, logger *zap.SugaredLogger) (models.Metric, error) {
+	switch metric.MType {
+	case constants.Gauge, constants.Counter, constants.Histogram, constants.Summary:
+	default:
+		err := fmt.Errorf("unsupported Metric type: %s", metric.MType)
+		logger.Errorw("error storing Metric", "metric_id", metric.ID, "error", err.Error())
+		return models.Metric{}, err
+	}
+
+	key := metric.Key()
+	existingMetric, found := metrics[key]
+	if !found {
+		metrics[key] = metric
 		return metric, nil
 	}
 
-	err := fmt.Errorf("unsupported Metric type: %s", metric.MType)
-	logger.Errorw("error storing Metric", "metric_id", metric.ID, "error", err.Error())
-	return models.Metric{}, err
+	updatedMetric, err := MergeMetric(existingMetric, metric)
+	if err != nil {
+		logger.Errorw("fail to update Metric", "metric_id", metric.ID, "metric_type", metric.MType, "labels", metric.Labels, "error", err.Error())
+		return models.Metric{}, err
+	}
+	metrics[key] = updatedMetric
+	return updatedMetric, nil
 }
 
 // MetricsToHTML generates an HTML table of metrics, using a builder for performance.
@@ -110,15 +201,15 @@ func MetricsToHTML(metrics map[string]models.Metric, logger *zap.SugaredLogger)
 	return htmlBuilder.String(), nil
 }
 
-// ProcessMetricsDuplicates consolidates duplicate metrics in a slice by ID and type.
+// ProcessMetricsDuplicates consolidates duplicate metrics in a slice by key.
 func ProcessMetricsDuplicates(metrics []models.Metric) ([]models.Metric, error) {
 	metricMap := make(map[string]models.Metric)
 
 	for _, metric := range metrics {
-		key := metric.ID + "_" + metric.MType
+		key := metric.Key()
 
-		if existingMetric, exists := metricMap[key]; exists && metric.MType == constants.Counter {
-			updatedMetric, err := UpdateCounterMetric(existingMetric, metric)
+		if existingMetric, exists := metricMap[key]; exists {
+			updatedMetric, err := MergeMetric(existingMetric, metric)
 			if err != nil {
 				return metrics, err
 			}
@@ -146,6 +237,7 @@ func LogMetric(message string, metric models.Metric, logger *zap.SugaredLogger)
 		message,
 		"metric_id", metric.ID,
 		"metric_type", metric.MType,
+		"metric_labels", metric.Labels,
 		"metric_value", metric.Value,
 		"metric_delta", metric.Delta,
 	)
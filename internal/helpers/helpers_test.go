@@ -52,6 +52,18 @@ func TestExtractValue(t *testing.T) {
 			want:    "",
 			wantErr: true,
 		},
+		{
+			name:    "Extract histogram quantiles",
+			args:    models.Metric{ID: "metric6", MType: constants.Histogram, Histogram: &models.Histogram{Bounds: []float64{1, 2}, Counts: []uint64{0, 10, 0}, Count: 10}},
+			want:    "p50=1.5 p90=1.9 p99=1.99",
+			wantErr: false,
+		},
+		{
+			name:    "Histogram is nil",
+			args:    models.Metric{ID: "metric7", MType: constants.Summary},
+			want:    "",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -162,6 +174,60 @@ func TestUpdateCounterMetric(t *testing.T) {
 	}
 }
 
+func TestMergeMetric(t *testing.T) {
+	histogram := func(values ...float64) *models.Histogram {
+		h := models.NewHistogram([]float64{1, 10})
+		for _, v := range values {
+			h.Observe(v)
+		}
+		return h
+	}
+	summary := func(values ...float64) *models.Sketch {
+		s := models.NewSketch(models.DefaultSketchAccuracy)
+		for _, v := range values {
+			s.Observe(v)
+		}
+		return s
+	}
+
+	t.Run("Gauge is replaced", func(t *testing.T) {
+		got, err := MergeMetric(
+			models.Metric{ID: "m", MType: constants.Gauge, Value: float64Ptr(1)},
+			models.Metric{ID: "m", MType: constants.Gauge, Value: float64Ptr(2)},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 2.0, *got.Value)
+	})
+
+	t.Run("Histogram buckets are added", func(t *testing.T) {
+		got, err := MergeMetric(
+			models.Metric{ID: "m", MType: constants.Histogram, Histogram: histogram(0.5, 5)},
+			models.Metric{ID: "m", MType: constants.Histogram, Histogram: histogram(50)},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{1, 1, 1}, got.Histogram.Counts)
+		assert.Equal(t, 55.5, got.Histogram.Sum)
+	})
+
+	t.Run("Summary sketches are merged", func(t *testing.T) {
+		got, err := MergeMetric(
+			models.Metric{ID: "m", MType: constants.Summary, Summary: summary(1, 2)},
+			models.Metric{ID: "m", MType: constants.Summary, Summary: summary(3)},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(3), got.Summary.Count)
+		assert.Equal(t, 6.0, got.Summary.Sum)
+	})
+
+	t.Run("Different types", func(t *testing.T) {
+		_, err := MergeMetric(
+			models.Metric{ID: "m", MType: constants.Gauge, Value: float64Ptr(1)},
+			models.Metric{ID: "m", MType: constants.Counter, Delta: int64Ptr(1)},
+		)
+		assert.Error(t, err)
+	})
+}
+
 func TestMetricsToHTML(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -418,6 +484,21 @@ func TestProcessMetricsDuplicates(t *testing.T) {
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "Same ID with different labels",
+			args: args{
+				metrics: []models.Metric{
+					{ID: "metric1", MType: constants.Counter, Delta: int64Ptr(10), Labels: map[string]string{"host": "a"}},
+					{ID: "metric1", MType: constants.Counter, Delta: int64Ptr(5), Labels: map[string]string{"host": "b"}},
+					{ID: "metric1", MType: constants.Counter, Delta: int64Ptr(1), Labels: map[string]string{"host": "a"}},
+				},
+			},
+			want: []models.Metric{
+				{ID: "metric1", MType: constants.Counter, Delta: int64Ptr(11), Labels: map[string]string{"host": "a"}},
+				{ID: "metric1", MType: constants.Counter, Delta: int64Ptr(5), Labels: map[string]string{"host": "b"}},
+			},
+			wantErr: assert.NoError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/mtls"
 )
 
 type (
@@ -52,6 +54,7 @@ func WithLogger(h http.Handler, s *zap.SugaredLogger) http.HandlerFunc {
 			"duration", duration,
 			"status", responseData.status,
 			"size", responseData.size,
+			"agent", mtls.Identity(r.TLS),
 		)
 	}
 	return logFn
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// DefaultBuckets are upper bounds of histogram buckets used when client doesn't provide its own.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram counts observations in buckets with configurable upper bounds.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"` // inclusive upper bounds of buckets in increasing order
+	Counts []uint64  `json:"counts"` // observations per bucket, the last one has no upper bound
+	Sum    float64   `json:"sum"`    // sum of all observations
+	Count  uint64    `json:"count"`  // number of observations
+}
+
+// NewHistogram creates empty histogram with given bucket bounds.
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		Bounds: append([]float64(nil), bounds...),
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe adds single value to histogram.
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.Bounds, value)
+	h.Counts[i]++
+	h.Sum += value
+	h.Count++
+}
+
+// Validate checks that bounds are increasing and counts are consistent.
+func (h *Histogram) Validate() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return errors.New("histogram must have one more count than bounds")
+	}
+	for i := 1; i < len(h.Bounds); i++ {
+		if !(h.Bounds[i-1] < h.Bounds[i]) {
+			return errors.New("histogram bounds must be increasing")
+		}
+	}
+	var total uint64
+	for _, count := range h.Counts {
+		total += count
+	}
+	if total != h.Count {
+		return errors.New("histogram count doesn't match bucket counts")
+	}
+	return nil
+}
+
+// Merge returns new histogram with observations of both histograms. Bounds must be equal.
+func (h *Histogram) Merge(other *Histogram) (*Histogram, error) {
+	if len(h.Bounds) != len(other.Bounds) || len(h.Counts) != len(other.Counts) {
+		return nil, errors.New("histogram bounds mismatch")
+	}
+	for i := range h.Bounds {
+		if h.Bounds[i] != other.Bounds[i] {
+			return nil, errors.New("histogram bounds mismatch")
+		}
+	}
+
+	merged := NewHistogram(h.Bounds)
+	for i := range h.Counts {
+		merged.Counts[i] = h.Counts[i] + other.Counts[i]
+	}
+	merged.Sum = h.Sum + other.Sum
+	merged.Count = h.Count + other.Count
+	return merged, nil
+}
+
+// Quantile estimates q-quantile assuming uniform distribution inside bucket.
+// Values in the last bucket are reported as the highest bound. Returns NaN for empty histogram.
+func (h *Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(h.Count)
+	var cumulative uint64
+	for i, count := range h.Counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		if i == len(h.Bounds) {
+			if len(h.Bounds) == 0 {
+				return h.Sum / float64(h.Count)
+			}
+			return h.Bounds[len(h.Bounds)-1]
+		}
+
+		lower := 0.0
+		if i > 0 {
+			lower = h.Bounds[i-1]
+		} else if h.Bounds[0] <= 0 {
+			return h.Bounds[0]
+		}
+		upper := h.Bounds[i]
+		return lower + (upper-lower)*(rank-float64(cumulative))/float64(count)
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
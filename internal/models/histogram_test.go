@@ -0,0 +1,49 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 4})
+	for _, v := range []float64{0.5, 1.5, 1.5, 3, 10} {
+		h.Observe(v)
+	}
+
+	require.NoError(t, h.Validate())
+	assert.Equal(t, []uint64{1, 2, 1, 1}, h.Counts)
+	assert.Equal(t, uint64(5), h.Count)
+	assert.Equal(t, 16.5, h.Sum)
+
+	t.Run("Quantiles", func(t *testing.T) {
+		assert.InDelta(t, 1.75, h.Quantile(0.5), 1e-9)
+		assert.Equal(t, 4.0, h.Quantile(0.99))
+		assert.True(t, math.IsNaN(NewHistogram(DefaultBuckets).Quantile(0.5)))
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		other := NewHistogram([]float64{1, 2, 4})
+		other.Observe(0.1)
+
+		merged, err := h.Merge(other)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{2, 2, 1, 1}, merged.Counts)
+		assert.Equal(t, uint64(6), merged.Count)
+		assert.Equal(t, uint64(5), h.Count, "merge must not modify source")
+	})
+
+	t.Run("Merge with other bounds", func(t *testing.T) {
+		_, err := h.Merge(NewHistogram([]float64{1, 2}))
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Error(t, (&Histogram{Bounds: []float64{2, 1}, Counts: []uint64{0, 0, 0}}).Validate())
+		assert.Error(t, (&Histogram{Bounds: []float64{1}, Counts: []uint64{1}}).Validate())
+		assert.Error(t, (&Histogram{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 1}).Validate())
+	})
+}
@@ -2,20 +2,77 @@ package models
 
 import (
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/VOTONO/go-metrics/internal/constants"
 )
 
-// Metric struct defines a metric with an ID, type, and either a Delta or Value
+// Metric struct defines a metric with an ID, type, and value field of that type
 type Metric struct {
-	ID    string   `json:"id"`              // metric`s name
-	MType string   `json:"type"`            // metric`s type ("counter" or "gauge")
-	Delta *int64   `json:"delta,omitempty"` // metric`s value for "counter" type
-	Value *float64 `json:"value,omitempty"` // metric`s value for "gauge" type
+	ID        string            `json:"id"`                  // metric`s name
+	MType     string            `json:"type"`                // metric`s type ("counter", "gauge", "histogram" or "summary")
+	Delta     *int64            `json:"delta,omitempty"`     // metric`s value for "counter" type
+	Value     *float64          `json:"value,omitempty"`     // metric`s value for "gauge" type
+	Histogram *Histogram        `json:"histogram,omitempty"` // metric`s value for "histogram" type
+	Summary   *Sketch           `json:"summary,omitempty"`   // metric`s value for "summary" type
+	Labels    map[string]string `json:"labels,omitempty"`    // metric`s dimensions, part of identity
 }
 
-// NewMetric is a factory method to create a new Metric based on the type and value provided
+// ReportedQuantiles are quantiles returned for histogram and summary metrics.
+var ReportedQuantiles = []float64{0.5, 0.9, 0.99}
+
+var (
+	labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	labelEscaper    = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// Key returns identity of metric in storage.
+func (m Metric) Key() string {
+	return MetricKey(m.MType, m.ID, m.Labels)
+}
+
+// MetricKey builds identity of metric: type, ID and labels sorted by name, e.g. `gauge:HeapAlloc{host="a"}`.
+// Labels part is omitted for metric without labels, so counter and gauge with the same ID never collide.
+func MetricKey(mType, id string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return mType + ":" + id
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(mType)
+	b.WriteByte(':')
+	b.WriteString(id)
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// ValidLabelName checks that label name is non-empty and consists of letters, digits and underscores.
+func ValidLabelName(name string) bool {
+	return labelNameRegexp.MatchString(name)
+}
+
+// NewMetric is a factory method to create a new Metric based on the type and value provided.
+// Value of histogram and summary is a single observation, histogram uses DefaultBuckets.
 func NewMetric(id string, metricType string, value string) (Metric, error) {
 	var metric Metric
 	var err error
@@ -43,6 +100,23 @@ func NewMetric(id string, metricType string, value string) (Metric, error) {
 			MType: constants.Counter,
 			Delta: &v,
 		}
+	case constants.Histogram, constants.Summary:
+		var v float64
+		v, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return Metric{}, fmt.Errorf("invalid metric observation: %s", value)
+		}
+		metric = Metric{
+			ID:    id,
+			MType: metricType,
+		}
+		if metricType == constants.Histogram {
+			metric.Histogram = NewHistogram(DefaultBuckets)
+			metric.Histogram.Observe(v)
+		} else {
+			metric.Summary = NewSketch(DefaultSketchAccuracy)
+			metric.Summary.Observe(v)
+		}
 	default:
 		return Metric{}, fmt.Errorf("invalid metric type")
 	}
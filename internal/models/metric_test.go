@@ -95,3 +95,42 @@ func TestNewMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		mType  string
+		id     string
+		labels map[string]string
+		want   string
+	}{
+		{
+			name:  "No labels",
+			mType: constants.Gauge,
+			id:    "HeapAlloc",
+			want:  "gauge:HeapAlloc",
+		},
+		{
+			name:   "Labels sorted by name",
+			mType:  constants.Gauge,
+			id:     "HeapAlloc",
+			labels: map[string]string{"instance": "b", "host": "a"},
+			want:   `gauge:HeapAlloc{host="a",instance="b"}`,
+		},
+		{
+			name:   "Escaped value",
+			mType:  constants.Counter,
+			id:     "HeapAlloc",
+			labels: map[string]string{"host": `a"b\c`},
+			want:   `counter:HeapAlloc{host="a\"b\\c"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MetricKey(tt.mType, tt.id, tt.labels); got != tt.want {
+				t.Errorf("MetricKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
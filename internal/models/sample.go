@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Sample is a value of metric accepted by server at Timestamp.
+// Samples produced by rollups describe the whole bucket starting at Timestamp:
+// Value is the last gauge value and Delta is the sum of counter increments in the bucket.
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Delta     *int64    `json:"delta,omitempty"` // increment for "counter" type
+	Value     *float64  `json:"value,omitempty"` // value for "gauge" type
+	Min       *float64  `json:"min,omitempty"`   // minimal gauge value in bucket
+	Max       *float64  `json:"max,omitempty"`   // maximal gauge value in bucket
+	Avg       *float64  `json:"avg,omitempty"`   // average gauge value in bucket
+	Count     int64     `json:"count,omitempty"` // number of raw samples in bucket
+}
+
+// NewSample creates sample from metric value.
+func NewSample(metric Metric, timestamp time.Time) Sample {
+	return Sample{
+		Timestamp: timestamp,
+		Delta:     metric.Delta,
+		Value:     metric.Value,
+	}
+}
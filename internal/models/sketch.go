@@ -0,0 +1,180 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// DefaultSketchAccuracy is a relative accuracy of quantiles of summary created from single value.
+const DefaultSketchAccuracy = 0.01
+
+// minIndexableValue is the smallest magnitude that gets its own bucket, smaller values are counted as zero.
+const minIndexableValue = 1e-9
+
+// Sketch is a mergeable quantile sketch (DDSketch) used by summary metrics.
+// Values are counted in logarithmic buckets, so every quantile is estimated
+// with relative error not exceeding Accuracy, and sketches are merged by adding counts.
+type Sketch struct {
+	Accuracy float64        `json:"accuracy"`
+	Positive map[int]uint64 `json:"positive,omitempty"` // bucket index -> count of positive values
+	Negative map[int]uint64 `json:"negative,omitempty"` // bucket index -> count of negative values by magnitude
+	Zero     uint64         `json:"zero,omitempty"`
+	Sum      float64        `json:"sum"`
+	Count    uint64         `json:"count"`
+	Min      float64        `json:"min"`
+	Max      float64        `json:"max"`
+}
+
+// NewSketch creates empty sketch with given relative accuracy.
+func NewSketch(accuracy float64) *Sketch {
+	return &Sketch{
+		Accuracy: accuracy,
+		Positive: make(map[int]uint64),
+		Negative: make(map[int]uint64),
+	}
+}
+
+func (s *Sketch) gamma() float64 {
+	return (1 + s.Accuracy) / (1 - s.Accuracy)
+}
+
+func (s *Sketch) index(magnitude float64) int {
+	return int(math.Ceil(math.Log(magnitude) / math.Log(s.gamma())))
+}
+
+// value returns representative of bucket which is within Accuracy of every value in it.
+func (s *Sketch) value(index int) float64 {
+	gamma := s.gamma()
+	return 2 * math.Pow(gamma, float64(index)) / (gamma + 1)
+}
+
+// Observe adds single value to sketch.
+func (s *Sketch) Observe(value float64) {
+	s.ObserveN(value, 1)
+}
+
+// ObserveN adds value to sketch n times, e.g. for sampled values.
+func (s *Sketch) ObserveN(value float64, n uint64) {
+	if n == 0 {
+		return
+	}
+	switch {
+	case value > minIndexableValue:
+		if s.Positive == nil {
+			s.Positive = make(map[int]uint64)
+		}
+		s.Positive[s.index(value)] += n
+	case value < -minIndexableValue:
+		if s.Negative == nil {
+			s.Negative = make(map[int]uint64)
+		}
+		s.Negative[s.index(-value)] += n
+	default:
+		s.Zero += n
+	}
+
+	if s.Count == 0 || value < s.Min {
+		s.Min = value
+	}
+	if s.Count == 0 || value > s.Max {
+		s.Max = value
+	}
+	s.Sum += value * float64(n)
+	s.Count += n
+}
+
+// Validate checks that accuracy is in (0, 1) and counts are consistent.
+func (s *Sketch) Validate() error {
+	if !(s.Accuracy > 0 && s.Accuracy < 1) {
+		return errors.New("sketch accuracy must be in (0, 1)")
+	}
+	total := s.Zero
+	for _, count := range s.Positive {
+		total += count
+	}
+	for _, count := range s.Negative {
+		total += count
+	}
+	if total != s.Count {
+		return errors.New("sketch count doesn't match bucket counts")
+	}
+	return nil
+}
+
+// Merge returns new sketch with values of both sketches. Accuracy must be equal.
+func (s *Sketch) Merge(other *Sketch) (*Sketch, error) {
+	if s.Accuracy != other.Accuracy {
+		return nil, errors.New("sketch accuracy mismatch")
+	}
+
+	merged := NewSketch(s.Accuracy)
+	for _, sketch := range []*Sketch{s, other} {
+		for index, count := range sketch.Positive {
+			merged.Positive[index] += count
+		}
+		for index, count := range sketch.Negative {
+			merged.Negative[index] += count
+		}
+		merged.Zero += sketch.Zero
+		merged.Sum += sketch.Sum
+	}
+
+	switch {
+	case s.Count == 0:
+		merged.Min, merged.Max = other.Min, other.Max
+	case other.Count == 0:
+		merged.Min, merged.Max = s.Min, s.Max
+	default:
+		merged.Min, merged.Max = math.Min(s.Min, other.Min), math.Max(s.Max, other.Max)
+	}
+	merged.Count = s.Count + other.Count
+	return merged, nil
+}
+
+// Quantile estimates q-quantile. Returns NaN for empty sketch.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return math.NaN()
+	}
+
+	rank := uint64(q * float64(s.Count-1))
+	var cumulative uint64
+
+	// Negative values in increasing order are stored in decreasing order of magnitude index.
+	for _, index := range sortedIndexes(s.Negative, true) {
+		cumulative += s.Negative[index]
+		if cumulative > rank {
+			return s.clamp(-s.value(index))
+		}
+	}
+	cumulative += s.Zero
+	if cumulative > rank {
+		return s.clamp(0)
+	}
+	for _, index := range sortedIndexes(s.Positive, false) {
+		cumulative += s.Positive[index]
+		if cumulative > rank {
+			return s.clamp(s.value(index))
+		}
+	}
+	return s.Max
+}
+
+// clamp keeps estimation inside observed range.
+func (s *Sketch) clamp(value float64) float64 {
+	return math.Max(s.Min, math.Min(s.Max, value))
+}
+
+func sortedIndexes(buckets map[int]uint64, descending bool) []int {
+	indexes := make([]int, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	if descending {
+		sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+	} else {
+		sort.Ints(indexes)
+	}
+	return indexes
+}
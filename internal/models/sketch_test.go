@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketch(t *testing.T) {
+	first := NewSketch(DefaultSketchAccuracy)
+	second := NewSketch(DefaultSketchAccuracy)
+	for i := 1; i <= 1000; i++ {
+		if i%2 == 0 {
+			first.Observe(float64(i))
+		} else {
+			second.Observe(float64(i))
+		}
+	}
+
+	merged, err := first.Merge(second)
+	require.NoError(t, err)
+	require.NoError(t, merged.Validate())
+	assert.Equal(t, uint64(1000), merged.Count)
+	assert.Equal(t, 1.0, merged.Min)
+	assert.Equal(t, 1000.0, merged.Max)
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		expected := q*999 + 1
+		assert.InEpsilon(t, expected, merged.Quantile(q), DefaultSketchAccuracy*1.01, "quantile %v", q)
+	}
+
+	t.Run("Negative and zero values", func(t *testing.T) {
+		s := NewSketch(DefaultSketchAccuracy)
+		for _, v := range []float64{-10, -1, 0, 1, 10} {
+			s.Observe(v)
+		}
+		assert.InEpsilon(t, -10, s.Quantile(0), DefaultSketchAccuracy)
+		assert.Equal(t, 0.0, s.Quantile(0.5))
+		assert.InEpsilon(t, 10, s.Quantile(1), DefaultSketchAccuracy)
+	})
+
+	t.Run("JSON round trip", func(t *testing.T) {
+		data, err := json.Marshal(merged)
+		require.NoError(t, err)
+
+		var decoded Sketch
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, merged.Quantile(0.9), decoded.Quantile(0.9))
+	})
+
+	t.Run("Observe n times", func(t *testing.T) {
+		s := NewSketch(DefaultSketchAccuracy)
+		s.ObserveN(10, 4)
+		s.ObserveN(20, 0)
+		s.Observe(-1)
+		require.NoError(t, s.Validate())
+		assert.Equal(t, uint64(5), s.Count)
+		assert.Equal(t, 39.0, s.Sum)
+		assert.Equal(t, -1.0, s.Min)
+		assert.Equal(t, 10.0, s.Max)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		assert.True(t, math.IsNaN(NewSketch(DefaultSketchAccuracy).Quantile(0.5)))
+	})
+
+	t.Run("Merge with other accuracy", func(t *testing.T) {
+		_, err := first.Merge(NewSketch(0.05))
+		assert.Error(t, err)
+	})
+}
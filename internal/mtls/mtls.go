@@ -0,0 +1,93 @@
+// Package mtls configures mutual TLS and exposes identity of verified agents.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Modes of client certificate verification.
+const (
+	ClientAuthVerify  = "verify"  // certificate is optional, but verified if presented
+	ClientAuthRequire = "require" // certificate is required and verified
+)
+
+type identityKey struct{}
+
+// ServerConfig returns TLS config that verifies client certificates against CA bundle at caPath.
+// Server certificates must be added by caller.
+func ServerConfig(caPath string, mode string) (*tls.Config, error) {
+	var clientAuth tls.ClientAuthType
+	switch mode {
+	case ClientAuthVerify:
+		clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q", mode)
+	}
+
+	bundle, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no certificates found in client CA bundle")
+	}
+
+	return &tls.Config{
+		ClientAuth: clientAuth,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// Identity returns identity of verified client certificate: its common name,
+// or the first DNS name, URI or email address if common name is empty.
+// Empty string is returned if connection has no verified client certificate.
+func Identity(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	cert := state.VerifiedChains[0][0]
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	default:
+		return ""
+	}
+}
+
+// Middleware puts identity of verified agent into request context, see IdentityFromContext.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity := Identity(r.TLS); identity != "" {
+			r = r.WithContext(WithIdentity(r.Context(), identity))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithIdentity returns context with agent identity.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns identity of verified agent, or empty string for anonymous agent.
+func IdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(string)
+	return identity
+}
@@ -0,0 +1,162 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca testCA) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func startServer(t *testing.T, ca testCA, mode string) *httptest.Server {
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caPath, ca.pem, 0o600))
+
+	tlsConfig, err := ServerConfig(caPath, mode)
+	require.NoError(t, err)
+	tlsConfig.Certificates = []tls.Certificate{ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "server"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})}
+
+	server := httptest.NewUnstartedServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, IdentityFromContext(r.Context()))
+	})))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(ca testCA, certs ...tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: certs}}}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	agentCert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "agent-1"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	foreignCert := newTestCA(t).issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "intruder"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	tests := []struct {
+		name         string
+		mode         string
+		certs        []tls.Certificate
+		wantErr      bool
+		wantIdentity string
+	}{
+		{name: "require with certificate", mode: ClientAuthRequire, certs: []tls.Certificate{agentCert}, wantIdentity: "agent-1"},
+		{name: "require without certificate", mode: ClientAuthRequire, wantErr: true},
+		{name: "require with foreign certificate", mode: ClientAuthRequire, certs: []tls.Certificate{foreignCert}, wantErr: true},
+		{name: "verify with certificate", mode: ClientAuthVerify, certs: []tls.Certificate{agentCert}, wantIdentity: "agent-1"},
+		{name: "verify without certificate", mode: ClientAuthVerify, wantIdentity: ""},
+		{name: "verify with foreign certificate", mode: ClientAuthVerify, certs: []tls.Certificate{foreignCert}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startServer(t, ca, tt.mode)
+
+			resp, err := newClient(ca, tt.certs...).Get(server.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+				}
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIdentity, string(body))
+		})
+	}
+}
+
+func TestIdentitySANFallback(t *testing.T) {
+	cert := &x509.Certificate{DNSNames: []string{"agent.example.com", "other.example.com"}}
+	state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	assert.Equal(t, "agent.example.com", Identity(state))
+
+	unverified := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	assert.Equal(t, "", Identity(unverified))
+	assert.Equal(t, "", Identity(nil))
+}
+
+func TestServerConfigErrors(t *testing.T) {
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caPath, []byte("not a certificate"), 0o600))
+
+	_, err := ServerConfig(caPath, ClientAuthRequire)
+	assert.Error(t, err)
+
+	_, err = ServerConfig(caPath, "optional")
+	assert.Error(t, err)
+
+	_, err = ServerConfig(filepath.Join(t.TempDir(), "missing.pem"), ClientAuthRequire)
+	assert.Error(t, err)
+}
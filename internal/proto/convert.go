@@ -0,0 +1,102 @@
+// Package proto contains gRPC API of metrics server and conversions between its messages and models.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative metrics.proto
+
+import (
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// FromModel converts metric to message.
+func FromModel(metric models.Metric) *Metric {
+	message := &Metric{
+		Id:     metric.ID,
+		Type:   metric.MType,
+		Delta:  metric.Delta,
+		Value:  metric.Value,
+		Labels: metric.Labels,
+	}
+	if h := metric.Histogram; h != nil {
+		message.Histogram = &Histogram{Bounds: h.Bounds, Counts: h.Counts, Sum: h.Sum, Count: h.Count}
+	}
+	if s := metric.Summary; s != nil {
+		message.Summary = &Sketch{
+			Accuracy: s.Accuracy,
+			Positive: toInt32Keys(s.Positive),
+			Negative: toInt32Keys(s.Negative),
+			Zero:     s.Zero,
+			Sum:      s.Sum,
+			Count:    s.Count,
+			Min:      s.Min,
+			Max:      s.Max,
+		}
+	}
+	return message
+}
+
+// ToModel converts message to metric.
+func ToModel(message *Metric) models.Metric {
+	metric := models.Metric{
+		ID:     message.GetId(),
+		MType:  message.GetType(),
+		Delta:  message.Delta,
+		Value:  message.Value,
+		Labels: message.GetLabels(),
+	}
+	if len(metric.Labels) == 0 {
+		metric.Labels = nil
+	}
+	if h := message.GetHistogram(); h != nil {
+		metric.Histogram = &models.Histogram{Bounds: h.GetBounds(), Counts: h.GetCounts(), Sum: h.GetSum(), Count: h.GetCount()}
+	}
+	if s := message.GetSummary(); s != nil {
+		metric.Summary = &models.Sketch{
+			Accuracy: s.GetAccuracy(),
+			Positive: toIntKeys(s.GetPositive()),
+			Negative: toIntKeys(s.GetNegative()),
+			Zero:     s.GetZero(),
+			Sum:      s.GetSum(),
+			Count:    s.GetCount(),
+			Min:      s.GetMin(),
+			Max:      s.GetMax(),
+		}
+	}
+	return metric
+}
+
+// FromModels converts metrics to messages.
+func FromModels(metrics []models.Metric) []*Metric {
+	messages := make([]*Metric, len(metrics))
+	for i, metric := range metrics {
+		messages[i] = FromModel(metric)
+	}
+	return messages
+}
+
+// ToModels converts messages to metrics.
+func ToModels(messages []*Metric) []models.Metric {
+	metrics := make([]models.Metric, len(messages))
+	for i, message := range messages {
+		metrics[i] = ToModel(message)
+	}
+	return metrics
+}
+
+func toInt32Keys(buckets map[int]uint64) map[int32]uint64 {
+	if buckets == nil {
+		return nil
+	}
+	converted := make(map[int32]uint64, len(buckets))
+	for index, count := range buckets {
+		converted[int32(index)] = count
+	}
+	return converted
+}
+
+func toIntKeys(buckets map[int32]uint64) map[int]uint64 {
+	converted := make(map[int]uint64, len(buckets))
+	for index, count := range buckets {
+		converted[int(index)] = count
+	}
+	return converted
+}
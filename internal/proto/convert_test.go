@@ -0,0 +1,30 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	delta := int64(3)
+	value := 1.5
+
+	histogram := models.NewHistogram(models.DefaultBuckets)
+	histogram.Observe(0.2)
+	summary := models.NewSketch(models.DefaultSketchAccuracy)
+	summary.Observe(12)
+	summary.Observe(-4)
+
+	metrics := []models.Metric{
+		{ID: "requests", MType: constants.Counter, Delta: &delta, Labels: map[string]string{"host": "a"}},
+		{ID: "temp", MType: constants.Gauge, Value: &value},
+		{ID: "latency", MType: constants.Histogram, Histogram: histogram},
+		{ID: "size", MType: constants.Summary, Summary: summary},
+	}
+
+	assert.Equal(t, metrics, ToModels(FromModels(metrics)))
+}
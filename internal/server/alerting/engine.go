@@ -0,0 +1,219 @@
+package alerting
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+// State of alert produced by rule.
+type State string
+
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Alert is the current state of single rule.
+type Alert struct {
+	Rule       string            `json:"rule"`
+	MetricID   string            `json:"metric_id"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Severity   string            `json:"severity,omitempty"`
+	State      State             `json:"state"`
+	Value      float64           `json:"value"`
+	Threshold  float64           `json:"threshold"`
+	Op         string            `json:"op"`
+	ActiveAt   *time.Time        `json:"active_at,omitempty"`
+	FiredAt    *time.Time        `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time        `json:"resolved_at,omitempty"`
+}
+
+// Notifier receives firing and resolved alerts after every evaluation.
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert)
+}
+
+// Engine periodically evaluates rules against metrics from storer.
+type Engine struct {
+	mu       sync.RWMutex
+	storer   repo.MetricStorer
+	logger   *zap.SugaredLogger
+	rules    []Rule
+	alerts   map[string]*Alert
+	interval time.Duration
+	notifier Notifier
+}
+
+// NewEngine creates engine for given rules. Interval is in seconds.
+func NewEngine(storer repo.MetricStorer, rules []Rule, logger *zap.SugaredLogger, interval int) *Engine {
+	alerts := make(map[string]*Alert, len(rules))
+	for _, rule := range rules {
+		alerts[rule.Name] = &Alert{
+			Rule:      rule.Name,
+			MetricID:  rule.MetricID,
+			Labels:    rule.Labels,
+			Severity:  rule.Severity,
+			State:     StateInactive,
+			Threshold: rule.Threshold,
+			Op:        rule.Op,
+		}
+	}
+
+	return &Engine{
+		storer:   storer,
+		logger:   logger,
+		rules:    rules,
+		alerts:   alerts,
+		interval: time.Duration(interval) * time.Second,
+	}
+}
+
+// SetNotifier sets notifier that will be called after every evaluation.
+func (e *Engine) SetNotifier(notifier Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.notifier = notifier
+}
+
+// Start starts goroutine that's periodically evaluates rules until ctx is done.
+func (e *Engine) Start(ctx context.Context) {
+	if e.interval <= 0 || len(e.rules) == 0 {
+		e.logger.Infow("skip alert rules evaluation", "rules", len(e.rules), "interval", e.interval)
+		return
+	}
+
+	ticker := time.NewTicker(e.interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := e.Evaluate(ctx, now); err != nil {
+					e.logger.Errorw("failed to evaluate alert rules", "error", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// Evaluate checks all rules against current metrics and updates alerts state.
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) error {
+	metrics, err := e.storer.All(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	var notifiable []Alert
+	for _, rule := range e.rules {
+		alert := e.alerts[rule.Name]
+
+		var active bool
+		var value float64
+		for _, key := range rule.MetricKeys() {
+			if metric, found := metrics[key]; found {
+				active, value = rule.Matches(metric)
+				break
+			}
+		}
+		e.transition(rule, alert, active, value, now)
+
+		if alert.State == StateFiring || alert.State == StateResolved {
+			notifiable = append(notifiable, *alert)
+		}
+	}
+	notifier := e.notifier
+	e.mu.Unlock()
+
+	// Notifier is called without lock, so slow channels don't block readers of alerts.
+	if notifier != nil && len(notifiable) > 0 {
+		notifier.Notify(ctx, notifiable)
+	}
+
+	return nil
+}
+
+// transition moves alert to the next state according to rule condition.
+func (e *Engine) transition(rule Rule, alert *Alert, active bool, value float64, now time.Time) {
+	if active {
+		alert.Value = value
+	}
+
+	switch {
+	case active && (alert.State == StateInactive || alert.State == StateResolved):
+		alert.ActiveAt = timePtr(now)
+		alert.FiredAt = nil
+		alert.ResolvedAt = nil
+		alert.State = StatePending
+		if rule.For <= 0 {
+			e.fire(alert, now)
+		}
+	case active && alert.State == StatePending:
+		if now.Sub(*alert.ActiveAt) >= time.Duration(rule.For) {
+			e.fire(alert, now)
+		}
+	case !active && alert.State == StatePending:
+		alert.State = StateInactive
+		alert.ActiveAt = nil
+	case !active && alert.State == StateFiring:
+		alert.State = StateResolved
+		alert.ResolvedAt = timePtr(now)
+		e.logger.Infow("alert resolved", "rule", alert.Rule, "metric_id", alert.MetricID)
+	}
+}
+
+func (e *Engine) fire(alert *Alert, now time.Time) {
+	alert.State = StateFiring
+	alert.FiredAt = timePtr(now)
+	e.logger.Warnw(
+		"alert firing",
+		"rule", alert.Rule,
+		"metric_id", alert.MetricID,
+		"severity", alert.Severity,
+		"value", alert.Value,
+	)
+}
+
+// Active returns pending and firing alerts sorted by rule name.
+func (e *Engine) Active() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	active := make([]Alert, 0)
+	for _, alert := range e.alerts {
+		if alert.State == StatePending || alert.State == StateFiring {
+			active = append(active, *alert)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Rule < active[j].Rule })
+
+	return active
+}
+
+// Get returns current alert of rule by its name.
+func (e *Engine) Get(name string) (Alert, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alert, found := e.alerts[name]
+	if !found {
+		return Alert{}, false
+	}
+	return *alert, true
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,108 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/config"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+func storeGauge(t *testing.T, storer repo.MetricStorer, id string, value float64) {
+	t.Helper()
+
+	if _, err := storer.StoreSingle(context.Background(), models.Metric{ID: id, MType: constants.Gauge, Value: &value}); err != nil {
+		t.Fatalf("failed to store metric: %v", err)
+	}
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+	rules := []Rule{
+		{Name: "HighHeap", MetricID: "HeapAlloc", Op: OpGreater, Threshold: 100, For: config.Duration(time.Minute), Severity: "critical"},
+	}
+	engine := NewEngine(storer, rules, logger, 1)
+	start := time.Now()
+
+	steps := []struct {
+		name  string
+		value float64
+		after time.Duration
+		want  State
+	}{
+		{"Below threshold", 50, 0, StateInactive},
+		{"Condition starts holding", 150, 10 * time.Second, StatePending},
+		{"Condition holds shorter than for", 160, 30 * time.Second, StatePending},
+		{"Condition holds long enough", 170, 80 * time.Second, StateFiring},
+		{"Still firing", 180, 90 * time.Second, StateFiring},
+		{"Condition stops holding", 20, 100 * time.Second, StateResolved},
+		{"Condition holds again", 200, 110 * time.Second, StatePending},
+		{"Condition breaks before for", 10, 120 * time.Second, StateInactive},
+	}
+
+	for _, step := range steps {
+		storeGauge(t, storer, "HeapAlloc", step.value)
+		require.NoError(t, engine.Evaluate(context.Background(), start.Add(step.after)), step.name)
+
+		alert, found := engine.Get("HighHeap")
+		require.True(t, found)
+		assert.Equal(t, step.want, alert.State, step.name)
+	}
+}
+
+func TestEngine_EvaluateWithoutFor(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+	rules := []Rule{
+		{Name: "LowMemory", MetricID: "FreeMemory", Op: OpLess, Threshold: 10},
+		{Name: "Missing", MetricID: "NotReported", Op: OpGreater, Threshold: 0},
+	}
+	engine := NewEngine(storer, rules, logger, 1)
+
+	storeGauge(t, storer, "FreeMemory", 5)
+	require.NoError(t, engine.Evaluate(context.Background(), time.Now()))
+
+	active := engine.Active()
+	require.Len(t, active, 1)
+	assert.Equal(t, "LowMemory", active[0].Rule)
+	assert.Equal(t, StateFiring, active[0].State)
+	assert.Equal(t, float64(5), active[0].Value)
+	assert.NotNil(t, active[0].FiredAt)
+}
+
+type recordingNotifier struct {
+	calls [][]Alert
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, alerts []Alert) {
+	n.calls = append(n.calls, alerts)
+}
+
+func TestEngine_Notifier(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+	engine := NewEngine(storer, []Rule{{Name: "LowMemory", MetricID: "FreeMemory", Op: OpLess, Threshold: 10}}, logger, 1)
+	notifier := &recordingNotifier{}
+	engine.SetNotifier(notifier)
+
+	storeGauge(t, storer, "FreeMemory", 50)
+	require.NoError(t, engine.Evaluate(context.Background(), time.Now()))
+	assert.Empty(t, notifier.calls, "inactive alerts must not be sent to notifier")
+
+	storeGauge(t, storer, "FreeMemory", 5)
+	require.NoError(t, engine.Evaluate(context.Background(), time.Now()))
+	storeGauge(t, storer, "FreeMemory", 50)
+	require.NoError(t, engine.Evaluate(context.Background(), time.Now()))
+
+	require.Len(t, notifier.calls, 2)
+	assert.Equal(t, StateFiring, notifier.calls[0][0].State)
+	assert.Equal(t, StateResolved, notifier.calls[1][0].State)
+}
@@ -0,0 +1,150 @@
+// Package alerting evaluates threshold rules against stored metrics and tracks alert state.
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/VOTONO/go-metrics/internal/config"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// Comparison operators supported by rules.
+const (
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+)
+
+// Rule describes condition under which alert fires.
+type Rule struct {
+	Name       string            `json:"name"`
+	MetricID   string            `json:"metric_id"`
+	Labels     map[string]string `json:"labels,omitempty"`      // optional, selects metric with exactly these labels
+	MetricType string            `json:"metric_type,omitempty"` // optional, matches any type if empty
+	Op         string            `json:"op"`
+	Threshold  float64           `json:"threshold"`
+	For        config.Duration   `json:"for,omitempty"` // how long condition must hold before alert fires
+	Severity   string            `json:"severity,omitempty"`
+}
+
+// RulesFile is the structure of alerting configuration file.
+type RulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// LoadRules reads and validates rules from JSON file.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file RulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode rules file: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(file.Rules))
+	for _, rule := range file.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := names[rule.Name]; exists {
+			return nil, fmt.Errorf("duplicate rule name: %s", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+	}
+
+	return file.Rules, nil
+}
+
+// Validate checks that rule is complete.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("rule name is empty")
+	}
+	if r.MetricID == "" {
+		return fmt.Errorf("rule %s: metric_id is empty", r.Name)
+	}
+	for name := range r.Labels {
+		if !models.ValidLabelName(name) {
+			return fmt.Errorf("rule %s: invalid label name %q", r.Name, name)
+		}
+	}
+	switch r.MetricType {
+	case "", constants.Gauge, constants.Counter:
+	default:
+		return fmt.Errorf("rule %s: unknown metric type %s", r.Name, r.MetricType)
+	}
+	switch r.Op {
+	case OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpEqual, OpNotEqual:
+	default:
+		return fmt.Errorf("rule %s: unknown comparison %q", r.Name, r.Op)
+	}
+	if r.For < 0 {
+		return fmt.Errorf("rule %s: negative for duration", r.Name)
+	}
+	return nil
+}
+
+// MetricKeys returns storage keys of metrics checked by rule, rule without type checks gauge first.
+func (r Rule) MetricKeys() []string {
+	if r.MetricType != "" {
+		return []string{models.MetricKey(r.MetricType, r.MetricID, r.Labels)}
+	}
+	return []string{
+		models.MetricKey(constants.Gauge, r.MetricID, r.Labels),
+		models.MetricKey(constants.Counter, r.MetricID, r.Labels),
+	}
+}
+
+// Matches reports whether metric satisfies rule condition and returns its numeric value.
+func (r Rule) Matches(metric models.Metric) (bool, float64) {
+	if r.MetricType != "" && r.MetricType != metric.MType {
+		return false, 0
+	}
+
+	var value float64
+	switch metric.MType {
+	case constants.Gauge:
+		if metric.Value == nil {
+			return false, 0
+		}
+		value = *metric.Value
+	case constants.Counter:
+		if metric.Delta == nil {
+			return false, 0
+		}
+		value = float64(*metric.Delta)
+	default:
+		return false, 0
+	}
+
+	return compare(value, r.Op, r.Threshold), value
+}
+
+func compare(value float64, op string, threshold float64) bool {
+	switch op {
+	case OpGreater:
+		return value > threshold
+	case OpGreaterEqual:
+		return value >= threshold
+	case OpLess:
+		return value < threshold
+	case OpLessEqual:
+		return value <= threshold
+	case OpEqual:
+		return value == threshold
+	case OpNotEqual:
+		return value != threshold
+	default:
+		return false
+	}
+}
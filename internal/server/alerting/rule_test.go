@@ -0,0 +1,106 @@
+package alerting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/VOTONO/go-metrics/internal/config"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func writeRulesFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write rules file: %v", err)
+	}
+	return path
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Rule
+		wantErr bool
+	}{
+		{
+			name: "Valid rules",
+			content: `{"rules": [
+				{"name": "HighHeap", "metric_id": "HeapAlloc", "metric_type": "gauge", "op": ">", "threshold": 100, "for": "1m", "severity": "critical"},
+				{"name": "ManyPolls", "metric_id": "PollCount", "op": ">=", "threshold": 5, "for": 30}
+			]}`,
+			want: []Rule{
+				{Name: "HighHeap", MetricID: "HeapAlloc", MetricType: constants.Gauge, Op: OpGreater, Threshold: 100, For: config.Duration(time.Minute), Severity: "critical"},
+				{Name: "ManyPolls", MetricID: "PollCount", Op: OpGreaterEqual, Threshold: 5, For: config.Duration(30 * time.Second)},
+			},
+		},
+		{
+			name:    "Unknown operator",
+			content: `{"rules": [{"name": "r", "metric_id": "m", "op": "~", "threshold": 1}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Duplicate names",
+			content: `{"rules": [{"name": "r", "metric_id": "m", "op": ">", "threshold": 1}, {"name": "r", "metric_id": "m", "op": "<", "threshold": 1}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid duration",
+			content: `{"rules": [{"name": "r", "metric_id": "m", "op": ">", "threshold": 1, "for": "soon"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid JSON",
+			content: `rules`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadRules(writeRulesFile(t, tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	value := 10.5
+	delta := int64(3)
+	gauge := models.Metric{ID: "m", MType: constants.Gauge, Value: &value}
+	counter := models.Metric{ID: "m", MType: constants.Counter, Delta: &delta}
+
+	tests := []struct {
+		name   string
+		rule   Rule
+		metric models.Metric
+		want   bool
+	}{
+		{"Gauge greater", Rule{Op: OpGreater, Threshold: 10}, gauge, true},
+		{"Gauge less", Rule{Op: OpLess, Threshold: 10}, gauge, false},
+		{"Counter equal", Rule{Op: OpEqual, Threshold: 3}, counter, true},
+		{"Counter not equal", Rule{Op: OpNotEqual, Threshold: 3}, counter, false},
+		{"Type mismatch", Rule{MetricType: constants.Counter, Op: OpGreater, Threshold: 0}, gauge, false},
+		{"Missing value", Rule{Op: OpLessEqual, Threshold: 100}, models.Metric{ID: "m", MType: constants.Gauge}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := tt.rule.Matches(tt.metric)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package exposition renders stored metrics in Prometheus text and OpenMetrics formats.
+package exposition
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// Format of exposition.
+type Format int
+
+const (
+	FormatText        Format = iota // Prometheus text format 0.0.4
+	FormatOpenMetrics               // OpenMetrics 1.0.0
+)
+
+// Content types of formats.
+const (
+	ContentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Negotiate selects format by Accept header of request, Prometheus text format is the default.
+func Negotiate(accept string) Format {
+	if strings.Contains(accept, "application/openmetrics-text") {
+		return FormatOpenMetrics
+	}
+	return FormatText
+}
+
+// ContentType returns value of Content-Type header for format.
+func (f Format) ContentType() string {
+	if f == FormatOpenMetrics {
+		return ContentTypeOpenMetrics
+	}
+	return ContentTypeText
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// family is a group of metrics exposed under the same name and type.
+type family struct {
+	name    string
+	mType   string
+	help    string
+	metrics []models.Metric
+}
+
+// Write renders metrics in given format. Families and samples are sorted, so output is stable.
+func Write(w io.Writer, metrics map[string]models.Metric, format Format) error {
+	bw := bufio.NewWriter(w)
+
+	for _, f := range groupFamilies(metrics, format) {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, helpEscaper.Replace(f.help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.mType)
+
+		for _, metric := range f.metrics {
+			writeMetric(bw, f.name, metric, format)
+		}
+	}
+
+	if format == FormatOpenMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+// groupFamilies maps metrics to families with valid unique names.
+// Counter family name gets "_total" suffix in text format, OpenMetrics adds the suffix to samples only.
+// If sanitised name is already taken by family of other type, metric type is appended to the name.
+func groupFamilies(metrics map[string]models.Metric, format Format) []*family {
+	keys := make([]string, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	families := make(map[string]*family)
+	for _, key := range keys {
+		metric := metrics[key]
+
+		name := SanitizeName(metric.ID)
+		if metric.MType == constants.Counter {
+			name = strings.TrimSuffix(name, "_total")
+			if format == FormatText {
+				name += "_total"
+			}
+		}
+
+		f, found := families[name]
+		if found && f.mType != metric.MType {
+			name = name + "_" + metric.MType
+			f, found = families[name]
+		}
+		if !found {
+			f = &family{name: name, mType: metric.MType, help: metric.ID}
+			families[name] = f
+		}
+		f.metrics = append(f.metrics, metric)
+	}
+
+	sorted := make([]*family, 0, len(families))
+	for _, f := range families {
+		sorted = append(sorted, f)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted
+}
+
+func writeMetric(w *bufio.Writer, name string, metric models.Metric, format Format) {
+	switch metric.MType {
+	case constants.Gauge:
+		if metric.Value != nil {
+			writeSample(w, name, metric.Labels, "", "", *metric.Value)
+		}
+	case constants.Counter:
+		if metric.Delta != nil {
+			sampleName := name
+			if format == FormatOpenMetrics {
+				sampleName += "_total"
+			}
+			writeSample(w, sampleName, metric.Labels, "", "", float64(*metric.Delta))
+		}
+	case constants.Histogram:
+		h := metric.Histogram
+		if h == nil {
+			return
+		}
+		var cumulative uint64
+		for i, count := range h.Counts {
+			cumulative += count
+			le := math.Inf(1)
+			if i < len(h.Bounds) {
+				le = h.Bounds[i]
+			}
+			writeSample(w, name+"_bucket", metric.Labels, "le", formatFloat(le), float64(cumulative))
+		}
+		writeSample(w, name+"_sum", metric.Labels, "", "", h.Sum)
+		writeSample(w, name+"_count", metric.Labels, "", "", float64(h.Count))
+	case constants.Summary:
+		s := metric.Summary
+		if s == nil {
+			return
+		}
+		if s.Count > 0 {
+			for _, q := range models.ReportedQuantiles {
+				writeSample(w, name, metric.Labels, "quantile", formatFloat(q), s.Quantile(q))
+			}
+		}
+		writeSample(w, name+"_sum", metric.Labels, "", "", s.Sum)
+		writeSample(w, name+"_count", metric.Labels, "", "", float64(s.Count))
+	}
+}
+
+// writeSample writes single line with metric labels sorted by name and optional extra label, e.g. "le".
+func writeSample(w *bufio.Writer, name string, labels map[string]string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+
+	names := make([]string, 0, len(labels))
+	for labelName := range labels {
+		if labelName != extraName {
+			names = append(names, labelName)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range names {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, labelName, labels[labelName])
+		}
+		if extraName != "" {
+			if len(names) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	w.WriteString(labelEscaper.Replace(value))
+	w.WriteByte('"')
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// SanitizeName converts metric ID to valid Prometheus metric name:
+// invalid characters are replaced with underscores and name starting with digit is prefixed with underscore.
+func SanitizeName(id string) string {
+	if id == "" {
+		return "_"
+	}
+
+	var b strings.Builder
+	for i, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
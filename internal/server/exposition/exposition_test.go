@@ -0,0 +1,110 @@
+package exposition
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func float64Ptr(v float64) *float64 { return &v }
+func int64Ptr(v int64) *int64       { return &v }
+
+func testMetrics() map[string]models.Metric {
+	histogram := models.NewHistogram([]float64{0.1, 1})
+	for _, v := range []float64{0.05, 0.5, 5} {
+		histogram.Observe(v)
+	}
+
+	metrics := []models.Metric{
+		{ID: "HeapAlloc", MType: constants.Gauge, Value: float64Ptr(1.5), Labels: map[string]string{"host": "a", "instance": `x"y`}},
+		{ID: "PollCount", MType: constants.Counter, Delta: int64Ptr(7)},
+		{ID: "HeapAlloc", MType: constants.Counter, Delta: int64Ptr(2)},
+		{ID: "1st.request-latency", MType: constants.Histogram, Histogram: histogram},
+	}
+
+	result := make(map[string]models.Metric, len(metrics))
+	for _, metric := range metrics {
+		result[metric.Key()] = metric
+	}
+	return result
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, testMetrics(), FormatText))
+
+	expected := `# HELP HeapAlloc HeapAlloc
+# TYPE HeapAlloc gauge
+HeapAlloc{host="a",instance="x\"y"} 1.5
+# HELP HeapAlloc_total HeapAlloc
+# TYPE HeapAlloc_total counter
+HeapAlloc_total 2
+# HELP PollCount_total PollCount
+# TYPE PollCount_total counter
+PollCount_total 7
+# HELP _1st_request_latency 1st.request-latency
+# TYPE _1st_request_latency histogram
+_1st_request_latency_bucket{le="0.1"} 1
+_1st_request_latency_bucket{le="1"} 2
+_1st_request_latency_bucket{le="+Inf"} 3
+_1st_request_latency_sum 5.55
+_1st_request_latency_count 3
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, map[string]models.Metric{
+		"counter:PollCount": {ID: "PollCount", MType: constants.Counter, Delta: int64Ptr(7)},
+		"gauge:PollCount":   {ID: "PollCount", MType: constants.Gauge, Value: float64Ptr(3)},
+	}, FormatOpenMetrics))
+
+	expected := `# HELP PollCount PollCount
+# TYPE PollCount counter
+PollCount_total 7
+# HELP PollCount_gauge PollCount
+# TYPE PollCount_gauge gauge
+PollCount_gauge 3
+# EOF
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteSummary(t *testing.T) {
+	summary := models.NewSketch(models.DefaultSketchAccuracy)
+	summary.Observe(2)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, map[string]models.Metric{
+		"summary:rpc": {ID: "rpc", MType: constants.Summary, Summary: summary, Labels: map[string]string{"host": "a"}},
+	}, FormatText))
+
+	assert.Contains(t, buf.String(), "# TYPE rpc summary\n")
+	assert.Contains(t, buf.String(), `rpc{host="a",quantile="0.99"} 2`+"\n")
+	assert.Contains(t, buf.String(), `rpc_count{host="a"} 1`+"\n")
+}
+
+func TestNegotiate(t *testing.T) {
+	assert.Equal(t, FormatText, Negotiate(""))
+	assert.Equal(t, FormatText, Negotiate("text/plain;version=0.0.4"))
+	assert.Equal(t, FormatOpenMetrics, Negotiate("application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5"))
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"HeapAlloc":      "HeapAlloc",
+		"http.requests":  "http_requests",
+		"9lives":         "_9lives",
+		"cpu:usage-rate": "cpu:usage_rate",
+		"метрика":        "_______",
+	}
+	for id, want := range tests {
+		assert.Equal(t, want, SanitizeName(id), id)
+	}
+}
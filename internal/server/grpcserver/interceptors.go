@@ -0,0 +1,315 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/mtls"
+	pb "github.com/VOTONO/go-metrics/internal/proto"
+	"github.com/VOTONO/go-metrics/internal/subnet"
+)
+
+// writeMethods are methods that change stored metrics.
+var writeMethods = map[string]bool{
+	pb.Metrics_UpdateMetrics_FullMethodName: true,
+	pb.Metrics_StreamUpdates_FullMethodName: true,
+}
+
+// LoggingUnaryInterceptor logs every call with its duration, status and agent identity, like logger.WithLogger.
+// Identity of verified agent is also put into context, see mtls.IdentityFromContext.
+func LoggingUnaryInterceptor(logger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		identity := peerIdentity(ctx)
+		if identity != "" {
+			ctx = mtls.WithIdentity(ctx, identity)
+		}
+
+		resp, err := handler(ctx, req)
+		logger.Infow("Request",
+			"method", info.FullMethod,
+			"duration", time.Since(start),
+			"status", status.Code(err).String(),
+			"agent", identity,
+		)
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor logs every stream with its duration, status and agent identity.
+func LoggingStreamInterceptor(logger *zap.SugaredLogger) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		identity := peerIdentity(stream.Context())
+		if identity != "" {
+			stream = &identityStream{ServerStream: stream, ctx: mtls.WithIdentity(stream.Context(), identity)}
+		}
+
+		err := handler(srv, stream)
+		logger.Infow("Request",
+			"method", info.FullMethod,
+			"duration", time.Since(start),
+			"status", status.Code(err).String(),
+			"agent", identity,
+		)
+		return err
+	}
+}
+
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
+
+// peerIdentity returns identity of agent verified by mutual TLS.
+func peerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ""
+	}
+	return mtls.Identity(&tlsInfo.State)
+}
+
+// TrustedSubnetUnaryInterceptor rejects write calls of agents outside of checker subnets.
+// Agent IP is taken from subnet.RealIPMetadataKey metadata or from peer address, depending on checker source.
+func TrustedSubnetUnaryInterceptor(checker *subnet.Checker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkSubnet(ctx, checker, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// TrustedSubnetStreamInterceptor rejects write streams of agents outside of checker subnets.
+func TrustedSubnetStreamInterceptor(checker *subnet.Checker) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkSubnet(stream.Context(), checker, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func checkSubnet(ctx context.Context, checker *subnet.Checker, method string) error {
+	if checker == nil || !writeMethods[method] {
+		return nil
+	}
+
+	var ip string
+	if checker.Source() == subnet.SourceHeader {
+		ip = firstMetadataValue(ctx, subnet.RealIPMetadataKey)
+	} else if p, ok := peer.FromContext(ctx); ok {
+		ip = subnet.HostIP(p.Addr.String())
+	}
+	if !checker.Trusted(ip) {
+		return status.Error(codes.PermissionDenied, "agent is not in trusted subnet")
+	}
+	return nil
+}
+
+// HashUnaryInterceptor checks signature of request if HashSHA256 metadata exists, like auth.SignatureChecker.
+// Version 2 signature (HashVersion metadata is auth.SignatureV2) covers method, deterministically serialized
+// request, HashTimestamp and HashNonce metadata, see auth.SignMessageV2. Calls with timestamp outside of
+// config.MaxSkew or with already seen nonce are rejected. Version 1 signature covers request only,
+// see auth.SignMessage, and is checked if config.AcceptV1 is set. In strict mode write calls without
+// signature are rejected.
+func HashUnaryInterceptor(keyring *auth.Keyring, config auth.SignatureConfig) grpc.UnaryServerInterceptor {
+	config = config.WithNonceCache()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		hash := firstMetadataValue(ctx, strings.ToLower(constants.HashSHA256))
+		if keyring.Empty() {
+			return handler(ctx, req)
+		}
+		if hash == "" {
+			if config.Strict && writeMethods[info.FullMethod] {
+				return nil, status.Error(codes.Unauthenticated, "request must be signed")
+			}
+			return handler(ctx, req)
+		}
+
+		message, ok := req.(proto.Message)
+		if !ok {
+			return nil, status.Error(codes.Internal, "request is not protobuf message")
+		}
+
+		if firstMetadataValue(ctx, strings.ToLower(constants.HashVersion)) != auth.SignatureV2 {
+			if !config.AcceptV1 {
+				return nil, status.Error(codes.Unauthenticated, "signature version 1 is not accepted")
+			}
+			key, err := lookupKey(keyring, firstMetadataValue(ctx, strings.ToLower(constants.HashKeyID)))
+			if err != nil {
+				return nil, err
+			}
+			if err := checkHash(key.Secret, message, hash); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}
+
+		timestamp := firstMetadataValue(ctx, strings.ToLower(constants.HashTimestamp))
+		nonce := firstMetadataValue(ctx, strings.ToLower(constants.HashNonce))
+		keyID := firstMetadataValue(ctx, strings.ToLower(constants.HashKeyID))
+		if err := checkSignatureV2(keyring, config, keyID, info.FullMethod, message, hash, timestamp, nonce); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// signedMessage is stream message that carries its own HMAC, see pb.StreamUpdatesRequest.
+type signedMessage interface {
+	proto.Message
+	GetHash() string
+	GetTimestamp() string
+	GetNonce() string
+	GetKeyId() string
+}
+
+// HashStreamInterceptor checks HMAC of every received stream message that has hash.
+// Hash is computed over message with empty hash field. Message with timestamp or nonce is checked
+// like version 2 signature of unary call, messages with body-only hash are accepted if config.AcceptV1 is set.
+// Key is named by key_id field of message or by HashKeyID metadata of the stream.
+// In strict mode messages without hash are rejected on write streams.
+func HashStreamInterceptor(keyring *auth.Keyring, config auth.SignatureConfig) grpc.StreamServerInterceptor {
+	config = config.WithNonceCache()
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if keyring.Empty() {
+			return handler(srv, stream)
+		}
+		return handler(srv, &hashCheckingStream{
+			ServerStream: stream,
+			keyring:      keyring,
+			config:       config,
+			method:       info.FullMethod,
+			strict:       config.Strict && writeMethods[info.FullMethod],
+		})
+	}
+}
+
+type hashCheckingStream struct {
+	grpc.ServerStream
+	keyring *auth.Keyring
+	config  auth.SignatureConfig
+	method  string
+	strict  bool
+}
+
+func (s *hashCheckingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	message, ok := m.(signedMessage)
+	if !ok || message.GetHash() == "" {
+		if s.strict {
+			return status.Error(codes.Unauthenticated, "message must be signed")
+		}
+		return nil
+	}
+
+	unsigned := proto.Clone(message)
+	reflected := unsigned.ProtoReflect()
+	reflected.Clear(reflected.Descriptor().Fields().ByName("hash"))
+
+	keyID := message.GetKeyId()
+	if keyID == "" {
+		keyID = firstMetadataValue(s.Context(), strings.ToLower(constants.HashKeyID))
+	}
+	if message.GetTimestamp() != "" || message.GetNonce() != "" {
+		return checkSignatureV2(s.keyring, s.config, keyID, s.method, unsigned, message.GetHash(), message.GetTimestamp(), message.GetNonce())
+	}
+
+	if !s.config.AcceptV1 {
+		return status.Error(codes.Unauthenticated, "signature version 1 is not accepted")
+	}
+	key, err := lookupKey(s.keyring, keyID)
+	if err != nil {
+		return err
+	}
+	return checkHash(key.Secret, unsigned, message.GetHash())
+}
+
+// checkSignatureV2 checks version 2 signature of message sent to fullMethod, see auth.SignMessageV2.
+// Signatures with timestamp outside of config.MaxSkew or with already seen nonce are rejected.
+func checkSignatureV2(keyring *auth.Keyring, config auth.SignatureConfig, keyID, fullMethod string, message proto.Message, hash, timestamp, nonce string) error {
+	current := time.Now()
+	signedAt, err := config.CheckTimestamp(timestamp, current)
+	if errors.Is(err, auth.ErrMalformedTimestamp) || nonce == "" {
+		return status.Error(codes.InvalidArgument, auth.ErrMalformedTimestamp.Error())
+	}
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	key, err := lookupKey(keyring, keyID)
+	if err != nil {
+		return err
+	}
+	computed, err := auth.SignMessageV2(key.Secret, fullMethod, message, timestamp, nonce)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if !hmac.Equal([]byte(computed), []byte(strings.ToLower(hash))) {
+		return status.Error(codes.Unauthenticated, "invalid hash")
+	}
+	if !config.RememberNonce(nonce, signedAt, current) {
+		return status.Error(codes.Unauthenticated, "replayed request")
+	}
+	return nil
+}
+
+// lookupKey returns key with id, see auth.Keyring.Lookup.
+func lookupKey(keyring *auth.Keyring, id string) (auth.Key, error) {
+	key, ok := keyring.Lookup(id)
+	if !ok {
+		return auth.Key{}, status.Error(codes.Unauthenticated, "unknown or expired hash key")
+	}
+	return key, nil
+}
+
+func checkHash(key string, message proto.Message, hash string) error {
+	computed, err := auth.SignMessage(key, message)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if !hmac.Equal([]byte(computed), []byte(strings.ToLower(hash))) {
+		return status.Error(codes.Unauthenticated, "invalid hash")
+	}
+	return nil
+}
+
+func firstMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+// Package grpcserver contains gRPC service of metrics server and its interceptors.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/helpers"
+	"github.com/VOTONO/go-metrics/internal/models"
+	pb "github.com/VOTONO/go-metrics/internal/proto"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+	"github.com/VOTONO/go-metrics/internal/subnet"
+)
+
+// MetricsServer implements gRPC Metrics service over storer.
+type MetricsServer struct {
+	pb.UnimplementedMetricsServer
+	storer repo.MetricStorer
+}
+
+// NewMetricsServer creates service over storer.
+func NewMetricsServer(storer repo.MetricStorer) *MetricsServer {
+	return &MetricsServer{storer: storer}
+}
+
+// NewServer creates gRPC server with Metrics service, request logging, trusted subnet and HMAC checks.
+// Empty keyring disables HMAC check, signature configures it like for HTTP, nil trustedSubnet disables subnet check.
+func NewServer(storer repo.MetricStorer, logger *zap.SugaredLogger, keyring *auth.Keyring, signature auth.SignatureConfig, trustedSubnet *subnet.Checker, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(
+			LoggingUnaryInterceptor(logger),
+			TrustedSubnetUnaryInterceptor(trustedSubnet),
+			HashUnaryInterceptor(keyring, signature),
+		),
+		grpc.ChainStreamInterceptor(
+			LoggingStreamInterceptor(logger),
+			TrustedSubnetStreamInterceptor(trustedSubnet),
+			HashStreamInterceptor(keyring, signature),
+		),
+	)
+
+	server := grpc.NewServer(opts...)
+	pb.RegisterMetricsServer(server, NewMetricsServer(storer))
+	return server
+}
+
+// UpdateMetrics stores batch of metrics.
+func (s *MetricsServer) UpdateMetrics(ctx context.Context, req *pb.UpdateMetricsRequest) (*pb.UpdateMetricsResponse, error) {
+	if err := s.store(ctx, req.GetMetrics()); err != nil {
+		return nil, err
+	}
+	return &pb.UpdateMetricsResponse{}, nil
+}
+
+// GetMetric returns metric by type, ID and labels.
+func (s *MetricsServer) GetMetric(ctx context.Context, req *pb.GetMetricRequest) (*pb.GetMetricResponse, error) {
+	metric, found, err := s.storer.Get(ctx, models.MetricKey(req.GetType(), req.GetId(), req.GetLabels()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "metric not found")
+	}
+	return &pb.GetMetricResponse{Metric: pb.FromModel(metric)}, nil
+}
+
+// ListMetrics returns all stored metrics.
+func (s *MetricsServer) ListMetrics(ctx context.Context, _ *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	metrics, err := s.storer.All(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &pb.ListMetricsResponse{Metrics: make([]*pb.Metric, 0, len(metrics))}
+	for _, metric := range metrics {
+		resp.Metrics = append(resp.Metrics, pb.FromModel(metric))
+	}
+	return resp, nil
+}
+
+// StreamUpdates stores every batch of stream when it is received.
+func (s *MetricsServer) StreamUpdates(stream pb.Metrics_StreamUpdatesServer) error {
+	var received int64
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&pb.StreamUpdatesResponse{Received: received})
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.store(stream.Context(), req.GetMetrics()); err != nil {
+			return err
+		}
+		received += int64(len(req.GetMetrics()))
+	}
+}
+
+func (s *MetricsServer) store(ctx context.Context, messages []*pb.Metric) error {
+	metrics := pb.ToModels(messages)
+	for _, metric := range metrics {
+		if !helpers.ValidateMetric(metric) {
+			return status.Errorf(codes.InvalidArgument, "invalid metric %q", metric.Key())
+		}
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	if err := s.storer.StoreSlice(ctx, metrics); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
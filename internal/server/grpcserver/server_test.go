@@ -0,0 +1,248 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	pb "github.com/VOTONO/go-metrics/internal/proto"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+	"github.com/VOTONO/go-metrics/internal/subnet"
+)
+
+const testKey = "secret"
+
+func newTestClient(t *testing.T, trustedSubnet *subnet.Checker, signature auth.SignatureConfig) pb.MetricsClient {
+	t.Helper()
+
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewServer(storer, logger, auth.NewKeyring(testKey), signature, trustedSubnet)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewMetricsClient(conn)
+}
+
+// signV2 returns ctx with v2 signature of UpdateMetrics request made at signedAt.
+func signV2(t *testing.T, ctx context.Context, key string, req *pb.UpdateMetricsRequest, signedAt time.Time) context.Context {
+	t.Helper()
+	timestamp, nonce, err := auth.NewSignatureParams()
+	require.NoError(t, err)
+	timestamp = strconv.FormatInt(signedAt.Unix(), 10)
+	hash, err := auth.SignMessageV2(key, pb.Metrics_UpdateMetrics_FullMethodName, req, timestamp, nonce)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(ctx,
+		strings.ToLower(constants.HashVersion), auth.SignatureV2,
+		strings.ToLower(constants.HashTimestamp), timestamp,
+		strings.ToLower(constants.HashNonce), nonce,
+		strings.ToLower(constants.HashSHA256), hash,
+	)
+}
+
+// signBatchV2 sets version 2 signature fields of stream batch.
+func signBatchV2(t *testing.T, key string, batch *pb.StreamUpdatesRequest, signedAt time.Time) {
+	t.Helper()
+	_, nonce, err := auth.NewSignatureParams()
+	require.NoError(t, err)
+	batch.Hash = ""
+	batch.Timestamp = strconv.FormatInt(signedAt.Unix(), 10)
+	batch.Nonce = nonce
+	batch.Hash, err = auth.SignMessageV2(key, pb.Metrics_StreamUpdates_FullMethodName, batch, batch.Timestamp, batch.Nonce)
+	require.NoError(t, err)
+}
+
+func sendBatch(t *testing.T, client pb.MetricsClient, batch *pb.StreamUpdatesRequest) error {
+	t.Helper()
+	stream, err := client.StreamUpdates(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(batch))
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func float64Ptr(v float64) *float64 { return &v }
+
+func TestMetricsServer(t *testing.T) {
+	client := newTestClient(t, nil, auth.DefaultSignatureConfig)
+	ctx := context.Background()
+
+	labels := map[string]string{"host": "a"}
+	_, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{
+		{Id: "requests", Type: constants.Counter, Delta: int64Ptr(2), Labels: labels},
+		{Id: "temp", Type: constants.Gauge, Value: float64Ptr(21.5)},
+	}})
+	require.NoError(t, err)
+
+	stream, err := client.StreamUpdates(ctx)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, stream.Send(&pb.StreamUpdatesRequest{Metrics: []*pb.Metric{
+			{Id: "requests", Type: constants.Counter, Delta: int64Ptr(3), Labels: labels},
+		}}))
+	}
+	streamResp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), streamResp.GetReceived())
+
+	getResp, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "requests", Type: constants.Counter, Labels: labels})
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), getResp.GetMetric().GetDelta())
+
+	_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "requests", Type: constants.Counter})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	listResp, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, listResp.GetMetrics(), 2)
+
+	_, err = client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "temp", Type: constants.Gauge}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHashInterceptors(t *testing.T) {
+	client := newTestClient(t, nil, auth.DefaultSignatureConfig)
+	ctx := context.Background()
+	hashKey := strings.ToLower(constants.HashSHA256)
+
+	req := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "temp", Type: constants.Gauge, Value: float64Ptr(1), Labels: map[string]string{"a": "1", "b": "2"}}}}
+	signed := signV2(t, ctx, testKey, req, time.Now())
+	_, err := client.UpdateMetrics(signed, req)
+	assert.NoError(t, err)
+
+	// The same call can't be replayed.
+	_, err = client.UpdateMetrics(signed, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.UpdateMetrics(signV2(t, ctx, "other", req, time.Now()), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.UpdateMetrics(signV2(t, ctx, testKey, req, time.Now().Add(-time.Hour)), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	tampered := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "temp", Type: constants.Gauge, Value: float64Ptr(2)}}}
+	_, err = client.UpdateMetrics(signV2(t, ctx, testKey, req, time.Now()), tampered)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.UpdateMetrics(metadata.AppendToOutgoingContext(ctx,
+		strings.ToLower(constants.HashVersion), auth.SignatureV2, hashKey, "00"), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Version 1 signature is checked only if accepted.
+	hash, err := auth.SignMessage(testKey, req)
+	require.NoError(t, err)
+	_, err = client.UpdateMetrics(metadata.AppendToOutgoingContext(ctx, hashKey, hash), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	v1Client := newTestClient(t, nil, auth.SignatureConfig{MaxSkew: time.Minute, NonceCacheSize: 10, AcceptV1: true})
+	_, err = v1Client.UpdateMetrics(metadata.AppendToOutgoingContext(ctx, hashKey, hash), req)
+	assert.NoError(t, err)
+	wrongHash, err := auth.SignMessage("other", req)
+	require.NoError(t, err)
+	_, err = v1Client.UpdateMetrics(metadata.AppendToOutgoingContext(ctx, hashKey, wrongHash), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	batch := &pb.StreamUpdatesRequest{Metrics: req.GetMetrics()}
+	batch.Hash = wrongHash
+	assert.Equal(t, codes.Unauthenticated, status.Code(sendBatch(t, client, batch)))
+
+	// Stream batches are signed with version 2 scheme in message fields.
+	signBatchV2(t, testKey, batch, time.Now())
+	assert.NoError(t, sendBatch(t, client, batch))
+	assert.Equal(t, codes.Unauthenticated, status.Code(sendBatch(t, client, batch)), "replayed batch")
+
+	signBatchV2(t, testKey, batch, time.Now().Add(-time.Hour))
+	assert.Equal(t, codes.Unauthenticated, status.Code(sendBatch(t, client, batch)))
+
+	signBatchV2(t, "other", batch, time.Now())
+	assert.Equal(t, codes.Unauthenticated, status.Code(sendBatch(t, client, batch)))
+
+	signBatchV2(t, testKey, batch, time.Now())
+	batch.Nonce = ""
+	assert.Equal(t, codes.InvalidArgument, status.Code(sendBatch(t, client, batch)))
+
+	// Version 1 batch signature is checked only if accepted.
+	v1Batch := &pb.StreamUpdatesRequest{Metrics: req.GetMetrics()}
+	v1Batch.Hash, err = auth.SignMessage(testKey, v1Batch)
+	require.NoError(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(sendBatch(t, client, v1Batch)))
+	assert.NoError(t, sendBatch(t, v1Client, v1Batch))
+}
+
+func TestStrictHashInterceptors(t *testing.T) {
+	client := newTestClient(t, nil, auth.SignatureConfig{Strict: true, MaxSkew: time.Minute, NonceCacheSize: 10})
+	ctx := context.Background()
+
+	req := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "temp", Type: constants.Gauge, Value: float64Ptr(1)}}}
+	_, err := client.UpdateMetrics(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.UpdateMetrics(signV2(t, ctx, testKey, req, time.Now()), req)
+	assert.NoError(t, err)
+
+	stream, err := client.StreamUpdates(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.StreamUpdatesRequest{Metrics: req.GetMetrics()}))
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Reads don't need signature.
+	_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "temp", Type: constants.Gauge})
+	assert.NoError(t, err)
+}
+
+func TestTrustedSubnetInterceptors(t *testing.T) {
+	checker, err := subnet.NewChecker("10.0.0.0/8,fd00::/8", subnet.SourceHeader)
+	require.NoError(t, err)
+
+	client := newTestClient(t, checker, auth.DefaultSignatureConfig)
+	ctx := context.Background()
+	req := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "temp", Type: constants.Gauge, Value: float64Ptr(1)}}}
+
+	_, err = client.UpdateMetrics(metadata.AppendToOutgoingContext(ctx, subnet.RealIPMetadataKey, "10.1.2.3"), req)
+	assert.NoError(t, err)
+
+	_, err = client.UpdateMetrics(metadata.AppendToOutgoingContext(ctx, subnet.RealIPMetadataKey, "fd00::1"), req)
+	assert.NoError(t, err)
+
+	_, err = client.UpdateMetrics(metadata.AppendToOutgoingContext(ctx, subnet.RealIPMetadataKey, "192.168.0.1"), req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.UpdateMetrics(ctx, req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err := client.StreamUpdates(ctx)
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Reads are allowed from any address.
+	_, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+	assert.NoError(t, err)
+}
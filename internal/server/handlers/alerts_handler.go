@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/VOTONO/go-metrics/internal/server/alerting"
+)
+
+// AlertsHandler returns pending and firing alerts in JSON format.
+func AlertsHandler(engine *alerting.Engine) http.HandlerFunc {
+	return func(res http.ResponseWriter, _ *http.Request) {
+		out, err := json.Marshal(engine.Active())
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusOK)
+		res.Write(out)
+	}
+}
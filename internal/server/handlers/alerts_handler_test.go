@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/alerting"
+	"github.com/VOTONO/go-metrics/internal/server/handlers/utils"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+func TestAlertsHandler(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("can't initialize zap logger: %v", err)
+	}
+	defer logger.Sync()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metricStorer := mocks.NewMockMetricStorer(ctrl)
+	metricStorer.EXPECT().All(gomock.Any()).Return(map[string]models.Metric{
+		utils.ValidGaugeMetric.Key(): utils.ValidGaugeMetric,
+	}, nil)
+
+	zapLogger := *logger.Sugar()
+	engine := alerting.NewEngine(metricStorer, []alerting.Rule{
+		{Name: "GaugeTooHigh", MetricID: utils.ValidGaugeMetric.ID, Op: alerting.OpGreater, Threshold: 100, Severity: "warning"},
+		{Name: "GaugeTooLow", MetricID: utils.ValidGaugeMetric.ID, Op: alerting.OpLess, Threshold: 100},
+	}, &zapLogger, 1)
+	require.NoError(t, engine.Evaluate(context.Background(), time.Now()))
+
+	server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, &zapLogger, "", router.WithAlerts(engine)))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/alerts")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var alerts []alerting.Alert
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&alerts))
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "GaugeTooHigh", alerts[0].Rule)
+	assert.Equal(t, alerting.StateFiring, alerts[0].State)
+	assert.Equal(t, utils.ValidFloat64, alerts[0].Value)
+}
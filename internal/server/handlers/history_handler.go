@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+// defaultHistoryRange is used when "from" query parameter is missing.
+const defaultHistoryRange = time.Hour
+
+type historyResponse struct {
+	ID         string            `json:"id"`
+	MType      string            `json:"type"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	Resolution string            `json:"resolution"`
+	Samples    []models.Sample   `json:"samples"`
+}
+
+// HistoryHandler returns samples of metric in [from, to] range in JSON format.
+// Range bounds are accepted in RFC3339 format or as unix timestamps in seconds.
+// Optional step (duration like "5m" or seconds) selects the coarsest rollup resolution that satisfies it.
+func HistoryHandler(storer repo.HistoryStorer) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		metricType := chi.URLParam(req, "metricType")
+		name := chi.URLParam(req, "metricName")
+
+		if metricType != constants.Gauge && metricType != constants.Counter {
+			http.Error(res, "invalid metric type", http.StatusBadRequest)
+			return
+		}
+
+		labels, err := labelsFromQuery(req)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		to, err := parseTimeParam(req.URL.Query().Get("to"), time.Now())
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, err := parseTimeParam(req.URL.Query().Get("from"), to.Add(-defaultHistoryRange))
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if from.After(to) {
+			http.Error(res, "from must not be after to", http.StatusBadRequest)
+			return
+		}
+
+		step, err := parseStepParam(req.URL.Query().Get("step"))
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resolution := repo.ResolutionForStep(step)
+
+		ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+		defer cancel()
+
+		samples, err := storer.History(ctx, models.MetricKey(metricType, name, labels), from, to, resolution)
+		if err != nil {
+			http.Error(res, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		out, err := json.Marshal(historyResponse{
+			ID:         name,
+			Labels:     labels,
+			MType:      metricType,
+			From:       from,
+			To:         to,
+			Resolution: resolutionName(resolution),
+			Samples:    samples,
+		})
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusOK)
+		res.Write(out)
+	}
+}
+
+// parseTimeParam parses RFC3339 time or unix timestamp in seconds, returns fallback for empty value.
+func parseTimeParam(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return t, nil
+}
+
+// parseStepParam parses duration like "5m" or number of seconds, empty step means raw samples.
+func parseStepParam(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	step, err := time.ParseDuration(value)
+	if err != nil || step < 0 {
+		return 0, fmt.Errorf("invalid step %q", value)
+	}
+	return step, nil
+}
+
+func resolutionName(resolution time.Duration) string {
+	if resolution == repo.ResolutionRaw {
+		return "raw"
+	}
+	return resolution.String()
+}
@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/handlers/utils"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	zapLogger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewRingHistoryStorer(repo.NewLocalMetricStorer(false, "", zapLogger), 10)
+
+	_, err := storer.StoreSingle(context.Background(), utils.ValidGaugeMetric)
+	require.NoError(t, err)
+	_, err = storer.StoreSingle(context.Background(), utils.ValidGaugeMetric)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router.Router(storer, &sql.DB{}, zapLogger, ""))
+	defer server.Close()
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		wantSamples  int
+	}{
+		{
+			name:         "Default range",
+			url:          fmt.Sprintf("/history/gauge/%s", utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusOK,
+			wantSamples:  2,
+		},
+		{
+			name:         "Explicit range",
+			url:          fmt.Sprintf("/history/gauge/%s?from=%d&to=%s", utils.ValidGaugeMetric.ID, time.Now().Add(-time.Minute).Unix(), url.QueryEscape(future)),
+			expectedCode: http.StatusOK,
+			wantSamples:  2,
+		},
+		{
+			name:         "Range in the past",
+			url:          fmt.Sprintf("/history/gauge/%s?to=%d", utils.ValidGaugeMetric.ID, time.Now().Add(-time.Hour).Unix()),
+			expectedCode: http.StatusOK,
+			wantSamples:  0,
+		},
+		{
+			name:         "Other type",
+			url:          fmt.Sprintf("/history/counter/%s", utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusOK,
+			wantSamples:  0,
+		},
+		{
+			name:         "Minute step without rollup",
+			url:          fmt.Sprintf("/history/gauge/%s?step=5m", utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusOK,
+			wantSamples:  0,
+		},
+		{
+			name:         "Invalid step",
+			url:          fmt.Sprintf("/history/gauge/%s?step=often", utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid type",
+			url:          fmt.Sprintf("/history/unknown/%s", utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Invalid time",
+			url:          fmt.Sprintf("/history/gauge/%s?from=yesterday", utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "From after to",
+			url:          fmt.Sprintf("/history/gauge/%s?from=200&to=100", utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := server.Client().Get(server.URL + test.url)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode)
+			if test.expectedCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				ID      string          `json:"id"`
+				Samples []models.Sample `json:"samples"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, utils.ValidGaugeMetric.ID, body.ID)
+			assert.Len(t, body.Samples, test.wantSamples)
+		})
+	}
+}
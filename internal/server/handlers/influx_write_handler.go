@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+// InfluxWriteHandler accepts metrics in InfluxDB line protocol, see ingest.InfluxDecoder.
+// Request is rejected as a whole if any line is malformed.
+func InfluxWriteHandler(storer repo.MetricStorer) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		metrics, err := ingest.DecodeLines(req.Body, ingest.InfluxDecoder{})
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(metrics) > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+			defer cancel()
+
+			if storeErr := storeMetricsWithRetry(ctx, storer, metrics, 3, 1*time.Second); storeErr != nil {
+				http.Error(res, "fail store metric", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		res.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+func TestInfluxWriteHandler(t *testing.T) {
+	zapLogger := zaptest.NewLogger(t).Sugar()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metricStorer := mocks.NewMockMetricStorer(ctrl)
+
+	server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, zapLogger, ""))
+	defer server.Close()
+
+	usage := 12.5
+	requests := int64(3)
+	labels := map[string]string{"host": "a"}
+	metricStorer.EXPECT().StoreSlice(gomock.Any(), []models.Metric{
+		{ID: "cpu_usage", MType: constants.Gauge, Value: &usage, Labels: labels},
+		{ID: "cpu_requests", MType: constants.Counter, Delta: &requests, Labels: labels},
+	}).Return(nil)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "Valid request",
+			body:         "cpu,host=a usage=12.5,requests=3i 1700000000000000000\n",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Malformed line",
+			body:         "cpu,host=a usage=12.5\ncpu usage\n",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := server.Client().Post(server.URL+"/write", "text/plain", strings.NewReader(test.body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// labelsFromQuery reads metric labels from repeated "label=name:value" query parameters.
+// Returns nil if request has no labels, so label-less metrics keep their old identity.
+func labelsFromQuery(req *http.Request) (map[string]string, error) {
+	values := req.URL.Query()["label"]
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	labels := make(map[string]string, len(values))
+	for _, value := range values {
+		name, labelValue, found := strings.Cut(value, ":")
+		if !found || !models.ValidLabelName(name) {
+			return nil, fmt.Errorf("invalid label %q, expected name:value", value)
+		}
+		labels[name] = labelValue
+	}
+	return labels, nil
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/server/exposition"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+// MetricsHandler returns all metrics in Prometheus text format, or in OpenMetrics format if client accepts it.
+func MetricsHandler(storer repo.MetricStorer, logger *zap.SugaredLogger) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+		defer cancel()
+
+		metrics, err := fetchMetricsWithRetry(ctx, storer, 3, 1*time.Second)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		format := exposition.Negotiate(req.Header.Get("Accept"))
+
+		res.Header().Set("Content-Type", format.ContentType())
+		res.WriteHeader(http.StatusOK)
+		if writeErr := exposition.Write(res, metrics, format); writeErr != nil {
+			logger.Errorw("failed to write metrics", "error", writeErr.Error())
+		}
+	}
+}
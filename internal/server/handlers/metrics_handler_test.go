@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/exposition"
+	"github.com/VOTONO/go-metrics/internal/server/handlers/utils"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("can't initialize zap logger: %v", err)
+	}
+	defer logger.Sync()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metricStorer := mocks.NewMockMetricStorer(ctrl)
+	metricStorer.EXPECT().All(gomock.Any()).Return(map[string]models.Metric{
+		utils.ValidGaugeMetric.Key():   utils.ValidGaugeMetric,
+		utils.ValidCounterMetric.Key(): utils.ValidCounterMetric,
+	}, nil).Times(2)
+
+	zapLogger := *logger.Sugar()
+	server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, &zapLogger, ""))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		contains    []string
+	}{
+		{
+			name:        "Prometheus text",
+			contentType: exposition.ContentTypeText,
+			contains: []string{
+				"# TYPE validGaugeMetric gauge\nvalidGaugeMetric 456.78\n",
+				"# TYPE validCounterMetric_total counter\nvalidCounterMetric_total 456\n",
+			},
+		},
+		{
+			name:        "OpenMetrics",
+			accept:      "application/openmetrics-text; version=1.0.0",
+			contentType: exposition.ContentTypeOpenMetrics,
+			contains: []string{
+				"# TYPE validCounterMetric counter\nvalidCounterMetric_total 456\n",
+				"# EOF\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/metrics", nil)
+			require.NoError(t, err)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+
+			resp, err := server.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, test.contentType, resp.Header.Get("Content-Type"))
+			for _, part := range test.contains {
+				assert.Contains(t, string(body), part)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// maxOTLPBodySize is limit of OTLP request body. Gzip body is decoded by compressor.Decompressor
+// before handler, so limit applies to decoded size and bounds compressed body as well.
+const maxOTLPBodySize = 16 << 20
+
+// OTLPMetricsHandler accepts OTLP/HTTP ExportMetricsServiceRequest in protobuf or JSON encoding.
+// Response is encoded the same way as request. Data points that can't be stored are reported
+// in partial_success of response, as OTLP specifies, the rest of request is stored.
+// Cumulative sums are converted to increments by counters, fractional delta sums by increments.
+func OTLPMetricsHandler(storer repo.MetricStorer, counters *ingest.CumulativeConverter, increments *ingest.DeltaAccumulator) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+			http.Error(res, "unsupported content type", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var buf bytes.Buffer
+		_, err := buf.ReadFrom(http.MaxBytesReader(res, req.Body, maxOTLPBodySize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(res, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var exportRequest ingest.OTLPRequest
+		if contentType == contentTypeProtobuf {
+			exportRequest, err = ingest.ParseOTLPProtobuf(buf.Bytes())
+		} else {
+			exportRequest, err = ingest.ParseOTLPJSON(buf.Bytes())
+		}
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		metrics, commit, rejected, message := ingest.OTLPMetrics(exportRequest, counters, increments)
+
+		if len(metrics) > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+			defer cancel()
+
+			if storeErr := storeMetricsWithRetry(ctx, storer, metrics, 3, 1*time.Second); storeErr != nil {
+				// 503 tells OTLP exporters to retry request.
+				http.Error(res, "fail store metric", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		commit()
+
+		res.Header().Set("Content-Type", contentType)
+		res.WriteHeader(http.StatusOK)
+		if contentType == contentTypeProtobuf {
+			res.Write(otlpProtobufResponse(rejected, message))
+		} else {
+			res.Write(otlpJSONResponse(rejected, message))
+		}
+	}
+}
+
+// otlpProtobufResponse encodes ExportMetricsServiceResponse, partial_success is omitted if all points are accepted.
+func otlpProtobufResponse(rejected int64, message string) []byte {
+	if rejected == 0 && message == "" {
+		return nil
+	}
+
+	var partial []byte
+	partial = protowire.AppendTag(partial, 1, protowire.VarintType)
+	partial = protowire.AppendVarint(partial, uint64(rejected))
+	partial = protowire.AppendTag(partial, 2, protowire.BytesType)
+	partial = protowire.AppendString(partial, message)
+
+	response := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(response, partial)
+}
+
+// otlpJSONResponse encodes ExportMetricsServiceResponse in JSON, 64-bit integers are strings in OTLP JSON.
+func otlpJSONResponse(rejected int64, message string) []byte {
+	type partialSuccess struct {
+		RejectedDataPoints string `json:"rejectedDataPoints"`
+		ErrorMessage       string `json:"errorMessage"`
+	}
+	type response struct {
+		PartialSuccess *partialSuccess `json:"partialSuccess,omitempty"`
+	}
+
+	var resp response
+	if rejected != 0 || message != "" {
+		resp.PartialSuccess = &partialSuccess{RejectedDataPoints: strconv.FormatInt(rejected, 10), ErrorMessage: message}
+	}
+	data, _ := json.Marshal(resp)
+	return data
+}
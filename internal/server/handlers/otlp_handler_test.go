@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+func TestOTLPMetricsHandler(t *testing.T) {
+	zapLogger := zaptest.NewLogger(t).Sugar()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metricStorer := mocks.NewMockMetricStorer(ctrl)
+
+	server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, zapLogger, ""))
+	defer server.Close()
+
+	value := 3.0
+	metricStorer.EXPECT().StoreSlice(gomock.Any(), []models.Metric{
+		{ID: "queue.size", MType: constants.Gauge, Value: &value, Labels: map[string]string{"service_name": "api"}},
+	}).Return(nil)
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:        "Partial success",
+			contentType: "application/json",
+			body: `{"resourceMetrics":[{
+				"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+				"scopeMetrics":[{"metrics":[
+					{"name":"queue.size","gauge":{"dataPoints":[{"asDouble":3}]}},
+					{"name":"latency","histogram":{"dataPoints":[{}]}}
+				]}]
+			}]}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"partialSuccess":{"rejectedDataPoints":"1","errorMessage":"metric \"latency\" has unsupported data type"}}`,
+		},
+		{
+			name:         "Invalid JSON",
+			contentType:  "application/json",
+			body:         `{"resourceMetrics":`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Unsupported content type",
+			contentType:  "text/plain",
+			body:         "queue.size 3",
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := server.Client().Post(server.URL+"/v1/metrics", test.contentType, strings.NewReader(test.body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode)
+			if test.expectedBody != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.JSONEq(t, test.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func TestOTLPMetricsHandlerBodyLimit(t *testing.T) {
+	zapLogger := zaptest.NewLogger(t).Sugar()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := httptest.NewServer(router.Router(mocks.NewMockMetricStorer(ctrl), &sql.DB{}, zapLogger, ""))
+	defer server.Close()
+
+	body := bytes.Repeat([]byte{0}, 17<<20)
+
+	resp, err := server.Client().Post(server.URL+"/v1/metrics", "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// Small gzip body that decodes to too large body.
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err = gz.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/metrics", &compressed)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = server.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
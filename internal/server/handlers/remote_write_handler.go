@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+// Limits of remote-write request, Prometheus sends batches of a few megabytes.
+const (
+	maxRemoteWriteBodySize    = 16 << 20 // compressed body
+	maxRemoteWriteDecodedSize = 64 << 20 // body after snappy decoding
+)
+
+// RemoteWriteHandler accepts Prometheus remote-write requests: snappy compressed protobuf WriteRequest.
+// Cumulative counters are converted to increments by counters before storing.
+func RemoteWriteHandler(storer repo.MetricStorer, counters *ingest.CumulativeConverter) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+
+		_, err := buf.ReadFrom(http.MaxBytesReader(res, req.Body, maxRemoteWriteBodySize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(res, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Decoded length is declared by body, it's checked before buffer is allocated.
+		decodedLen, err := snappy.DecodedLen(buf.Bytes())
+		if err != nil {
+			http.Error(res, "invalid snappy body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if decodedLen > maxRemoteWriteDecodedSize {
+			http.Error(res, "decoded body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		data, err := snappy.Decode(nil, buf.Bytes())
+		if err != nil {
+			http.Error(res, "invalid snappy body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeRequest, err := ingest.ParseWriteRequest(data)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		metrics, commit, err := ingest.RemoteWriteMetrics(writeRequest, counters)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(metrics) > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+			defer cancel()
+
+			if storeErr := storeMetricsWithRetry(ctx, storer, metrics, 3, 1*time.Second); storeErr != nil {
+				http.Error(res, "fail store metric", http.StatusInternalServerError)
+				return
+			}
+		}
+		commit()
+
+		res.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+func TestRemoteWriteHandler(t *testing.T) {
+	zapLogger := zaptest.NewLogger(t).Sugar()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metricStorer := mocks.NewMockMetricStorer(ctrl)
+
+	server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, zapLogger, ""))
+	defer server.Close()
+
+	// WriteRequest with single series up{job="api"} 1.
+	var label, sample, series, body []byte
+	label = protowire.AppendTag(label, 1, protowire.BytesType)
+	label = protowire.AppendString(label, "__name__")
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, "up")
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	series = protowire.AppendBytes(series, label)
+	label = protowire.AppendTag(nil, 1, protowire.BytesType)
+	label = protowire.AppendString(label, "job")
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, "api")
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	series = protowire.AppendBytes(series, label)
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(1))
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+	body = protowire.AppendTag(body, 1, protowire.BytesType)
+	body = protowire.AppendBytes(body, series)
+
+	value := float64(1)
+	metricStorer.EXPECT().StoreSlice(gomock.Any(), []models.Metric{
+		{ID: "up", MType: constants.Gauge, Value: &value, Labels: map[string]string{"job": "api"}},
+	}).Return(nil)
+
+	tests := []struct {
+		name         string
+		body         []byte
+		expectedCode int
+	}{
+		{
+			name:         "Valid request",
+			body:         snappy.Encode(nil, body),
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Not compressed body",
+			body:         body,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Body too large",
+			body:         bytes.Repeat([]byte{0}, 17<<20),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "Decoded body too large",
+			body:         append(protowire.AppendVarint(nil, 1<<30), 0, 0, 0, 0),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "Invalid protobuf",
+			body:         snappy.Encode(nil, []byte{0x0a, 0xff}),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/api/v1/write", bytes.NewReader(test.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			req.Header.Set("Content-Encoding", "snappy")
+
+			resp, err := server.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode)
+		})
+	}
+}
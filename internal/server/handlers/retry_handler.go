@@ -86,14 +86,14 @@ func storeMetricWithRetry(ctx context.Context, storer repo.MetricStorer, metric
 	return nil, err
 }
 
-func getMetricWithRetry(ctx context.Context, storer repo.MetricStorer, id string, retryCount int, initialPause time.Duration) (models.Metric, bool, error) {
+func getMetricWithRetry(ctx context.Context, storer repo.MetricStorer, key string, retryCount int, initialPause time.Duration) (models.Metric, bool, error) {
 	retryPause := initialPause
 	var err error
 
 	for i := 0; i <= retryCount; i++ {
 		var metric models.Metric
 		var found bool
-		metric, found, err = storer.Get(ctx, id)
+		metric, found, err = storer.Get(ctx, key)
 
 		if err == nil {
 			return metric, found, nil
@@ -0,0 +1,140 @@
+package handlers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/handlers/utils"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+const signatureTestKey = "secret"
+
+// TestSignedRequests sends signed, unsigned and tampered requests through the full router chain.
+func TestSignedRequests(t *testing.T) {
+	metrics := []models.Metric{utils.ValidCounterMetric, utils.ValidGaugeMetric}
+	body, err := json.Marshal(metrics)
+	require.NoError(t, err)
+
+	v2 := func(t *testing.T, req *http.Request) {
+		require.NoError(t, auth.SignRequest(req, signatureTestKey, body))
+	}
+	v1 := func(t *testing.T, req *http.Request) {
+		req.Header.Set(constants.HashSHA256, auth.Sign(signatureTestKey, body))
+	}
+	tampered := func(t *testing.T, req *http.Request) {
+		// Signature covers different body than the one sent.
+		require.NoError(t, auth.SignRequest(req, signatureTestKey, append([]byte(" "), body...)))
+	}
+	wrongKey := func(t *testing.T, req *http.Request) {
+		require.NoError(t, auth.SignRequest(req, "other", body))
+	}
+	malformed := func(t *testing.T, req *http.Request) {
+		v2(t, req)
+		req.Header.Set(constants.HashSHA256, "not hex")
+	}
+
+	tests := []struct {
+		name         string
+		config       auth.SignatureConfig
+		method       string
+		path         string
+		sign         func(t *testing.T, req *http.Request)
+		expectedCode int
+		expectStore  bool
+	}{
+		{name: "Signed write", config: auth.DefaultSignatureConfig, method: http.MethodPost, path: "/updates/", sign: v2, expectedCode: http.StatusOK, expectStore: true},
+		{name: "Unsigned write", config: auth.DefaultSignatureConfig, method: http.MethodPost, path: "/updates/", expectedCode: http.StatusOK, expectStore: true},
+		{name: "Tampered body", config: auth.DefaultSignatureConfig, method: http.MethodPost, path: "/updates/", sign: tampered, expectedCode: http.StatusUnauthorized},
+		{name: "Wrong key", config: auth.DefaultSignatureConfig, method: http.MethodPost, path: "/updates/", sign: wrongKey, expectedCode: http.StatusUnauthorized},
+		{name: "Malformed hash", config: auth.DefaultSignatureConfig, method: http.MethodPost, path: "/updates/", sign: malformed, expectedCode: http.StatusBadRequest},
+		{name: "V1 write rejected", config: auth.DefaultSignatureConfig, method: http.MethodPost, path: "/updates/", sign: v1, expectedCode: http.StatusUnauthorized},
+		{name: "V1 write accepted", config: auth.SignatureConfig{MaxSkew: auth.DefaultSignatureConfig.MaxSkew, NonceCacheSize: 10, AcceptV1: true}, method: http.MethodPost, path: "/updates/", sign: v1, expectedCode: http.StatusOK, expectStore: true},
+		{name: "Strict signed write", config: auth.SignatureConfig{Strict: true, MaxSkew: auth.DefaultSignatureConfig.MaxSkew, NonceCacheSize: 10}, method: http.MethodPost, path: "/updates/", sign: v2, expectedCode: http.StatusOK, expectStore: true},
+		{name: "Strict unsigned write", config: auth.SignatureConfig{Strict: true, MaxSkew: auth.DefaultSignatureConfig.MaxSkew, NonceCacheSize: 10}, method: http.MethodPost, path: "/updates/", expectedCode: http.StatusUnauthorized},
+		{name: "Strict unsigned read", config: auth.SignatureConfig{Strict: true, MaxSkew: auth.DefaultSignatureConfig.MaxSkew, NonceCacheSize: 10}, method: http.MethodGet, path: "/", expectedCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			metricStorer := mocks.NewMockMetricStorer(ctrl)
+			if test.expectStore {
+				metricStorer.EXPECT().StoreSlice(gomock.Any(), metrics).Return(nil)
+			}
+			metricStorer.EXPECT().All(gomock.Any()).Return(map[string]models.Metric{}, nil).AnyTimes()
+
+			server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, zaptest.NewLogger(t).Sugar(), signatureTestKey,
+				router.WithSignatureConfig(test.config)))
+			defer server.Close()
+
+			req, err := http.NewRequest(test.method, server.URL+test.path, bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if test.sign != nil {
+				test.sign(t, req)
+			}
+
+			resp, err := server.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode)
+			if test.expectedCode == http.StatusOK {
+				respBody, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.True(t, auth.Verify(signatureTestKey, respBody, resp.Header.Get(constants.HashSHA256)), "response must be signed")
+			}
+			if test.expectedCode == http.StatusUnauthorized {
+				assert.Equal(t, constants.HashSHA256, resp.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// TestSignedLargeRequest checks that body larger than memory limit of verification reaches handler intact.
+func TestSignedLargeRequest(t *testing.T) {
+	metrics := make([]models.Metric, 20000)
+	for i := range metrics {
+		value := float64(i)
+		metrics[i] = models.Metric{ID: fmt.Sprintf("gauge_with_a_rather_long_name_%d", i), MType: constants.Gauge, Value: &value}
+	}
+	body, err := json.Marshal(metrics)
+	require.NoError(t, err)
+	require.Greater(t, len(body), 1<<20)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	metricStorer := mocks.NewMockMetricStorer(ctrl)
+	metricStorer.EXPECT().StoreSlice(gomock.Any(), metrics).Return(nil)
+
+	server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, zaptest.NewLogger(t).Sugar(), signatureTestKey))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/updates/", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	require.NoError(t, auth.SignRequest(req, signatureTestKey, body))
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
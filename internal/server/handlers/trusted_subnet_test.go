@@ -0,0 +1,65 @@
+package handlers_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+	"github.com/VOTONO/go-metrics/internal/subnet"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	zapLogger := zaptest.NewLogger(t).Sugar()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metricStorer := mocks.NewMockMetricStorer(ctrl)
+	metricStorer.EXPECT().StoreSlice(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	metricStorer.EXPECT().All(gomock.Any()).Return(map[string]models.Metric{}, nil).AnyTimes()
+
+	checker, err := subnet.NewChecker("10.0.0.0/8,fd00::/8", subnet.SourceHeader)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router.Router(metricStorer, &sql.DB{}, zapLogger, "", router.WithTrustedSubnet(checker)))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		realIP       string
+		expectedCode int
+	}{
+		{name: "Write from trusted agent", method: http.MethodPost, path: "/updates/", realIP: "fd00::5", expectedCode: http.StatusOK},
+		{name: "Write from untrusted agent", method: http.MethodPost, path: "/updates/", realIP: "192.168.0.1", expectedCode: http.StatusForbidden},
+		{name: "Write without IP", method: http.MethodPost, path: "/update/gauge/temp/1", expectedCode: http.StatusForbidden},
+		{name: "Read from untrusted agent", method: http.MethodGet, path: "/metrics", realIP: "192.168.0.1", expectedCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, server.URL+test.path, strings.NewReader("[]"))
+			require.NoError(t, err)
+			if test.realIP != "" {
+				req.Header.Set(subnet.RealIPHeader, test.realIP)
+			}
+
+			resp, err := server.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode)
+		})
+	}
+}
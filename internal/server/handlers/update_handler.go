@@ -26,6 +26,12 @@ func UpdateHandler(storer repo.MetricStorer) http.HandlerFunc {
 			return
 		}
 
+		newMetric.Labels, err = labelsFromQuery(req)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
 		defer cancel()
 
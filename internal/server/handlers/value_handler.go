@@ -9,23 +9,31 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/VOTONO/go-metrics/internal/helpers"
+	"github.com/VOTONO/go-metrics/internal/models"
 	"github.com/VOTONO/go-metrics/internal/server/repo"
 )
 
-// ValueHandler retrieve metric name from URLParams and return value.
+// ValueHandler retrieve metric type and name from URLParams and return value.
 func ValueHandler(storer repo.MetricStorer) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 
+		metricType := chi.URLParam(req, "metricType")
 		name := chi.URLParam(req, "metricName")
 
 		if name == "" {
 			http.Error(res, "Invalide metric name", http.StatusNotFound)
 		}
 
+		labels, err := labelsFromQuery(req)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
 		defer cancel()
 
-		metric, found, getErr := getMetricWithRetry(ctx, storer, name, 3, 1*time.Second)
+		metric, found, getErr := getMetricWithRetry(ctx, storer, models.MetricKey(metricType, name, labels), 3, 1*time.Second)
 
 		if getErr != nil {
 			http.Error(res, "Internal Server Error", http.StatusInternalServerError)
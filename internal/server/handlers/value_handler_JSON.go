@@ -7,11 +7,20 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/helpers"
 	"github.com/VOTONO/go-metrics/internal/models"
 	"github.com/VOTONO/go-metrics/internal/server/repo"
 )
 
+// valueResponse is a stored metric with quantiles of histogram or summary.
+type valueResponse struct {
+	models.Metric
+	Quantiles map[string]float64 `json:"quantiles,omitempty"` // e.g. "p99" -> value
+}
+
 // ValueHandlerJSON retrieve metric name from body in JSON format and return value.
+// Response of histogram and summary also contains p50, p90 and p99.
 func ValueHandlerJSON(storer repo.MetricStorer) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		var metric models.Metric
@@ -31,7 +40,7 @@ func ValueHandlerJSON(storer repo.MetricStorer) http.HandlerFunc {
 		ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
 		defer cancel()
 
-		storedMetric, found, getErr := getMetricWithRetry(ctx, storer, metric.ID, 3, 1*time.Second)
+		storedMetric, found, getErr := getMetricWithRetry(ctx, storer, metric.Key(), 3, 1*time.Second)
 
 		if getErr != nil {
 			http.Error(res, "Internal Server Error", http.StatusInternalServerError)
@@ -43,7 +52,16 @@ func ValueHandlerJSON(storer repo.MetricStorer) http.HandlerFunc {
 			return
 		}
 
-		out, err := json.Marshal(storedMetric)
+		response := valueResponse{Metric: storedMetric}
+		if storedMetric.MType == constants.Histogram || storedMetric.MType == constants.Summary {
+			response.Quantiles, err = helpers.Quantiles(storedMetric)
+			if err != nil {
+				http.Error(res, "Invalid metric value", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		out, err := json.Marshal(response)
 		if err != nil {
 			http.Error(res, err.Error(), http.StatusInternalServerError)
 			return
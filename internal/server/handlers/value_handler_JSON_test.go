@@ -59,7 +59,7 @@ func TestValueHandlerJSON(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 
 			if test.expectedCode == http.StatusOK {
-				metricStorer.EXPECT().Get(gomock.Any(), test.expectedMetric.ID).Return(test.expectedMetric, true, nil)
+				metricStorer.EXPECT().Get(gomock.Any(), test.expectedMetric.Key()).Return(test.expectedMetric, true, nil)
 			}
 
 			jsonBody, err := json.Marshal(test.expectedMetric)
@@ -39,14 +39,29 @@ func TestValueHandler(t *testing.T) {
 		name         string
 		method       string
 		url          string
+		key          string
 		expectedCode int
 	}{
 		{
 			name:         "Valid get",
 			method:       "GET",
 			url:          fmt.Sprintf("/value/%v/%v", utils.ValidGaugeMetric.MType, utils.ValidGaugeMetric.ID),
+			key:          utils.ValidGaugeMetric.Key(),
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:         "Valid get with labels",
+			method:       "GET",
+			url:          fmt.Sprintf("/value/%v/%v?label=instance:b&label=host:a", utils.ValidGaugeMetric.MType, utils.ValidGaugeMetric.ID),
+			key:          utils.ValidGaugeMetric.Key() + `{host="a",instance="b"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Invalid label",
+			method:       "GET",
+			url:          fmt.Sprintf("/value/%v/%v?label=host", utils.ValidGaugeMetric.MType, utils.ValidGaugeMetric.ID),
+			expectedCode: http.StatusBadRequest,
+		},
 		{
 			name:         "Invalid method",
 			method:       "POST",
@@ -65,7 +80,7 @@ func TestValueHandler(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 
 			if test.expectedCode == http.StatusOK {
-				metricStorer.EXPECT().Get(gomock.Any(), utils.ValidGaugeMetric.ID).Return(utils.ValidGaugeMetric, true, nil)
+				metricStorer.EXPECT().Get(gomock.Any(), test.key).Return(utils.ValidGaugeMetric, true, nil)
 			}
 
 			req, err := http.NewRequest(test.method, server.URL+test.url, nil)
@@ -0,0 +1,65 @@
+// Package ingest converts metrics received in foreign formats to models.Metric.
+package ingest
+
+import (
+	"math"
+	"sync"
+)
+
+// CumulativePoint is a value of monotonic counter that reports total since its start.
+type CumulativePoint struct {
+	Key   string // identity of series, see models.Metric.Key
+	Value float64
+}
+
+// CumulativeConverter converts cumulative counters to increments expected by counter metrics.
+// It remembers the last value of every series. First value of series is only a baseline and is
+// reported as zero increment, since total accumulated before it may be already stored, e.g. before
+// server restart. Value lower than previous one is treated as counter reset.
+type CumulativeConverter struct {
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+// NewCumulativeConverter creates converter without known series.
+func NewCumulativeConverter() *CumulativeConverter {
+	return &CumulativeConverter{last: make(map[string]float64)}
+}
+
+// Deltas returns increments of points in the same order. Points of the same series must be in chronological order.
+// Returned commit saves points as new baseline and must be called only after increments are stored,
+// so points of failed request produce the same increments when request is retried.
+func (c *CumulativeConverter) Deltas(points []CumulativePoint) ([]int64, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := make(map[string]float64)
+	deltas := make([]int64, len(points))
+	for i, point := range points {
+		last, found := pending[point.Key]
+		if !found {
+			last, found = c.last[point.Key]
+		}
+
+		current := math.Round(point.Value)
+		switch {
+		case !found:
+			deltas[i] = 0
+		case current < last:
+			deltas[i] = int64(current)
+		default:
+			deltas[i] = int64(current - last)
+		}
+		pending[point.Key] = current
+	}
+
+	commit := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for key, value := range pending {
+			c.last[key] = value
+		}
+	}
+	return deltas, commit
+}
@@ -0,0 +1,30 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCumulativeConverter(t *testing.T) {
+	converter := NewCumulativeConverter()
+
+	deltas, commit := converter.Deltas([]CumulativePoint{
+		{Key: "a", Value: 5},
+		{Key: "a", Value: 8},
+		{Key: "b", Value: 2},
+	})
+	assert.Equal(t, []int64{0, 3, 0}, deltas)
+
+	// Not committed request is converted again from the same baseline.
+	deltas, _ = converter.Deltas([]CumulativePoint{{Key: "a", Value: 8}})
+	assert.Equal(t, []int64{0}, deltas)
+
+	commit()
+
+	deltas, _ = converter.Deltas([]CumulativePoint{
+		{Key: "a", Value: 10},
+		{Key: "b", Value: 1}, // counter reset
+	})
+	assert.Equal(t, []int64{2, 1}, deltas)
+}
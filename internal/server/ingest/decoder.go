@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// Decoder decodes single line of line-based ingestion format, such as Graphite plaintext or InfluxDB line protocol.
+type Decoder interface {
+	// Decode returns metrics of line. Line is passed without trailing newline.
+	Decode(line string) ([]models.Metric, error)
+}
+
+// DecodeLines decodes every line of r with decoder. Empty lines and comments starting with '#' are skipped.
+func DecodeLines(r io.Reader, decoder Decoder) ([]models.Metric, error) {
+	var metrics []models.Metric
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		decoded, err := decoder.Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		metrics = append(metrics, decoded...)
+	}
+	return metrics, scanner.Err()
+}
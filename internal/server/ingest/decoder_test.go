@@ -0,0 +1,106 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func TestGraphiteDecoder(t *testing.T) {
+	value := 42.5
+
+	tests := []struct {
+		name    string
+		line    string
+		want    []models.Metric
+		wantErr bool
+	}{
+		{
+			name: "plain path",
+			line: "servers.web1.load 42.5 1700000000",
+			want: []models.Metric{{ID: "servers.web1.load", MType: constants.Gauge, Value: &value}},
+		},
+		{
+			name: "tagged path without timestamp",
+			line: "load;host=web1;dc=eu 42.5",
+			want: []models.Metric{{ID: "load", MType: constants.Gauge, Value: &value, Labels: map[string]string{"host": "web1", "dc": "eu"}}},
+		},
+		{name: "missing value", line: "servers.web1.load", wantErr: true},
+		{name: "invalid value", line: "servers.web1.load abc 1700000000", wantErr: true},
+		{name: "invalid timestamp", line: "servers.web1.load 1 now", wantErr: true},
+		{name: "invalid tag", line: "load;host 1 1700000000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GraphiteDecoder{}.Decode(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInfluxDecoder(t *testing.T) {
+	usage, temp, one := 12.5, -3.0, 1.0
+	requests, bytes := int64(7), int64(1024)
+	labels := map[string]string{"host": "server 1", "region": "eu,west"}
+
+	tests := []struct {
+		name    string
+		line    string
+		want    []models.Metric
+		wantErr bool
+	}{
+		{
+			name: "fields of all types",
+			line: `cpu,host=server\ 1,region=eu\,west usage=12.5,requests=7i,bytes=1024u,up=true,state="ok ,=" 1700000000000000000`,
+			want: []models.Metric{
+				{ID: "cpu_usage", MType: constants.Gauge, Value: &usage, Labels: labels},
+				{ID: "cpu_requests", MType: constants.Counter, Delta: &requests, Labels: labels},
+				{ID: "cpu_bytes", MType: constants.Counter, Delta: &bytes, Labels: labels},
+				{ID: "cpu_up", MType: constants.Gauge, Value: &one, Labels: labels},
+			},
+		},
+		{
+			name: "default field",
+			line: "temperature value=-3",
+			want: []models.Metric{{ID: "temperature", MType: constants.Gauge, Value: &temp}},
+		},
+		{name: "missing fields", line: "cpu,host=a", wantErr: true},
+		{name: "invalid integer", line: "cpu requests=1.5i", wantErr: true},
+		{name: "invalid float", line: "cpu usage=abc", wantErr: true},
+		{name: "invalid tag name", line: "cpu,1host=a usage=1", wantErr: true},
+		{name: "invalid timestamp", line: "cpu usage=1 now", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InfluxDecoder{}.Decode(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeLines(t *testing.T) {
+	metrics, err := DecodeLines(strings.NewReader("# comment\na 1 1700000000\n\nb 2 1700000000\n"), GraphiteDecoder{})
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "b", metrics[1].ID)
+
+	_, err = DecodeLines(strings.NewReader("a 1 1700000000\nb\n"), GraphiteDecoder{})
+	assert.ErrorContains(t, err, "line 2")
+}
@@ -0,0 +1,66 @@
+package ingest
+
+import (
+	"math"
+	"sync"
+)
+
+// deltaTolerance is error of float sum that is still treated as whole increment, e.g. 10 increments of 0.1.
+const deltaTolerance = 1e-9
+
+// DeltaPoint is an increment of counter that may be fractional, e.g. sampled StatsD counter or OTLP delta sum.
+type DeltaPoint struct {
+	Key   string // identity of series, see models.Metric.Key
+	Value float64
+}
+
+// DeltaAccumulator converts fractional increments to integer increments expected by counter metrics.
+// Fraction that doesn't make a whole increment is remembered for every series and added to its next
+// increment, so sub-1 increments are not lost.
+type DeltaAccumulator struct {
+	mu         sync.Mutex
+	remainders map[string]float64
+}
+
+// NewDeltaAccumulator creates accumulator without remainders.
+func NewDeltaAccumulator() *DeltaAccumulator {
+	return &DeltaAccumulator{remainders: make(map[string]float64)}
+}
+
+// Increments returns whole increments of points in the same order. Returned commit saves remainders
+// and must be called only after increments are stored, like CumulativeConverter.Deltas.
+func (a *DeltaAccumulator) Increments(points []DeltaPoint) ([]int64, func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pending := make(map[string]float64)
+	increments := make([]int64, len(points))
+	for i, point := range points {
+		remainder, found := pending[point.Key]
+		if !found {
+			remainder = a.remainders[point.Key]
+		}
+
+		total := remainder + point.Value
+		whole := math.Trunc(total)
+		if rounded := math.Round(total); math.Abs(total-rounded) < deltaTolerance {
+			whole = rounded
+		}
+		increments[i] = int64(whole)
+		pending[point.Key] = total - whole
+	}
+
+	commit := func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		for key, remainder := range pending {
+			if math.Abs(remainder) < deltaTolerance {
+				delete(a.remainders, key)
+			} else {
+				a.remainders[key] = remainder
+			}
+		}
+	}
+	return increments, commit
+}
@@ -0,0 +1,45 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeltaAccumulator(t *testing.T) {
+	accumulator := NewDeltaAccumulator()
+
+	increments, commit := accumulator.Increments([]DeltaPoint{
+		{Key: "a", Value: 0.4},
+		{Key: "a", Value: 0.4},
+		{Key: "b", Value: 2.5},
+	})
+	assert.Equal(t, []int64{0, 0, 2}, increments)
+
+	// Not committed request is converted again from the same remainders.
+	increments, _ = accumulator.Increments([]DeltaPoint{{Key: "a", Value: 0.4}})
+	assert.Equal(t, []int64{0}, increments)
+
+	commit()
+
+	increments, commit = accumulator.Increments([]DeltaPoint{
+		{Key: "a", Value: 0.4},
+		{Key: "b", Value: 0.5},
+	})
+	assert.Equal(t, []int64{1, 1}, increments)
+	commit()
+
+	// Float error of sub-1 increments doesn't lose whole increment.
+	points := make([]DeltaPoint, 10)
+	for i := range points {
+		points[i] = DeltaPoint{Key: "c", Value: 0.1}
+	}
+	increments, commit = accumulator.Increments(points)
+	var sum int64
+	for _, increment := range increments {
+		sum += increment
+	}
+	assert.Equal(t, int64(1), sum)
+	commit()
+	assert.NotContains(t, accumulator.remainders, "c")
+}
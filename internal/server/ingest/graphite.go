@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// GraphiteDecoder decodes Graphite plaintext lines "path[;tag=value...] value [timestamp]".
+// Graphite has no metric types, so every value is stored as gauge, tags become labels.
+// Timestamp is validated but not used, value is stored as current one.
+type GraphiteDecoder struct{}
+
+// Decode implements Decoder.
+func (GraphiteDecoder) Decode(line string) ([]models.Metric, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, errors.New("expected \"path value timestamp\"")
+	}
+
+	path, rawTags, _ := strings.Cut(fields[0], ";")
+	if path == "" {
+		return nil, errors.New("empty metric path")
+	}
+
+	var labels map[string]string
+	if rawTags != "" {
+		labels = make(map[string]string)
+		for _, tag := range strings.Split(rawTags, ";") {
+			name, value, found := strings.Cut(tag, "=")
+			if !found || value == "" {
+				return nil, fmt.Errorf("invalid tag %q", tag)
+			}
+			if !models.ValidLabelName(name) {
+				return nil, fmt.Errorf("invalid tag name %q", name)
+			}
+			labels[name] = value
+		}
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("invalid value %q", fields[1])
+	}
+
+	if len(fields) == 3 {
+		if _, err := strconv.ParseFloat(fields[2], 64); err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", fields[2])
+		}
+	}
+
+	return []models.Metric{{ID: path, MType: constants.Gauge, Value: &value, Labels: labels}}, nil
+}
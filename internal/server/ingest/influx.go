@@ -0,0 +1,170 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// influxDefaultField is field name that is not added to metric ID.
+const influxDefaultField = "value"
+
+// InfluxDecoder decodes InfluxDB line protocol "measurement[,tag=value...] field=value[,field=value...] [timestamp]".
+// Every field becomes separate metric with ID "measurement_field", or "measurement" for field "value".
+// Tags become labels. Integer fields ("1i", "1u") are counter increments, float and boolean fields are gauges,
+// string fields are skipped. Timestamp is not used, values are stored as current ones.
+type InfluxDecoder struct{}
+
+// Decode implements Decoder.
+func (InfluxDecoder) Decode(line string) ([]models.Metric, error) {
+	sections := splitUnescaped(line, ' ')
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, errors.New("expected \"measurement field=value timestamp\"")
+	}
+
+	series := splitUnescaped(sections[0], ',')
+	measurement := unescapeInflux(series[0])
+	if measurement == "" {
+		return nil, errors.New("empty measurement")
+	}
+
+	var labels map[string]string
+	for _, tag := range series[1:] {
+		name, value, err := splitInfluxPair(tag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag %q: %w", tag, err)
+		}
+		if !models.ValidLabelName(name) {
+			return nil, fmt.Errorf("invalid tag name %q", name)
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[name] = value
+	}
+
+	if len(sections) == 3 {
+		if _, err := strconv.ParseInt(sections[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", sections[2])
+		}
+	}
+
+	var metrics []models.Metric
+	for _, field := range splitUnescaped(sections[1], ',') {
+		name, rawValue, err := splitInfluxPair(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+
+		id := measurement
+		if name != influxDefaultField {
+			id = measurement + "_" + name
+		}
+		metric := models.Metric{ID: id, Labels: labels}
+
+		switch {
+		case strings.HasPrefix(rawValue, `"`):
+			continue
+		case strings.HasSuffix(rawValue, "i"):
+			delta, err := strconv.ParseInt(strings.TrimSuffix(rawValue, "i"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer field %q", field)
+			}
+			metric.MType = constants.Counter
+			metric.Delta = &delta
+		case strings.HasSuffix(rawValue, "u"):
+			unsigned, err := strconv.ParseUint(strings.TrimSuffix(rawValue, "u"), 10, 63)
+			if err != nil {
+				return nil, fmt.Errorf("invalid unsigned field %q", field)
+			}
+			delta := int64(unsigned)
+			metric.MType = constants.Counter
+			metric.Delta = &delta
+		default:
+			value, err := parseInfluxFloat(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field %q: %w", field, err)
+			}
+			metric.MType = constants.Gauge
+			metric.Value = &value
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// parseInfluxFloat parses float or boolean field value, booleans are 1 and 0.
+func parseInfluxFloat(raw string) (float64, error) {
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New("not a number")
+	}
+	return value, nil
+}
+
+// splitInfluxPair splits "key=value" on the first unescaped '=' and unescapes key.
+// Value is unescaped too, unless it is quoted string field.
+func splitInfluxPair(pair string) (string, string, error) {
+	parts := splitUnescaped(pair, '=')
+	if len(parts) < 2 || parts[0] == "" {
+		return "", "", errors.New("expected key=value")
+	}
+
+	value := strings.Join(parts[1:], "=")
+	if !strings.HasPrefix(value, `"`) {
+		value = unescapeInflux(value)
+	}
+	if value == "" {
+		return "", "", errors.New("empty value")
+	}
+	return unescapeInflux(parts[0]), value, nil
+}
+
+// splitUnescaped splits s on sep, that is not escaped with backslash and is not inside double-quoted string.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeInflux removes backslashes escaping commas, spaces, equal signs, quotes and backslashes.
+func unescapeInflux(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`, ="\`, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
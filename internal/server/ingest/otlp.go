@@ -0,0 +1,473 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// Kinds of OTLP metric data.
+const (
+	OTLPUnsupported = iota
+	OTLPGauge
+	OTLPSum
+)
+
+// OTLP aggregation temporality of sums.
+const (
+	OTLPTemporalityUnspecified = 0
+	OTLPTemporalityDelta       = 1
+	OTLPTemporalityCumulative  = 2
+)
+
+// otlpNoRecordedValue is data point flag of points without value, e.g. when series disappeared.
+const otlpNoRecordedValue = 1
+
+// OTLPRequest is a decoded OTLP ExportMetricsServiceRequest.
+type OTLPRequest struct {
+	Resources []OTLPResource
+}
+
+// OTLPResource is a set of metrics of single resource, e.g. service instance.
+type OTLPResource struct {
+	Attributes map[string]string
+	Metrics    []OTLPMetric
+}
+
+// OTLPMetric is a single metric with its data points. Points of unsupported kinds are only counted.
+type OTLPMetric struct {
+	Name        string
+	Kind        int
+	Temporality int
+	Monotonic   bool
+	Points      []OTLPPoint
+}
+
+// OTLPPoint is a single number data point, Time is in nanoseconds.
+type OTLPPoint struct {
+	Attributes map[string]string
+	Time       uint64
+	Value      float64
+	Flags      uint32
+}
+
+// ParseOTLPProtobuf decodes protobuf ExportMetricsServiceRequest.
+// Attributes with array, key-value list and bytes values are skipped.
+func ParseOTLPProtobuf(data []byte) (OTLPRequest, error) {
+	var req OTLPRequest
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		resource, err := parseOTLPResourceMetrics(value)
+		if err != nil {
+			return err
+		}
+		req.Resources = append(req.Resources, resource)
+		return nil
+	})
+	if err != nil {
+		return OTLPRequest{}, fmt.Errorf("invalid OTLP request: %w", err)
+	}
+	return req, nil
+}
+
+func parseOTLPResourceMetrics(data []byte) (OTLPResource, error) {
+	resource := OTLPResource{Attributes: make(map[string]string)}
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			return forEachField(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					return parseOTLPKeyValue(value, resource.Attributes)
+				}
+				return nil
+			})
+		case 2:
+			return forEachField(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num != 2 || typ != protowire.BytesType {
+					return nil
+				}
+				metric, err := parseOTLPMetric(value)
+				if err != nil {
+					return err
+				}
+				resource.Metrics = append(resource.Metrics, metric)
+				return nil
+			})
+		}
+		return nil
+	})
+	return resource, err
+}
+
+func parseOTLPMetric(data []byte) (OTLPMetric, error) {
+	var metric OTLPMetric
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			metric.Name = string(value)
+		case 5, 7:
+			metric.Kind = OTLPGauge
+			if num == 7 {
+				metric.Kind = OTLPSum
+			}
+			return forEachField(value, func(num protowire.Number, typ protowire.Type, value []byte, number uint64) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					point, err := parseOTLPNumberPoint(value)
+					if err != nil {
+						return err
+					}
+					metric.Points = append(metric.Points, point)
+				case num == 2 && typ == protowire.VarintType:
+					metric.Temporality = int(number)
+				case num == 3 && typ == protowire.VarintType:
+					metric.Monotonic = number != 0
+				}
+				return nil
+			})
+		case 9, 10, 11:
+			// Histogram, exponential histogram and summary points are not converted.
+			metric.Kind = OTLPUnsupported
+			return forEachField(value, func(num protowire.Number, typ protowire.Type, _ []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					metric.Points = append(metric.Points, OTLPPoint{})
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return metric, err
+}
+
+func parseOTLPNumberPoint(data []byte) (OTLPPoint, error) {
+	point := OTLPPoint{Attributes: make(map[string]string)}
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, number uint64) error {
+		switch {
+		case num == 7 && typ == protowire.BytesType:
+			return parseOTLPKeyValue(value, point.Attributes)
+		case num == 3 && typ == protowire.Fixed64Type:
+			point.Time = number
+		case num == 4 && typ == protowire.Fixed64Type:
+			point.Value = math.Float64frombits(number)
+		case num == 6 && typ == protowire.Fixed64Type:
+			point.Value = float64(int64(number))
+		case num == 8 && typ == protowire.VarintType:
+			point.Flags = uint32(number)
+		}
+		return nil
+	})
+	return point, err
+}
+
+// parseOTLPKeyValue decodes KeyValue into attributes, values of unsupported types are skipped.
+func parseOTLPKeyValue(data []byte, attributes map[string]string) error {
+	var key, value string
+	found := false
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, raw []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			key = string(raw)
+		case num == 2 && typ == protowire.BytesType:
+			return forEachField(raw, func(num protowire.Number, typ protowire.Type, raw []byte, number uint64) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					value, found = string(raw), true
+				case num == 2 && typ == protowire.VarintType:
+					value, found = strconv.FormatBool(number != 0), true
+				case num == 3 && typ == protowire.VarintType:
+					value, found = strconv.FormatInt(int64(number), 10), true
+				case num == 4 && typ == protowire.Fixed64Type:
+					value, found = strconv.FormatFloat(math.Float64frombits(number), 'g', -1, 64), true
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err == nil && found {
+		attributes[key] = value
+	}
+	return err
+}
+
+// otlpJSONRequest is ExportMetricsServiceRequest in OTLP JSON encoding.
+type otlpJSONRequest struct {
+	ResourceMetrics []struct {
+		Resource struct {
+			Attributes []otlpJSONKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeMetrics []struct {
+			Metrics []otlpJSONMetric `json:"metrics"`
+		} `json:"scopeMetrics"`
+	} `json:"resourceMetrics"`
+}
+
+type otlpJSONMetric struct {
+	Name  string `json:"name"`
+	Gauge *struct {
+		DataPoints []otlpJSONPoint `json:"dataPoints"`
+	} `json:"gauge"`
+	Sum *struct {
+		DataPoints             []otlpJSONPoint `json:"dataPoints"`
+		AggregationTemporality int             `json:"aggregationTemporality"`
+		IsMonotonic            bool            `json:"isMonotonic"`
+	} `json:"sum"`
+	Histogram            *otlpJSONUnsupported `json:"histogram"`
+	ExponentialHistogram *otlpJSONUnsupported `json:"exponentialHistogram"`
+	Summary              *otlpJSONUnsupported `json:"summary"`
+}
+
+type otlpJSONUnsupported struct {
+	DataPoints []json.RawMessage `json:"dataPoints"`
+}
+
+type otlpJSONPoint struct {
+	Attributes   []otlpJSONKeyValue `json:"attributes"`
+	TimeUnixNano otlpJSONInt        `json:"timeUnixNano"`
+	AsDouble     *float64           `json:"asDouble"`
+	AsInt        *otlpJSONInt       `json:"asInt"`
+	Flags        uint32             `json:"flags"`
+}
+
+type otlpJSONKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue *string      `json:"stringValue"`
+		BoolValue   *bool        `json:"boolValue"`
+		IntValue    *otlpJSONInt `json:"intValue"`
+		DoubleValue *float64     `json:"doubleValue"`
+	} `json:"value"`
+}
+
+// otlpJSONInt is 64-bit integer, that JSON encoding allows to be either number or string.
+type otlpJSONInt int64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *otlpJSONInt) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", data)
+	}
+	*i = otlpJSONInt(value)
+	return nil
+}
+
+// ParseOTLPJSON decodes ExportMetricsServiceRequest in OTLP JSON encoding.
+func ParseOTLPJSON(data []byte) (OTLPRequest, error) {
+	var decoded otlpJSONRequest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return OTLPRequest{}, fmt.Errorf("invalid OTLP request: %w", err)
+	}
+
+	var req OTLPRequest
+	for _, rm := range decoded.ResourceMetrics {
+		resource := OTLPResource{Attributes: otlpJSONAttributes(rm.Resource.Attributes)}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				metric := OTLPMetric{Name: m.Name}
+				var points []otlpJSONPoint
+				switch {
+				case m.Gauge != nil:
+					metric.Kind = OTLPGauge
+					points = m.Gauge.DataPoints
+				case m.Sum != nil:
+					metric.Kind = OTLPSum
+					metric.Temporality = m.Sum.AggregationTemporality
+					metric.Monotonic = m.Sum.IsMonotonic
+					points = m.Sum.DataPoints
+				default:
+					for _, unsupported := range []*otlpJSONUnsupported{m.Histogram, m.ExponentialHistogram, m.Summary} {
+						if unsupported != nil {
+							metric.Points = make([]OTLPPoint, len(unsupported.DataPoints))
+						}
+					}
+				}
+
+				for _, p := range points {
+					point := OTLPPoint{Attributes: otlpJSONAttributes(p.Attributes), Time: uint64(p.TimeUnixNano), Flags: p.Flags}
+					switch {
+					case p.AsDouble != nil:
+						point.Value = *p.AsDouble
+					case p.AsInt != nil:
+						point.Value = float64(*p.AsInt)
+					}
+					metric.Points = append(metric.Points, point)
+				}
+				resource.Metrics = append(resource.Metrics, metric)
+			}
+		}
+		req.Resources = append(req.Resources, resource)
+	}
+	return req, nil
+}
+
+func otlpJSONAttributes(keyValues []otlpJSONKeyValue) map[string]string {
+	attributes := make(map[string]string, len(keyValues))
+	for _, kv := range keyValues {
+		switch {
+		case kv.Value.StringValue != nil:
+			attributes[kv.Key] = *kv.Value.StringValue
+		case kv.Value.BoolValue != nil:
+			attributes[kv.Key] = strconv.FormatBool(*kv.Value.BoolValue)
+		case kv.Value.IntValue != nil:
+			attributes[kv.Key] = strconv.FormatInt(int64(*kv.Value.IntValue), 10)
+		case kv.Value.DoubleValue != nil:
+			attributes[kv.Key] = strconv.FormatFloat(*kv.Value.DoubleValue, 'g', -1, 64)
+		}
+	}
+	return attributes
+}
+
+// OTLPMetrics maps data points of request to metrics. Resource and point attributes become labels,
+// names are sanitized ("service.name" -> "service_name"), point attributes win on conflict.
+// Gauges and non-monotonic cumulative sums are gauges, monotonic sums are counters: delta sums are converted
+// to whole increments by increments, cumulative sums are converted to increments by counters, returned commit
+// must be called after metrics are stored. Points that can't be stored are counted in rejected and described in message.
+func OTLPMetrics(req OTLPRequest, counters *CumulativeConverter, increments *DeltaAccumulator) (metrics []models.Metric, commit func(), rejected int64, message string) {
+	type cumulativePoint struct {
+		metric models.Metric
+		value  float64
+		time   uint64
+	}
+	var cumulative []cumulativePoint
+	var deltaPoints []DeltaPoint
+	var deltaIndexes []int // indexes of metrics of deltaPoints
+	var reasons []string
+
+	reject := func(count int, reason string) {
+		rejected += int64(count)
+		for _, r := range reasons {
+			if r == reason {
+				return
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+
+	for _, resource := range req.Resources {
+		for _, m := range resource.Metrics {
+			if m.Name == "" {
+				reject(len(m.Points), "metric without name")
+				continue
+			}
+
+			mType := constants.Gauge
+			switch {
+			case m.Kind == OTLPGauge:
+			case m.Kind == OTLPSum && m.Monotonic && (m.Temporality == OTLPTemporalityCumulative || m.Temporality == OTLPTemporalityDelta):
+				mType = constants.Counter
+			case m.Kind == OTLPSum && !m.Monotonic && m.Temporality == OTLPTemporalityCumulative:
+			default:
+				reject(len(m.Points), fmt.Sprintf("metric %q has unsupported data type", m.Name))
+				continue
+			}
+
+			for _, point := range m.Points {
+				if point.Flags&otlpNoRecordedValue != 0 {
+					continue
+				}
+				if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+					reject(1, fmt.Sprintf("metric %q has non-finite value", m.Name))
+					continue
+				}
+
+				metric := models.Metric{ID: m.Name, MType: mType, Labels: otlpLabels(resource.Attributes, point.Attributes)}
+				switch {
+				case mType == constants.Gauge:
+					value := point.Value
+					metric.Value = &value
+				case m.Temporality == OTLPTemporalityDelta:
+					deltaPoints = append(deltaPoints, DeltaPoint{Key: metric.Key(), Value: point.Value})
+					deltaIndexes = append(deltaIndexes, len(metrics))
+				default:
+					cumulative = append(cumulative, cumulativePoint{metric: metric, value: point.Value, time: point.Time})
+					continue
+				}
+				metrics = append(metrics, metric)
+			}
+		}
+	}
+
+	wholeDeltas, commitIncrements := increments.Increments(deltaPoints)
+	for i, index := range deltaIndexes {
+		delta := wholeDeltas[i]
+		metrics[index].Delta = &delta
+	}
+
+	sort.SliceStable(cumulative, func(i, j int) bool { return cumulative[i].time < cumulative[j].time })
+	points := make([]CumulativePoint, len(cumulative))
+	for i, point := range cumulative {
+		points[i] = CumulativePoint{Key: point.metric.Key(), Value: point.value}
+	}
+	deltas, commitCounters := counters.Deltas(points)
+	for i, point := range cumulative {
+		delta := deltas[i]
+		point.metric.Delta = &delta
+		metrics = append(metrics, point.metric)
+	}
+
+	commit = func() {
+		commitIncrements()
+		commitCounters()
+	}
+	return metrics, commit, rejected, strings.Join(reasons, "; ")
+}
+
+// otlpLabels merges resource and point attributes into labels with valid names.
+func otlpLabels(resource, point map[string]string) map[string]string {
+	labels := make(map[string]string, len(resource)+len(point))
+	for _, attributes := range []map[string]string{resource, point} {
+		for name, value := range attributes {
+			name = sanitizeLabelName(name)
+			if value == "" || !models.ValidLabelName(name) {
+				continue
+			}
+			labels[name] = value
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// sanitizeLabelName replaces characters not allowed in label names with '_'.
+func sanitizeLabelName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
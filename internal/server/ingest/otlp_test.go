@@ -0,0 +1,181 @@
+package ingest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func encodeKeyValue(key, value string) []byte {
+	var anyValue []byte
+	anyValue = protowire.AppendTag(anyValue, 1, protowire.BytesType)
+	anyValue = protowire.AppendString(anyValue, value)
+
+	var kv []byte
+	kv = protowire.AppendTag(kv, 1, protowire.BytesType)
+	kv = protowire.AppendString(kv, key)
+	return appendMessage(kv, 2, anyValue)
+}
+
+func encodeNumberPoint(time uint64, value float64, attributes ...[]byte) []byte {
+	var point []byte
+	for _, attribute := range attributes {
+		point = appendMessage(point, 7, attribute)
+	}
+	point = protowire.AppendTag(point, 3, protowire.Fixed64Type)
+	point = protowire.AppendFixed64(point, time)
+	point = protowire.AppendTag(point, 4, protowire.Fixed64Type)
+	return protowire.AppendFixed64(point, math.Float64bits(value))
+}
+
+func TestParseOTLPProtobuf(t *testing.T) {
+	var sum []byte
+	sum = appendMessage(sum, 1, encodeNumberPoint(2, 15, encodeKeyValue("route", "/api")))
+	sum = appendMessage(sum, 1, encodeNumberPoint(1, 10, encodeKeyValue("route", "/api")))
+	sum = protowire.AppendTag(sum, 2, protowire.VarintType)
+	sum = protowire.AppendVarint(sum, OTLPTemporalityCumulative)
+	sum = protowire.AppendTag(sum, 3, protowire.VarintType)
+	sum = protowire.AppendVarint(sum, 1)
+
+	var metric []byte
+	metric = protowire.AppendTag(metric, 1, protowire.BytesType)
+	metric = protowire.AppendString(metric, "http.requests")
+	metric = appendMessage(metric, 7, sum)
+
+	var histogram []byte
+	histogram = protowire.AppendTag(histogram, 1, protowire.BytesType)
+	histogram = protowire.AppendString(histogram, "http.duration")
+	histogram = appendMessage(histogram, 9, appendMessage(nil, 1, nil))
+
+	var scope []byte
+	scope = appendMessage(scope, 2, metric)
+	scope = appendMessage(scope, 2, histogram)
+
+	var resourceMetrics []byte
+	resourceMetrics = appendMessage(resourceMetrics, 1, appendMessage(nil, 1, encodeKeyValue("service.name", "api")))
+	resourceMetrics = appendMessage(resourceMetrics, 2, scope)
+
+	req, err := ParseOTLPProtobuf(appendMessage(nil, 1, resourceMetrics))
+	require.NoError(t, err)
+
+	require.Len(t, req.Resources, 1)
+	assert.Equal(t, map[string]string{"service.name": "api"}, req.Resources[0].Attributes)
+	require.Len(t, req.Resources[0].Metrics, 2)
+
+	requests := req.Resources[0].Metrics[0]
+	assert.Equal(t, "http.requests", requests.Name)
+	assert.Equal(t, OTLPSum, requests.Kind)
+	assert.Equal(t, OTLPTemporalityCumulative, requests.Temporality)
+	assert.True(t, requests.Monotonic)
+	assert.Equal(t, []OTLPPoint{
+		{Attributes: map[string]string{"route": "/api"}, Time: 2, Value: 15},
+		{Attributes: map[string]string{"route": "/api"}, Time: 1, Value: 10},
+	}, requests.Points)
+
+	assert.Equal(t, OTLPUnsupported, req.Resources[0].Metrics[1].Kind)
+	assert.Len(t, req.Resources[0].Metrics[1].Points, 1)
+
+	_, err = ParseOTLPProtobuf([]byte{0x0a, 0xff})
+	assert.Error(t, err)
+}
+
+func TestParseOTLPJSON(t *testing.T) {
+	req, err := ParseOTLPJSON([]byte(`{"resourceMetrics":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+		"scopeMetrics":[{"metrics":[
+			{"name":"queue.size","gauge":{"dataPoints":[{"timeUnixNano":"5","asInt":"3","attributes":[{"key":"shard","value":{"intValue":2}}]}]}},
+			{"name":"latency","summary":{"dataPoints":[{},{}]}}
+		]}]
+	}]}`))
+	require.NoError(t, err)
+
+	require.Len(t, req.Resources, 1)
+	assert.Equal(t, map[string]string{"service.name": "api"}, req.Resources[0].Attributes)
+	assert.Equal(t, []OTLPMetric{
+		{Name: "queue.size", Kind: OTLPGauge, Points: []OTLPPoint{{Attributes: map[string]string{"shard": "2"}, Time: 5, Value: 3}}},
+		{Name: "latency", Kind: OTLPUnsupported, Points: make([]OTLPPoint, 2)},
+	}, req.Resources[0].Metrics)
+
+	_, err = ParseOTLPJSON([]byte(`{"resourceMetrics":`))
+	assert.Error(t, err)
+}
+
+func TestOTLPMetrics(t *testing.T) {
+	converter := NewCumulativeConverter()
+	resource := map[string]string{"service.name": "api"}
+	labels := map[string]string{"service_name": "api", "route": "/api"}
+
+	req := OTLPRequest{Resources: []OTLPResource{{
+		Attributes: resource,
+		Metrics: []OTLPMetric{
+			{Name: "requests", Kind: OTLPSum, Temporality: OTLPTemporalityCumulative, Monotonic: true, Points: []OTLPPoint{
+				{Attributes: map[string]string{"route": "/api"}, Time: 2, Value: 15},
+				{Attributes: map[string]string{"route": "/api"}, Time: 1, Value: 10},
+			}},
+			{Name: "errors", Kind: OTLPSum, Temporality: OTLPTemporalityDelta, Monotonic: true, Points: []OTLPPoint{{Value: 2}}},
+			{Name: "connections", Kind: OTLPSum, Temporality: OTLPTemporalityCumulative, Points: []OTLPPoint{{Value: 4}}},
+			{Name: "queue", Kind: OTLPGauge, Points: []OTLPPoint{{Value: 1.5}, {Value: math.NaN()}, {Flags: otlpNoRecordedValue}}},
+			{Name: "latency", Kind: OTLPUnsupported, Points: make([]OTLPPoint, 2)},
+		},
+	}}}
+
+	metrics, commit, rejected, message := OTLPMetrics(req, converter, NewDeltaAccumulator())
+	commit()
+
+	errors, connections, queue := int64(2), 4.0, 1.5
+	first, second := int64(0), int64(5)
+	resourceLabels := map[string]string{"service_name": "api"}
+	assert.Equal(t, []models.Metric{
+		{ID: "errors", MType: constants.Counter, Delta: &errors, Labels: resourceLabels},
+		{ID: "connections", MType: constants.Gauge, Value: &connections, Labels: resourceLabels},
+		{ID: "queue", MType: constants.Gauge, Value: &queue, Labels: resourceLabels},
+		{ID: "requests", MType: constants.Counter, Delta: &first, Labels: labels},
+		{ID: "requests", MType: constants.Counter, Delta: &second, Labels: labels},
+	}, metrics)
+	assert.Equal(t, int64(3), rejected)
+	assert.Contains(t, message, `"queue" has non-finite value`)
+	assert.Contains(t, message, `"latency" has unsupported data type`)
+
+	// Next cumulative value is converted to increment since the committed one.
+	metrics, _, _, _ = OTLPMetrics(OTLPRequest{Resources: []OTLPResource{{
+		Attributes: resource,
+		Metrics: []OTLPMetric{{Name: "requests", Kind: OTLPSum, Temporality: OTLPTemporalityCumulative, Monotonic: true, Points: []OTLPPoint{
+			{Attributes: map[string]string{"route": "/api"}, Time: 3, Value: 21},
+		}}},
+	}}}, converter, NewDeltaAccumulator())
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(6), *metrics[0].Delta)
+}
+
+func TestOTLPMetricsFractionalDeltas(t *testing.T) {
+	increments := NewDeltaAccumulator()
+	req := OTLPRequest{Resources: []OTLPResource{{
+		Metrics: []OTLPMetric{{Name: "bytes", Kind: OTLPSum, Temporality: OTLPTemporalityDelta, Monotonic: true, Points: []OTLPPoint{
+			{Time: 1, Value: 0.5},
+			{Time: 2, Value: 0.25},
+		}}},
+	}}}
+
+	metrics, commit, _, _ := OTLPMetrics(req, NewCumulativeConverter(), increments)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, int64(0), *metrics[0].Delta)
+	assert.Equal(t, int64(0), *metrics[1].Delta)
+	commit()
+
+	// Remainder of committed request is added to next increment.
+	metrics, _, _, _ = OTLPMetrics(req, NewCumulativeConverter(), increments)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, int64(1), *metrics[0].Delta)
+	assert.Equal(t, int64(0), *metrics[1].Delta)
+}
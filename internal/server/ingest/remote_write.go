@@ -0,0 +1,262 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// Metric types of Prometheus metadata.
+const (
+	promTypeUnknown   = 0
+	promTypeCounter   = 1
+	promTypeGauge     = 2
+	promTypeHistogram = 3
+	promTypeSummary   = 5
+)
+
+const nameLabel = "__name__"
+
+// WriteRequest is a decoded Prometheus remote-write request.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+	Metadata   map[string]int // metric family name -> Prometheus metric type
+}
+
+// TimeSeries is a series of samples with the same labels.
+type TimeSeries struct {
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// Sample is a single value of series, Timestamp is in milliseconds.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// ParseWriteRequest decodes protobuf WriteRequest from uncompressed body.
+// Exemplars and native histograms are skipped.
+func ParseWriteRequest(data []byte) (WriteRequest, error) {
+	req := WriteRequest{Metadata: make(map[string]int)}
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			series, err := parseTimeSeries(value)
+			if err != nil {
+				return err
+			}
+			req.Timeseries = append(req.Timeseries, series)
+		case num == 3 && typ == protowire.BytesType:
+			name, promType, err := parseMetadata(value)
+			if err != nil {
+				return err
+			}
+			req.Metadata[name] = promType
+		}
+		return nil
+	})
+	if err != nil {
+		return WriteRequest{}, fmt.Errorf("invalid write request: %w", err)
+	}
+	return req, nil
+}
+
+func parseTimeSeries(data []byte) (TimeSeries, error) {
+	series := TimeSeries{Labels: make(map[string]string)}
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var name, labelValue string
+			err := forEachField(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if typ != protowire.BytesType {
+					return nil
+				}
+				switch num {
+				case 1:
+					name = string(value)
+				case 2:
+					labelValue = string(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			series.Labels[name] = labelValue
+		case num == 2 && typ == protowire.BytesType:
+			var sample Sample
+			err := forEachField(value, func(num protowire.Number, typ protowire.Type, _ []byte, number uint64) error {
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					sample.Value = math.Float64frombits(number)
+				case num == 2 && typ == protowire.VarintType:
+					sample.Timestamp = int64(number)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			series.Samples = append(series.Samples, sample)
+		}
+		return nil
+	})
+	return series, err
+}
+
+func parseMetadata(data []byte) (string, int, error) {
+	var name string
+	var promType int
+
+	err := forEachField(data, func(num protowire.Number, typ protowire.Type, value []byte, number uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			promType = int(number)
+		case num == 2 && typ == protowire.BytesType:
+			name = string(value)
+		}
+		return nil
+	})
+	return name, promType, err
+}
+
+// forEachField calls fn for every field of protobuf message.
+// Value of bytes field is passed in value, varint and fixed values are passed in number.
+func forEachField(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, number uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		var number uint64
+		switch typ {
+		case protowire.VarintType:
+			number, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed64Type:
+			number, n = protowire.ConsumeFixed64(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(data)
+			number = uint64(v)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, value, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoteWriteMetrics maps series of request to metrics. Counters are converted to increments by counters,
+// returned commit must be called after metrics are stored. NaN (including staleness markers) and infinite samples are skipped.
+func RemoteWriteMetrics(req WriteRequest, counters *CumulativeConverter) ([]models.Metric, func(), error) {
+	var metrics []models.Metric
+	var points []CumulativePoint
+	var counterIdx []int
+
+	for _, series := range req.Timeseries {
+		name := series.Labels[nameLabel]
+		if name == "" {
+			return nil, nil, errors.New("series without metric name")
+		}
+
+		labels := make(map[string]string, len(series.Labels))
+		for labelName, value := range series.Labels {
+			if value == "" || strings.HasPrefix(labelName, "__") {
+				continue
+			}
+			if !models.ValidLabelName(labelName) {
+				return nil, nil, fmt.Errorf("invalid label name %q", labelName)
+			}
+			labels[labelName] = value
+		}
+		if len(labels) == 0 {
+			labels = nil
+		}
+
+		samples := append([]Sample(nil), series.Samples...)
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+
+		mType := seriesType(name, req.Metadata)
+		for _, sample := range samples {
+			if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+				continue
+			}
+
+			metric := models.Metric{ID: name, MType: mType, Labels: labels}
+			if mType == constants.Counter {
+				counterIdx = append(counterIdx, len(metrics))
+				points = append(points, CumulativePoint{Key: metric.Key(), Value: sample.Value})
+			} else {
+				value := sample.Value
+				metric.Value = &value
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+
+	deltas, commit := counters.Deltas(points)
+	for i, idx := range counterIdx {
+		delta := deltas[i]
+		metrics[idx].Delta = &delta
+	}
+	return metrics, commit, nil
+}
+
+// seriesType decides if series is stored as counter or gauge. Metadata is used when client sends it,
+// otherwise series with conventional suffixes of counters, histogram buckets and counts are counters.
+// Sums are stored as gauges, because they may be fractional.
+func seriesType(name string, metadata map[string]int) string {
+	promType, found := metadata[name]
+	suffix := ""
+	if !found {
+		for _, s := range []string{"_bucket", "_count", "_sum"} {
+			if t, ok := metadata[strings.TrimSuffix(name, s)]; ok && strings.HasSuffix(name, s) {
+				promType, found, suffix = t, true, s
+				break
+			}
+		}
+	}
+
+	if !found || promType == promTypeUnknown {
+		for _, s := range []string{"_total", "_bucket", "_count"} {
+			if strings.HasSuffix(name, s) {
+				return constants.Counter
+			}
+		}
+		return constants.Gauge
+	}
+
+	switch promType {
+	case promTypeCounter:
+		return constants.Counter
+	case promTypeHistogram, promTypeSummary:
+		if suffix == "_bucket" || suffix == "_count" {
+			return constants.Counter
+		}
+		return constants.Gauge
+	default:
+		return constants.Gauge
+	}
+}
@@ -0,0 +1,132 @@
+package ingest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// encodeWriteRequest builds protobuf WriteRequest the same way Prometheus does.
+func encodeWriteRequest(req WriteRequest) []byte {
+	var data []byte
+	for _, series := range req.Timeseries {
+		var seriesData []byte
+		for name, value := range series.Labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, value)
+
+			seriesData = protowire.AppendTag(seriesData, 1, protowire.BytesType)
+			seriesData = protowire.AppendBytes(seriesData, label)
+		}
+		for _, sample := range series.Samples {
+			var sampleData []byte
+			sampleData = protowire.AppendTag(sampleData, 1, protowire.Fixed64Type)
+			sampleData = protowire.AppendFixed64(sampleData, math.Float64bits(sample.Value))
+			sampleData = protowire.AppendTag(sampleData, 2, protowire.VarintType)
+			sampleData = protowire.AppendVarint(sampleData, uint64(sample.Timestamp))
+
+			seriesData = protowire.AppendTag(seriesData, 2, protowire.BytesType)
+			seriesData = protowire.AppendBytes(seriesData, sampleData)
+		}
+		data = protowire.AppendTag(data, 1, protowire.BytesType)
+		data = protowire.AppendBytes(data, seriesData)
+	}
+	for name, promType := range req.Metadata {
+		var metadata []byte
+		metadata = protowire.AppendTag(metadata, 1, protowire.VarintType)
+		metadata = protowire.AppendVarint(metadata, uint64(promType))
+		metadata = protowire.AppendTag(metadata, 2, protowire.BytesType)
+		metadata = protowire.AppendString(metadata, name)
+
+		data = protowire.AppendTag(data, 3, protowire.BytesType)
+		data = protowire.AppendBytes(data, metadata)
+	}
+	return data
+}
+
+func TestParseWriteRequest(t *testing.T) {
+	expected := WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels:  map[string]string{"__name__": "up", "job": "api"},
+				Samples: []Sample{{Value: 1, Timestamp: 1000}, {Value: 0, Timestamp: 2000}},
+			},
+		},
+		Metadata: map[string]int{"up": promTypeGauge},
+	}
+
+	parsed, err := ParseWriteRequest(encodeWriteRequest(expected))
+	require.NoError(t, err)
+	assert.Equal(t, expected, parsed)
+
+	_, err = ParseWriteRequest([]byte{0x0a, 0xff})
+	assert.Error(t, err)
+}
+
+func TestRemoteWriteMetrics(t *testing.T) {
+	counters := NewCumulativeConverter()
+
+	req := WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels:  map[string]string{"__name__": "http_requests_total", "job": "api", "empty": ""},
+				Samples: []Sample{{Value: 15, Timestamp: 2000}, {Value: 10, Timestamp: 1000}},
+			},
+			{
+				Labels:  map[string]string{"__name__": "temperature"},
+				Samples: []Sample{{Value: 21.5, Timestamp: 1000}, {Value: math.NaN(), Timestamp: 2000}},
+			},
+			{
+				Labels:  map[string]string{"__name__": "rpc_duration_seconds_count"},
+				Samples: []Sample{{Value: 3, Timestamp: 1000}},
+			},
+			{
+				Labels:  map[string]string{"__name__": "rpc_duration_seconds_sum"},
+				Samples: []Sample{{Value: 0.75, Timestamp: 1000}},
+			},
+		},
+		Metadata: map[string]int{"rpc_duration_seconds": promTypeSummary},
+	}
+
+	metrics, commit, err := RemoteWriteMetrics(req, counters)
+	require.NoError(t, err)
+	commit()
+
+	delta := func(v int64) *int64 { return &v }
+	value := func(v float64) *float64 { return &v }
+	labels := map[string]string{"job": "api"}
+	assert.Equal(t, []models.Metric{
+		{ID: "http_requests_total", MType: constants.Counter, Delta: delta(0), Labels: labels},
+		{ID: "http_requests_total", MType: constants.Counter, Delta: delta(5), Labels: labels},
+		{ID: "temperature", MType: constants.Gauge, Value: value(21.5)},
+		{ID: "rpc_duration_seconds_count", MType: constants.Counter, Delta: delta(0)},
+		{ID: "rpc_duration_seconds_sum", MType: constants.Gauge, Value: value(0.75)},
+	}, metrics)
+
+	t.Run("Next request continues from last value", func(t *testing.T) {
+		metrics, _, err := RemoteWriteMetrics(WriteRequest{Timeseries: []TimeSeries{{
+			Labels:  map[string]string{"__name__": "http_requests_total", "job": "api"},
+			Samples: []Sample{{Value: 18, Timestamp: 3000}},
+		}}}, counters)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		assert.Equal(t, int64(3), *metrics[0].Delta)
+	})
+
+	t.Run("Series without name", func(t *testing.T) {
+		_, _, err := RemoteWriteMetrics(WriteRequest{Timeseries: []TimeSeries{{
+			Labels:  map[string]string{"job": "api"},
+			Samples: []Sample{{Value: 1}},
+		}}}, counters)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/VOTONO/go-metrics/internal/config"
+)
+
+// Types of receivers.
+const (
+	TypeWebhook = "webhook"
+	TypeFile    = "file"
+	TypeExec    = "exec"
+)
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultRetries    = 3
+	defaultRetryPause = time.Second
+)
+
+// Receiver describes single notification channel.
+type Receiver struct {
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	Severities     []string        `json:"severities,omitempty"`      // empty list routes all severities
+	RepeatInterval config.Duration `json:"repeat_interval,omitempty"` // zero disables repeating of firing alerts
+	SendResolved   *bool           `json:"send_resolved,omitempty"`   // true by default
+
+	// Webhook settings.
+	URL        string          `json:"url,omitempty"`
+	Secret     string          `json:"secret,omitempty"`
+	Retries    *int            `json:"retries,omitempty"`
+	RetryPause config.Duration `json:"retry_pause,omitempty"`
+	Timeout    config.Duration `json:"timeout,omitempty"`
+
+	// File settings.
+	Path string `json:"path,omitempty"`
+
+	// Exec settings.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// ReceiversFile is the structure of notification channels configuration file.
+type ReceiversFile struct {
+	Receivers []Receiver `json:"receivers"`
+}
+
+// LoadReceivers reads and validates receivers from JSON file.
+func LoadReceivers(path string) ([]Receiver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file ReceiversFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode receivers file: %w", err)
+	}
+
+	for _, receiver := range file.Receivers {
+		if err := receiver.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return file.Receivers, nil
+}
+
+// Validate checks that receiver has all settings required by its type.
+func (r Receiver) Validate() error {
+	if r.Name == "" {
+		return errors.New("receiver name is empty")
+	}
+	switch r.Type {
+	case TypeWebhook:
+		if r.URL == "" {
+			return fmt.Errorf("receiver %s: url is empty", r.Name)
+		}
+	case TypeFile:
+		if r.Path == "" {
+			return fmt.Errorf("receiver %s: path is empty", r.Name)
+		}
+	case TypeExec:
+		if r.Command == "" {
+			return fmt.Errorf("receiver %s: command is empty", r.Name)
+		}
+	default:
+		return fmt.Errorf("receiver %s: unknown type %q", r.Name, r.Type)
+	}
+	return nil
+}
+
+// Sink creates sink described by receiver.
+func (r Receiver) Sink() (Sink, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(r.Timeout)
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch r.Type {
+	case TypeWebhook:
+		retries := defaultRetries
+		if r.Retries != nil {
+			retries = *r.Retries
+		}
+		retryPause := time.Duration(r.RetryPause)
+		if retryPause <= 0 {
+			retryPause = defaultRetryPause
+		}
+		return NewWebhookSink(r.URL, r.Secret, retries, retryPause, timeout), nil
+	case TypeFile:
+		return NewFileSink(r.Path), nil
+	default:
+		return NewExecSink(r.Command, r.Args, timeout), nil
+	}
+}
@@ -0,0 +1,47 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ExecSink runs external command and writes notification as JSON to its stdin.
+type ExecSink struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+func NewExecSink(command string, args []string, timeout time.Duration) *ExecSink {
+	return &ExecSink{
+		command: command,
+		args:    args,
+		timeout: timeout,
+	}
+}
+
+// Send runs command and waits until it exits. Non-zero exit code is treated as error.
+func (s *ExecSink) Send(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command, s.args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command %s failed: %w: %s", s.command, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
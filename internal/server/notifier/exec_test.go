@@ -0,0 +1,44 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecSink_Send(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	out := filepath.Join(t.TempDir(), "stdin.json")
+	sink := NewExecSink("sh", []string{"-c", `cat > "$0"`, out}, time.Second)
+
+	require.NoError(t, sink.Send(context.Background(), testNotification))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+
+	var received Notification
+	require.NoError(t, json.Unmarshal(data, &received))
+	assert.Equal(t, testNotification.Alert.Rule, received.Alert.Rule)
+}
+
+func TestExecSink_Failure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	sink := NewExecSink("sh", []string{"-c", "echo broken >&2; exit 3"}, time.Second)
+
+	err := sink.Send(context.Background(), testNotification)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken")
+}
@@ -0,0 +1,39 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+	sink := NewFileSink(path)
+
+	resolved := testNotification
+	resolved.Status = StatusResolved
+
+	require.NoError(t, sink.Send(context.Background(), testNotification))
+	require.NoError(t, sink.Send(context.Background(), resolved))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var statuses []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var n Notification
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &n))
+		statuses = append(statuses, n.Status)
+	}
+	require.NoError(t, scanner.Err())
+
+	assert.Equal(t, []string{StatusFiring, StatusResolved}, statuses)
+}
@@ -0,0 +1,157 @@
+// Package notifier delivers alerts produced by alerting engine to external channels.
+package notifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/server/alerting"
+)
+
+// Statuses of notification.
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Notification is a payload delivered to channels.
+type Notification struct {
+	Receiver  string         `json:"receiver"`
+	Status    string         `json:"status"`
+	Alert     alerting.Alert `json:"alert"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Sink sends single notification to external system.
+type Sink interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+// channel routes alerts to sink and remembers what was already sent.
+type channel struct {
+	name           string
+	sink           Sink
+	severities     map[string]struct{}
+	repeatInterval time.Duration
+	sendResolved   bool
+	sent           map[string]time.Time // rule name -> time of last firing notification
+}
+
+// accepts checks if channel is subscribed to alert severity. Empty list matches all severities.
+func (c *channel) accepts(alert alerting.Alert) bool {
+	if len(c.severities) == 0 {
+		return true
+	}
+	_, ok := c.severities[alert.Severity]
+	return ok
+}
+
+// Dispatcher implements alerting.Notifier and fans alerts out to channels.
+type Dispatcher struct {
+	mu       sync.Mutex
+	logger   *zap.SugaredLogger
+	channels []*channel
+	now      func() time.Time
+}
+
+// NewDispatcher creates dispatcher for configured receivers.
+func NewDispatcher(receivers []Receiver, logger *zap.SugaredLogger) (*Dispatcher, error) {
+	channels := make([]*channel, 0, len(receivers))
+	for _, receiver := range receivers {
+		sink, err := receiver.Sink()
+		if err != nil {
+			return nil, err
+		}
+
+		severities := make(map[string]struct{}, len(receiver.Severities))
+		for _, severity := range receiver.Severities {
+			severities[severity] = struct{}{}
+		}
+
+		channels = append(channels, &channel{
+			name:           receiver.Name,
+			sink:           sink,
+			severities:     severities,
+			repeatInterval: time.Duration(receiver.RepeatInterval),
+			sendResolved:   receiver.SendResolved == nil || *receiver.SendResolved,
+			sent:           make(map[string]time.Time),
+		})
+	}
+
+	return &Dispatcher{
+		logger:   logger,
+		channels: channels,
+		now:      time.Now,
+	}, nil
+}
+
+// Notify sends alerts to every channel concurrently and waits until all of them are done.
+func (d *Dispatcher) Notify(ctx context.Context, alerts []alerting.Alert) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+
+	var wg sync.WaitGroup
+	for _, ch := range d.channels {
+		wg.Add(1)
+		go func(ch *channel) {
+			defer wg.Done()
+			d.notifyChannel(ctx, ch, alerts, now)
+		}(ch)
+	}
+	wg.Wait()
+}
+
+// notifyChannel sends to channel only alerts which are new, due for repeat or resolved.
+func (d *Dispatcher) notifyChannel(ctx context.Context, ch *channel, alerts []alerting.Alert, now time.Time) {
+	for _, alert := range alerts {
+		if !ch.accepts(alert) {
+			continue
+		}
+
+		lastSent, wasSent := ch.sent[alert.Rule]
+
+		var status string
+		switch alert.State {
+		case alerting.StateFiring:
+			if wasSent && (ch.repeatInterval <= 0 || now.Sub(lastSent) < ch.repeatInterval) {
+				continue
+			}
+			status = StatusFiring
+		case alerting.StateResolved:
+			if !wasSent {
+				continue
+			}
+			if !ch.sendResolved {
+				delete(ch.sent, alert.Rule)
+				continue
+			}
+			status = StatusResolved
+		default:
+			continue
+		}
+
+		notification := Notification{
+			Receiver:  ch.name,
+			Status:    status,
+			Alert:     alert,
+			Timestamp: now,
+		}
+		if err := ch.sink.Send(ctx, notification); err != nil {
+			// Not marked as sent, so it will be retried after next evaluation.
+			d.logger.Errorw("failed to send notification", "receiver", ch.name, "rule", alert.Rule, "error", err.Error())
+			continue
+		}
+
+		if status == StatusFiring {
+			ch.sent[alert.Rule] = now
+		} else {
+			delete(ch.sent, alert.Rule)
+		}
+		d.logger.Infow("notification sent", "receiver", ch.name, "rule", alert.Rule, "status", status)
+	}
+}
@@ -0,0 +1,150 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/server/alerting"
+)
+
+type fakeSink struct {
+	mu            sync.Mutex
+	notifications []Notification
+	err           error
+}
+
+func (s *fakeSink) Send(_ context.Context, notification Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.notifications = append(s.notifications, notification)
+	return nil
+}
+
+func (s *fakeSink) statuses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]string, 0, len(s.notifications))
+	for _, n := range s.notifications {
+		statuses = append(statuses, n.Alert.Rule+":"+n.Status)
+	}
+	return statuses
+}
+
+func newTestDispatcher(t *testing.T, channels ...*channel) (*Dispatcher, *time.Time) {
+	t.Helper()
+
+	now := time.Now()
+	d := &Dispatcher{
+		logger:   zaptest.NewLogger(t).Sugar(),
+		channels: channels,
+		now:      func() time.Time { return now },
+	}
+	return d, &now
+}
+
+func newTestChannel(sink Sink, repeat time.Duration, sendResolved bool, severities ...string) *channel {
+	set := make(map[string]struct{})
+	for _, s := range severities {
+		set[s] = struct{}{}
+	}
+	return &channel{
+		name:           "test",
+		sink:           sink,
+		severities:     set,
+		repeatInterval: repeat,
+		sendResolved:   sendResolved,
+		sent:           make(map[string]time.Time),
+	}
+}
+
+func TestDispatcher_Deduplication(t *testing.T) {
+	sink := &fakeSink{}
+	d, now := newTestDispatcher(t, newTestChannel(sink, time.Hour, true))
+
+	firing := []alerting.Alert{{Rule: "r1", State: alerting.StateFiring}}
+	resolved := []alerting.Alert{{Rule: "r1", State: alerting.StateResolved}}
+
+	d.Notify(context.Background(), firing)
+	d.Notify(context.Background(), firing)
+	*now = now.Add(30 * time.Minute)
+	d.Notify(context.Background(), firing)
+	assert.Equal(t, []string{"r1:firing"}, sink.statuses(), "firing alert must be sent once within repeat interval")
+
+	*now = now.Add(31 * time.Minute)
+	d.Notify(context.Background(), firing)
+	assert.Equal(t, []string{"r1:firing", "r1:firing"}, sink.statuses(), "firing alert must be repeated after interval")
+
+	d.Notify(context.Background(), resolved)
+	d.Notify(context.Background(), resolved)
+	assert.Equal(t, []string{"r1:firing", "r1:firing", "r1:resolved"}, sink.statuses(), "resolved alert must be sent once")
+}
+
+func TestDispatcher_WithoutRepeatAndResolved(t *testing.T) {
+	sink := &fakeSink{}
+	d, now := newTestDispatcher(t, newTestChannel(sink, 0, false))
+
+	d.Notify(context.Background(), []alerting.Alert{{Rule: "r1", State: alerting.StateFiring}})
+	*now = now.Add(24 * time.Hour)
+	d.Notify(context.Background(), []alerting.Alert{{Rule: "r1", State: alerting.StateFiring}})
+	d.Notify(context.Background(), []alerting.Alert{{Rule: "r1", State: alerting.StateResolved}})
+	d.Notify(context.Background(), []alerting.Alert{{Rule: "r1", State: alerting.StateFiring}})
+
+	assert.Equal(t, []string{"r1:firing", "r1:firing"}, sink.statuses())
+}
+
+func TestDispatcher_SeverityRouting(t *testing.T) {
+	critical := &fakeSink{}
+	all := &fakeSink{}
+	d, _ := newTestDispatcher(t,
+		newTestChannel(critical, 0, true, "critical"),
+		newTestChannel(all, 0, true),
+	)
+
+	d.Notify(context.Background(), []alerting.Alert{
+		{Rule: "disk", Severity: "critical", State: alerting.StateFiring},
+		{Rule: "heap", Severity: "warning", State: alerting.StateFiring},
+	})
+
+	assert.Equal(t, []string{"disk:firing"}, critical.statuses())
+	assert.ElementsMatch(t, []string{"disk:firing", "heap:firing"}, all.statuses())
+}
+
+func TestDispatcher_RetryAfterFailure(t *testing.T) {
+	sink := &fakeSink{err: errors.New("unavailable")}
+	d, _ := newTestDispatcher(t, newTestChannel(sink, time.Hour, true))
+
+	d.Notify(context.Background(), []alerting.Alert{{Rule: "r1", State: alerting.StateFiring}})
+	assert.Empty(t, sink.statuses())
+
+	sink.err = nil
+	d.Notify(context.Background(), []alerting.Alert{{Rule: "r1", State: alerting.StateFiring}})
+	assert.Equal(t, []string{"r1:firing"}, sink.statuses())
+}
+
+func TestNewDispatcher(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+
+	d, err := NewDispatcher([]Receiver{
+		{Name: "hook", Type: TypeWebhook, URL: "http://localhost"},
+		{Name: "log", Type: TypeFile, Path: "/tmp/alerts.jsonl", Severities: []string{"critical"}},
+	}, logger)
+	require.NoError(t, err)
+	require.Len(t, d.channels, 2)
+	assert.True(t, d.channels[0].sendResolved)
+	assert.Contains(t, d.channels[1].severities, "critical")
+
+	_, err = NewDispatcher([]Receiver{{Name: "broken", Type: TypeExec}}, logger)
+	assert.Error(t, err)
+}
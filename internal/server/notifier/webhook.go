@@ -0,0 +1,87 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/constants"
+)
+
+// errPermanent marks webhook responses that should not be retried.
+var errPermanent = errors.New("permanent webhook error")
+
+// WebhookSink posts notifications as JSON to URL.
+// If secret is set, body is signed the same way as metrics requests, with HMAC-SHA256 in HashSHA256 header.
+type WebhookSink struct {
+	client     *http.Client
+	url        string
+	secret     string
+	retries    int
+	retryPause time.Duration
+}
+
+func NewWebhookSink(url string, secret string, retries int, retryPause time.Duration, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{
+		client:     &http.Client{Timeout: timeout},
+		url:        url,
+		secret:     secret,
+		retries:    retries,
+		retryPause: retryPause,
+	}
+}
+
+// Send posts notification and retries on network errors and 5xx or 429 responses.
+func (s *WebhookSink) Send(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	retryPause := s.retryPause
+	for i := 0; ; i++ {
+		err = s.post(ctx, body)
+		if err == nil || errors.Is(err, errPermanent) || i >= s.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryPause):
+		}
+		retryPause *= 2
+	}
+}
+
+func (s *WebhookSink) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s", errPermanent, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set(constants.HashSHA256, auth.Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook failed with status code %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: status code %d", errPermanent, resp.StatusCode)
+	}
+}
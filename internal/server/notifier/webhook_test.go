@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/server/alerting"
+)
+
+const testSecret = "webhook_secret"
+
+var testNotification = Notification{
+	Receiver: "oncall",
+	Status:   StatusFiring,
+	Alert:    alerting.Alert{Rule: "HighHeap", MetricID: "HeapAlloc", Severity: "critical", State: alerting.StateFiring, Value: 42},
+}
+
+func TestWebhookSink_Send(t *testing.T) {
+	var received Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, auth.Sign(testSecret, body), r.Header.Get(constants.HashSHA256))
+		assert.NoError(t, json.Unmarshal(body, &received))
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, testSecret, 0, time.Millisecond, time.Second)
+	require.NoError(t, sink.Send(context.Background(), testNotification))
+
+	assert.Equal(t, testNotification.Alert.Rule, received.Alert.Rule)
+	assert.Equal(t, StatusFiring, received.Status)
+}
+
+func TestWebhookSink_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		retries   int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"Succeeds after server errors", []int{500, 503, 200}, 3, 3, false},
+		{"Gives up after retries", []int{500, 500, 500}, 2, 3, true},
+		{"Does not retry client errors", []int{400, 200}, 3, 1, true},
+		{"Retries too many requests", []int{429, 200}, 3, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := calls.Add(1)
+				assert.Empty(t, r.Header.Get(constants.HashSHA256), "unsigned webhook must not send hash")
+				w.WriteHeader(tt.statuses[call-1])
+			}))
+			defer server.Close()
+
+			sink := NewWebhookSink(server.URL, "", tt.retries, time.Millisecond, time.Second)
+			err := sink.Send(context.Background(), testNotification)
+
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
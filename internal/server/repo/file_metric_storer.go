@@ -69,7 +69,7 @@ func (s *FileMetricStorerImpl) StoreSlice(_ context.Context, newMetrics []models
 	return nil
 }
 
-func (s *FileMetricStorerImpl) Get(_ context.Context, id string) (models.Metric, bool, error) {
+func (s *FileMetricStorerImpl) Get(_ context.Context, key string) (models.Metric, bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -79,7 +79,7 @@ func (s *FileMetricStorerImpl) Get(_ context.Context, id string) (models.Metric,
 		return models.Metric{}, false, err
 	}
 
-	metric, found := metrics[id]
+	metric, found := metrics[key]
 
 	return metric, found, nil
 }
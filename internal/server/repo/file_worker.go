@@ -2,8 +2,6 @@ package repo
 
 import (
 	"context"
-	"encoding/json"
-	"os"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,41 +10,42 @@ import (
 	"github.com/VOTONO/go-metrics/internal/models"
 )
 
-// ReadFile reads all metrics from file.
+// ReadFile reads all metrics from file. If file is damaged, metrics are read from its newest valid backup.
 func ReadFile(file string, logger *zap.SugaredLogger) (map[string]models.Metric, error) {
-	f, err := os.Open(file)
+	restored, err := restoreSnapshot(file, logger)
 	if err != nil {
-		if os.IsNotExist(err) {
-			logger.Infow("file not found", "file", file)
-			return make(map[string]models.Metric), nil
-		}
-		logError(logger, "failed to open file", file, err)
 		return nil, err
 	}
-	defer f.Close()
+	return restored.Metrics, nil
+}
 
-	var metrics map[string]models.Metric
-	decoder := json.NewDecoder(f)
-	if err := decoder.Decode(&metrics); err != nil {
-		logError(logger, "failed to decode file", file, err)
-		return nil, err
+// rekeyMetrics migrates snapshots written before identity included type and labels, keys are recomputed from metrics.
+func rekeyMetrics(metrics map[string]models.Metric, file string, logger *zap.SugaredLogger) map[string]models.Metric {
+	rekeyed := make(map[string]models.Metric, len(metrics))
+	migrated := 0
+	for key, metric := range metrics {
+		if key != metric.Key() {
+			migrated++
+		}
+		rekeyed[metric.Key()] = metric
 	}
 
-	return metrics, nil
+	if migrated > 0 {
+		logger.Infow("migrated metric keys", "file", file, "count", migrated)
+	}
+	return rekeyed
 }
 
-// RewriteFile all metrics in file
+// RewriteFile atomically replaces file with snapshot of all metrics.
 func RewriteFile(file string, metrics map[string]models.Metric, logger *zap.SugaredLogger) error {
-	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-	if err != nil {
-		logError(logger, "failed to open file", file, err)
-		return err
-	}
-	defer f.Close()
+	return WriteSnapshot(file, metrics, 1, logger)
+}
 
-	encoder := json.NewEncoder(f)
-	if err := encoder.Encode(metrics); err != nil {
-		logError(logger, "failed to encode metrics", file, err)
+// WriteSnapshot atomically replaces file with snapshot of all metrics and keeps keep newest snapshots,
+// older ones are renamed to file.1, file.2 and so on.
+func WriteSnapshot(file string, metrics map[string]models.Metric, keep int, logger *zap.SugaredLogger) error {
+	if err := writeSnapshot(file, snapshot{Metrics: metrics}, keep); err != nil {
+		logError(logger, "failed to write snapshot", file, err)
 		return err
 	}
 
@@ -76,8 +75,8 @@ func AddToFile(file string, metric models.Metric, logger *zap.SugaredLogger) (mo
 	return updated, nil
 }
 
-// StartWriting starts goroutine that's periodically saves metrics to file.
-func StartWriting(ctx context.Context, storer MetricStorer, logger *zap.SugaredLogger, storeInterval int, filePath string) {
+// StartWriting starts goroutine that's periodically saves metrics to file, keeping keep newest snapshots.
+func StartWriting(ctx context.Context, storer MetricStorer, logger *zap.SugaredLogger, storeInterval int, filePath string, keep int) {
 	if storeInterval <= 0 || filePath == "" {
 		logger.Infow("skip periodical writing to file", "file", filePath, "interval", storeInterval)
 		return
@@ -95,7 +94,7 @@ func StartWriting(ctx context.Context, storer MetricStorer, logger *zap.SugaredL
 					logError(logger, "failed get metrics from storage before writing to file", filePath, err)
 					return
 				}
-				rewriteErr := RewriteFile(filePath, metrics, logger)
+				rewriteErr := WriteSnapshot(filePath, metrics, keep, logger)
 				if rewriteErr != nil {
 					logger.Errorw("failed to rewrite metrics", filePath, "error", rewriteErr.Error())
 				}
@@ -106,7 +105,7 @@ func StartWriting(ctx context.Context, storer MetricStorer, logger *zap.SugaredL
 					logError(logger, "failed get metrics from storage before writing to file", filePath, err)
 					return
 				}
-				rewriteErr := RewriteFile(filePath, metrics, logger)
+				rewriteErr := WriteSnapshot(filePath, metrics, keep, logger)
 				if rewriteErr != nil {
 					logger.Errorw("failed to rewrite metrics", filePath, "error", rewriteErr.Error())
 				}
@@ -40,12 +40,12 @@ func TestRead(t *testing.T) {
 	logger := zaptest.NewLogger(t).Sugar()
 
 	metrics := map[string]models.Metric{
-		"metric1": {
+		"gauge:metric1": {
 			ID:    "metric1",
 			MType: constants.Gauge,
 			Value: func(f float64) *float64 { return &f }(123.45),
 		},
-		"metric2": {
+		"counter:metric2": {
 			ID:    "metric2",
 			MType: constants.Counter,
 			Delta: func(i int64) *int64 { return &i }(678),
@@ -60,6 +60,18 @@ func TestRead(t *testing.T) {
 	tmpFile := createTempFile(t, content)
 	defer os.Remove(tmpFile.Name())
 
+	// Snapshot written before keys included metric type.
+	legacyContent, err := json.Marshal(map[string]models.Metric{
+		"metric1": metrics["gauge:metric1"],
+		"metric2": metrics["counter:metric2"],
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal metrics: %v", err)
+	}
+
+	legacyFile := createTempFile(t, legacyContent)
+	defer os.Remove(legacyFile.Name())
+
 	tests := []struct {
 		name    string
 		args    string
@@ -72,6 +84,12 @@ func TestRead(t *testing.T) {
 			want:    metrics,
 			wantErr: false,
 		},
+		{
+			name:    "ReadFile migrates legacy keys",
+			args:    legacyFile.Name(),
+			want:    metrics,
+			wantErr: false,
+		},
 		{
 			name:    "ReadFile non-existent file",
 			args:    "non_existent_file.json",
@@ -104,12 +122,12 @@ func TestWrite(t *testing.T) {
 	logger := zaptest.NewLogger(t).Sugar()
 
 	metrics := map[string]models.Metric{
-		"metric1": {
+		"gauge:metric1": {
 			ID:    "metric1",
 			MType: constants.Gauge,
 			Value: func(f float64) *float64 { return &f }(123.45),
 		},
-		"metric2": {
+		"counter:metric2": {
 			ID:    "metric2",
 			MType: constants.Counter,
 			Delta: func(i int64) *int64 { return &i }(678),
@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// HistoryStorer is a MetricStorer that also keeps every accepted sample with server-side timestamp.
+type HistoryStorer interface {
+	MetricStorer
+	// History returns samples of metric recorded in [from, to] in chronological order.
+	// Samples are taken from the coarsest resolution that is not coarser than step.
+	History(ctx context.Context, key string, from, to time.Time, step time.Duration) ([]models.Sample, error)
+	// Rollup compacts complete buckets of history and drops samples older than retention.
+	Rollup(ctx context.Context, now time.Time, retention Retention) error
+}
+
+// RingHistoryStorer wraps MetricStorer and keeps last samples of every metric in memory.
+type RingHistoryStorer struct {
+	MetricStorer
+	mu       sync.RWMutex
+	capacity int
+	series   map[string]*series
+	now      func() time.Time
+}
+
+// series keeps history of single metric in all resolutions.
+type series struct {
+	raw         *sampleRing
+	minute      []models.Sample
+	hour        []models.Sample
+	minuteUntil time.Time // raw samples before this time are already rolled up into minute buckets
+	hourUntil   time.Time // minute buckets before this time are already rolled up into hour buckets
+}
+
+// NewRingHistoryStorer creates storer that keeps up to capacity raw samples per metric.
+func NewRingHistoryStorer(storer MetricStorer, capacity int) *RingHistoryStorer {
+	return &RingHistoryStorer{
+		MetricStorer: storer,
+		capacity:     capacity,
+		series:       make(map[string]*series),
+		now:          time.Now,
+	}
+}
+
+func (s *RingHistoryStorer) StoreSingle(ctx context.Context, metric models.Metric) (*models.Metric, error) {
+	stored, err := s.MetricStorer.StoreSingle(ctx, metric)
+	if err != nil {
+		return stored, err
+	}
+
+	s.record(s.now(), metric)
+	return stored, nil
+}
+
+func (s *RingHistoryStorer) StoreSlice(ctx context.Context, metrics []models.Metric) error {
+	if err := s.MetricStorer.StoreSlice(ctx, metrics); err != nil {
+		return err
+	}
+
+	s.record(s.now(), metrics...)
+	return nil
+}
+
+func (s *RingHistoryStorer) History(_ context.Context, key string, from, to time.Time, step time.Duration) ([]models.Sample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ser, found := s.series[key]
+	if !found {
+		return []models.Sample{}, nil
+	}
+
+	switch ResolutionForStep(step) {
+	case ResolutionHour:
+		return between(ser.hour, from, to), nil
+	case ResolutionMinute:
+		return between(ser.minute, from, to), nil
+	default:
+		return ser.raw.between(from, to), nil
+	}
+}
+
+func (s *RingHistoryStorer) Rollup(_ context.Context, now time.Time, retention Retention) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minuteBorder := now.Truncate(ResolutionMinute)
+	hourBorder := now.Truncate(ResolutionHour)
+
+	for _, ser := range s.series {
+		raw := excludeBorder(ser.raw.between(ser.minuteUntil, minuteBorder), minuteBorder)
+		ser.minute = append(ser.minute, rollupSamples(raw, ResolutionMinute)...)
+		ser.minuteUntil = minuteBorder
+
+		minutes := excludeBorder(between(ser.minute, ser.hourUntil, hourBorder), hourBorder)
+		ser.hour = append(ser.hour, rollupSamples(minutes, ResolutionHour)...)
+		ser.hourUntil = hourBorder
+
+		if retention.Raw > 0 {
+			ser.raw.dropBefore(now.Add(-retention.Raw))
+		}
+		if retention.Minute > 0 {
+			ser.minute = dropBefore(ser.minute, now.Add(-retention.Minute))
+		}
+		if retention.Hour > 0 {
+			ser.hour = dropBefore(ser.hour, now.Add(-retention.Hour))
+		}
+	}
+
+	return nil
+}
+
+func (s *RingHistoryStorer) record(now time.Time, metrics ...models.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, metric := range metrics {
+		if !hasHistory(metric) {
+			continue
+		}
+		ser, found := s.series[metric.Key()]
+		if !found {
+			ser = &series{raw: newSampleRing(s.capacity)}
+			s.series[metric.Key()] = ser
+		}
+		ser.raw.push(models.NewSample(metric, now))
+	}
+}
+
+// hasHistory reports whether samples of metric are recorded, distributions are kept only as current value.
+func hasHistory(metric models.Metric) bool {
+	return metric.MType == constants.Gauge || metric.MType == constants.Counter
+}
+
+// excludeBorder drops samples at border, because bucket starting at border is not complete yet.
+func excludeBorder(samples []models.Sample, border time.Time) []models.Sample {
+	for len(samples) > 0 && !samples[len(samples)-1].Timestamp.Before(border) {
+		samples = samples[:len(samples)-1]
+	}
+	return samples
+}
+
+// sampleRing is a fixed size circular buffer of samples, the oldest sample is overwritten first.
+type sampleRing struct {
+	samples []models.Sample
+	start   int
+	size    int
+}
+
+func newSampleRing(capacity int) *sampleRing {
+	return &sampleRing{samples: make([]models.Sample, capacity)}
+}
+
+func (r *sampleRing) push(sample models.Sample) {
+	if len(r.samples) == 0 {
+		return
+	}
+	if r.size < len(r.samples) {
+		r.samples[(r.start+r.size)%len(r.samples)] = sample
+		r.size++
+		return
+	}
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+func (r *sampleRing) between(from, to time.Time) []models.Sample {
+	result := make([]models.Sample, 0)
+	for i := 0; i < r.size; i++ {
+		sample := r.samples[(r.start+i)%len(r.samples)]
+		if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+			continue
+		}
+		result = append(result, sample)
+	}
+	return result
+}
+
+// dropBefore removes oldest samples recorded before border.
+func (r *sampleRing) dropBefore(border time.Time) {
+	for r.size > 0 && r.samples[r.start].Timestamp.Before(border) {
+		r.samples[r.start] = models.Sample{}
+		r.start = (r.start + 1) % len(r.samples)
+		r.size--
+	}
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func TestRingHistoryStorer(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := NewRingHistoryStorer(NewLocalMetricStorer(false, "", logger), 3)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	storer.now = func() time.Time { return clock }
+
+	for i := 1; i <= 5; i++ {
+		clock = start.Add(time.Duration(i) * time.Minute)
+		value := float64(i)
+		_, err := storer.StoreSingle(context.Background(), models.Metric{ID: "gauge", MType: constants.Gauge, Value: &value})
+		require.NoError(t, err)
+	}
+
+	delta := int64(7)
+	clock = start.Add(10 * time.Minute)
+	require.NoError(t, storer.StoreSlice(context.Background(), []models.Metric{{ID: "counter", MType: constants.Counter, Delta: &delta}}))
+
+	_, err := storer.StoreSingle(context.Background(), models.Metric{ID: "gauge", MType: constants.Gauge})
+	require.Error(t, err, "invalid metric must not be recorded")
+
+	t.Run("Keeps only last samples", func(t *testing.T) {
+		samples, err := storer.History(context.Background(), "gauge:gauge", start, start.Add(time.Hour), ResolutionRaw)
+		require.NoError(t, err)
+		require.Len(t, samples, 3)
+		for i, sample := range samples {
+			assert.Equal(t, float64(i+3), *sample.Value)
+			assert.Equal(t, start.Add(time.Duration(i+3)*time.Minute), sample.Timestamp)
+		}
+	})
+
+	t.Run("Filters by range", func(t *testing.T) {
+		samples, err := storer.History(context.Background(), "gauge:gauge", start.Add(4*time.Minute), start.Add(4*time.Minute), ResolutionRaw)
+		require.NoError(t, err)
+		require.Len(t, samples, 1)
+		assert.Equal(t, float64(4), *samples[0].Value)
+	})
+
+	t.Run("Records counter increments", func(t *testing.T) {
+		samples, err := storer.History(context.Background(), "counter:counter", start, start.Add(time.Hour), ResolutionRaw)
+		require.NoError(t, err)
+		require.Len(t, samples, 1)
+		assert.Equal(t, delta, *samples[0].Delta)
+	})
+
+	t.Run("Unknown metric", func(t *testing.T) {
+		samples, err := storer.History(context.Background(), "unknown:unknown", start, start.Add(time.Hour), ResolutionRaw)
+		require.NoError(t, err)
+		assert.Empty(t, samples)
+	})
+}
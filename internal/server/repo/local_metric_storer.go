@@ -66,11 +66,11 @@ func (s *LocalMetricStorerImpl) StoreSlice(_ context.Context, newMetrics []model
 	return nil
 }
 
-func (s *LocalMetricStorerImpl) Get(_ context.Context, id string) (models.Metric, bool, error) {
+func (s *LocalMetricStorerImpl) Get(_ context.Context, key string) (models.Metric, bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	metric, found := s.metrics[id]
+	metric, found := s.metrics[key]
 
 	return metric, found, nil
 }
@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/helpers"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo/logstore"
+)
+
+// LogMetricStorerImpl implementation of MetricStorer interface. Stores metrics as JSON values
+// of embedded logstore.Store keyed by models.Metric.Key, only keys are kept in memory.
+type LogMetricStorerImpl struct {
+	mu        sync.Mutex // serializes read-modify-write of counters and distributions
+	store     *logstore.Store
+	zapLogger *zap.SugaredLogger
+}
+
+// NewLogMetricStorer opens log store in dir.
+func NewLogMetricStorer(dir string, options logstore.Options, logger *zap.SugaredLogger) (*LogMetricStorerImpl, error) {
+	store, err := logstore.Open(dir, options, logger)
+	if err != nil {
+		logger.Errorw("failed to open log store", "dir", dir, "err", err.Error())
+		return nil, err
+	}
+	return &LogMetricStorerImpl{store: store, zapLogger: logger}, nil
+}
+
+func (s *LogMetricStorerImpl) StoreSingle(_ context.Context, newMetric models.Metric) (*models.Metric, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	valid := helpers.ValidateMetric(newMetric)
+	if !valid {
+		return &models.Metric{}, fmt.Errorf("invalide metric")
+	}
+
+	updated, err := s.write([]models.Metric{newMetric})
+	if err != nil {
+		return nil, err
+	}
+
+	updatedMetric := updated[newMetric.Key()]
+	helpers.LogMetric("new stored Metric", updatedMetric, s.zapLogger)
+	return &updatedMetric, nil
+}
+
+func (s *LogMetricStorerImpl) StoreSlice(_ context.Context, newMetrics []models.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.write(newMetrics)
+	return err
+}
+
+// write merges metrics with stored ones and writes changed metrics in one batch, caller must hold mu.
+func (s *LogMetricStorerImpl) write(metrics []models.Metric) (map[string]models.Metric, error) {
+	updated := make(map[string]models.Metric, len(metrics))
+	for _, metric := range metrics {
+		key := metric.Key()
+		if _, ok := updated[key]; !ok {
+			existing, found, err := s.get(key)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				updated[key] = existing
+			}
+		}
+		if _, err := helpers.UpdateMetricInMap(updated, metric, s.zapLogger); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]logstore.Entry, 0, len(updated))
+	for key, metric := range updated {
+		value, err := json.Marshal(metric)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, logstore.Entry{Key: key, Value: value})
+	}
+	if err := s.store.PutBatch(entries); err != nil {
+		s.zapLogger.Errorw("failed to write metrics", "count", len(entries), "err", err.Error())
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *LogMetricStorerImpl) get(key string) (models.Metric, bool, error) {
+	value, found, err := s.store.Get(key)
+	if err != nil || !found {
+		return models.Metric{}, false, err
+	}
+
+	var metric models.Metric
+	if err := json.Unmarshal(value, &metric); err != nil {
+		return models.Metric{}, false, err
+	}
+	return metric, true, nil
+}
+
+func (s *LogMetricStorerImpl) Get(_ context.Context, key string) (models.Metric, bool, error) {
+	return s.get(key)
+}
+
+func (s *LogMetricStorerImpl) All(_ context.Context) (map[string]models.Metric, error) {
+	metrics := make(map[string]models.Metric, s.store.Len())
+	err := s.store.ForEach(func(key string, value []byte) error {
+		var metric models.Metric
+		if err := json.Unmarshal(value, &metric); err != nil {
+			return err
+		}
+		metrics[key] = metric
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+func (s *LogMetricStorerImpl) Ping() error {
+	return nil
+}
+
+// Close closes log store.
+func (s *LogMetricStorerImpl) Close() error {
+	return s.store.Close()
+}
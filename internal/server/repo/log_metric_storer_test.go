@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo/logstore"
+)
+
+func TestLogMetricStorer(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t).Sugar()
+	dir := t.TempDir()
+
+	storer, err := NewLogMetricStorer(dir, logstore.DefaultOptions, logger)
+	require.NoError(t, err)
+	require.NoError(t, storer.StoreSlice(ctx, []models.Metric{counter("requests", 1), counter("requests", 2), gauge("temp", 1.5)}))
+	updated, err := storer.StoreSingle(ctx, counter("requests", 3))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *updated.Delta)
+
+	// Batch that can't be applied is not written.
+	assert.Error(t, storer.StoreSlice(ctx, []models.Metric{counter("requests", 10), {ID: "bad", MType: "bad"}}))
+	require.NoError(t, storer.Close())
+
+	storer, err = NewLogMetricStorer(dir, logstore.DefaultOptions, logger)
+	require.NoError(t, err)
+	defer storer.Close()
+
+	metrics, err := storer.All(ctx)
+	require.NoError(t, err)
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, int64(6), *metrics[counter("requests", 0).Key()].Delta)
+	metric, found, err := storer.Get(ctx, gauge("temp", 0).Key())
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 1.5, *metric.Value)
+}
@@ -0,0 +1,54 @@
+package logstore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheme of log store DSN.
+const Scheme = "logstore"
+
+// ParseDSN parses DSN of log store:
+//
+//	logstore://dir?max_segment_size=67108864&compact_interval=1m&compact_ratio=0.5&sync=true
+//
+// Options missing in DSN are taken from DefaultOptions. ok is false if DSN has other scheme.
+func ParseDSN(dsn string) (dir string, options Options, ok bool, err error) {
+	rest, ok := strings.CutPrefix(dsn, Scheme+"://")
+	if !ok {
+		return "", Options{}, false, nil
+	}
+	dir, query, _ := strings.Cut(rest, "?")
+	if dir == "" {
+		return "", Options{}, true, errors.New("logstore: DSN has no directory")
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", Options{}, true, fmt.Errorf("logstore: invalid DSN options: %w", err)
+	}
+	options = DefaultOptions
+	for name := range values {
+		value := values.Get(name)
+		switch name {
+		case "max_segment_size":
+			options.MaxSegmentSize, err = strconv.ParseInt(value, 10, 64)
+		case "compact_interval":
+			options.CompactInterval, err = time.ParseDuration(value)
+		case "compact_ratio":
+			options.CompactRatio, err = strconv.ParseFloat(value, 64)
+		case "sync":
+			options.Sync, err = strconv.ParseBool(value)
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return "", Options{}, true, fmt.Errorf("logstore: invalid DSN option %s: %w", name, err)
+		}
+	}
+	return dir, options, true, nil
+}
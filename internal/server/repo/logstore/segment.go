@@ -0,0 +1,145 @@
+package logstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Record is a header followed by key and value:
+//
+//	crc32c uint32 | key length uint32 | value length uint32 | key | value
+//
+// Numbers are little endian, checksum covers lengths, key and value.
+const (
+	headerSize   = 12
+	maxKeySize   = 64 << 10
+	maxValueSize = 64 << 20
+)
+
+const segmentExt = ".seg"
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord means record is incomplete or corrupt, it is the end of valid segment.
+var errTornRecord = errors.New("torn record")
+
+func encodeRecord(key string, value []byte) []byte {
+	buf := make([]byte, headerSize+len(key)+len(value))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(len(value)))
+	copy(buf[headerSize:], key)
+	copy(buf[headerSize+len(key):], value)
+	binary.LittleEndian.PutUint32(buf[0:], crc32.Checksum(buf[4:], crcTable))
+	return buf
+}
+
+// decodeRecord checks whole record in buf and returns its key and value.
+func decodeRecord(buf []byte) (string, []byte, error) {
+	if len(buf) < headerSize {
+		return "", nil, errTornRecord
+	}
+	keySize := binary.LittleEndian.Uint32(buf[4:])
+	valueSize := binary.LittleEndian.Uint32(buf[8:])
+	if int64(len(buf)) != headerSize+int64(keySize)+int64(valueSize) {
+		return "", nil, errTornRecord
+	}
+	if crc32.Checksum(buf[4:], crcTable) != binary.LittleEndian.Uint32(buf[0:]) {
+		return "", nil, errTornRecord
+	}
+	key := string(buf[headerSize : headerSize+keySize])
+	return key, buf[headerSize+keySize:], nil
+}
+
+// readRecord reads next record, io.EOF is returned at the end of segment.
+func readRecord(r io.Reader) (string, []byte, int, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", nil, 0, errTornRecord
+		}
+		return "", nil, 0, err
+	}
+
+	keySize := binary.LittleEndian.Uint32(header[4:])
+	valueSize := binary.LittleEndian.Uint32(header[8:])
+	if keySize > maxKeySize || valueSize > maxValueSize {
+		return "", nil, 0, errTornRecord
+	}
+	buf := make([]byte, headerSize+int(keySize)+int(valueSize))
+	copy(buf, header[:])
+	if _, err := io.ReadFull(r, buf[headerSize:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", nil, 0, errTornRecord
+		}
+		return "", nil, 0, err
+	}
+
+	key, value, err := decodeRecord(buf)
+	return key, value, len(buf), err
+}
+
+// segment is a data file. Only active segment of Store is appended, others are immutable until compaction.
+type segment struct {
+	id   uint64
+	file *os.File
+	size int64
+	dead int64 // bytes of records overwritten by newer ones
+}
+
+func segmentPath(dir string, id uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// segmentIDs returns ids of segments in dir in ascending order.
+func segmentIDs(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func openSegment(dir string, id uint64) (*segment, error) {
+	file, err := os.OpenFile(segmentPath(dir, id), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &segment{id: id, file: file, size: info.Size()}, nil
+}
+
+// syncDir makes creation, removal and renames of files in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,493 @@
+// Package logstore is an embedded log-structured key-value store.
+//
+// Values are appended to segment files in a directory, memory holds only index of keys with location
+// of their latest values. Active segment is rolled over when it reaches Options.MaxSegmentSize.
+// Compaction copies live values of older segments into one new segment and removes them, it runs in
+// background when overwritten values take Options.CompactRatio of data.
+package logstore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Options of Store.
+type Options struct {
+	MaxSegmentSize  int64         // active segment is rolled over at this size
+	CompactInterval time.Duration // interval of compaction check, 0 disables background compaction
+	CompactRatio    float64       // share of overwritten data that triggers compaction
+	Sync            bool          // fsync every write, otherwise write survives process crash but not power loss
+}
+
+// DefaultOptions of Store.
+var DefaultOptions = Options{
+	MaxSegmentSize:  64 << 20,
+	CompactInterval: time.Minute,
+	CompactRatio:    0.5,
+	Sync:            true,
+}
+
+// ErrClosed is returned by operations of closed Store.
+var ErrClosed = errors.New("logstore: store is closed")
+
+// location of value in segment.
+type location struct {
+	segment uint64
+	offset  int64
+	size    int64
+}
+
+// Entry is a key and value written by PutBatch.
+type Entry struct {
+	Key   string
+	Value []byte
+}
+
+// Store is a log-structured key-value store. It is safe for concurrent use.
+type Store struct {
+	mu          sync.RWMutex
+	compactMu   sync.Mutex // only one compaction runs at a time
+	dir         string
+	options     Options
+	logger      *zap.SugaredLogger
+	index       map[string]location
+	segments    map[uint64]*segment
+	active      *segment
+	closed      bool
+	stopChannel chan struct{}
+	waitGroup   sync.WaitGroup
+}
+
+// Open opens store in dir, creating dir if needed, and rebuilds index from segments.
+// Torn record at the end of segment, left by crash in the middle of write, is cut off.
+func Open(dir string, options Options, logger *zap.SugaredLogger) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	// Compaction output not renamed before crash.
+	if tmps, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt+".tmp")); err == nil {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}
+
+	s := &Store{
+		dir:         dir,
+		options:     options,
+		logger:      logger,
+		index:       make(map[string]location),
+		segments:    make(map[uint64]*segment),
+		stopChannel: make(chan struct{}),
+	}
+
+	ids, err := segmentIDs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if err := s.load(id); err != nil {
+			s.closeSegments()
+			return nil, err
+		}
+	}
+
+	var activeID uint64
+	if len(ids) > 0 {
+		activeID = ids[len(ids)-1]
+	}
+	if s.active = s.segments[activeID]; s.active == nil {
+		if s.active, err = s.createSegment(activeID); err != nil {
+			s.closeSegments()
+			return nil, err
+		}
+	}
+	logger.Infow("opened log store", "dir", dir, "segments", len(s.segments), "keys", len(s.index))
+
+	if options.CompactInterval > 0 {
+		s.waitGroup.Add(1)
+		go s.compactLoop()
+	}
+	return s, nil
+}
+
+// load reads segment and adds its records to index.
+func (s *Store) load(id uint64) error {
+	seg, err := openSegment(s.dir, id)
+	if err != nil {
+		return err
+	}
+	s.segments[id] = seg
+
+	reader := bufio.NewReader(io.NewSectionReader(seg.file, 0, seg.size))
+	var offset int64
+	for {
+		key, _, n, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.Is(err, errTornRecord) {
+			s.logger.Errorw("cut off torn record", "segment", seg.file.Name(), "offset", offset, "bytes", seg.size-offset)
+			if err := seg.file.Truncate(offset); err != nil {
+				return err
+			}
+			seg.size = offset
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.setLocation(key, location{segment: id, offset: offset, size: int64(n)})
+		offset += int64(n)
+	}
+}
+
+// setLocation points key to new location and accounts old value as dead.
+func (s *Store) setLocation(key string, loc location) {
+	if previous, ok := s.index[key]; ok {
+		if seg := s.segments[previous.segment]; seg != nil {
+			seg.dead += previous.size
+		}
+	}
+	s.index[key] = loc
+}
+
+func (s *Store) createSegment(id uint64) (*segment, error) {
+	seg, err := openSegment(s.dir, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := syncDir(s.dir); err != nil {
+		seg.file.Close()
+		return nil, err
+	}
+	s.segments[id] = seg
+	return seg, nil
+}
+
+// Get returns value of key, ok is false if key is not found.
+func (s *Store) Get(key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, false, ErrClosed
+	}
+	loc, ok := s.index[key]
+	if !ok {
+		return nil, false, nil
+	}
+	value, err := s.read(key, loc)
+	return value, err == nil, err
+}
+
+// read returns value at loc, caller must hold mu.
+func (s *Store) read(key string, loc location) ([]byte, error) {
+	buf := make([]byte, loc.size)
+	if _, err := s.segments[loc.segment].file.ReadAt(buf, loc.offset); err != nil {
+		return nil, err
+	}
+	storedKey, value, err := decodeRecord(buf)
+	if err != nil || storedKey != key {
+		return nil, fmt.Errorf("logstore: corrupt value of %q in segment %d at %d", key, loc.segment, loc.offset)
+	}
+	return value, nil
+}
+
+// Put sets value of key.
+func (s *Store) Put(key string, value []byte) error {
+	return s.PutBatch([]Entry{{Key: key, Value: value}})
+}
+
+// PutBatch sets values of keys with one write. Readers see either none or all of entries,
+// but crash in the middle of write may leave first entries only.
+func (s *Store) PutBatch(entries []Entry) error {
+	var buf []byte
+	sizes := make([]int64, len(entries))
+	for i, entry := range entries {
+		if len(entry.Key) > maxKeySize || len(entry.Value) > maxValueSize {
+			return fmt.Errorf("logstore: entry %q is too large", entry.Key)
+		}
+		record := encodeRecord(entry.Key, entry.Value)
+		sizes[i] = int64(len(record))
+		buf = append(buf, record...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.active.size > 0 && s.active.size+int64(len(buf)) > s.options.MaxSegmentSize {
+		if err := s.roll(s.active.id + 1); err != nil {
+			return err
+		}
+	}
+
+	seg := s.active
+	if _, err := seg.file.WriteAt(buf, seg.size); err != nil {
+		return s.rollback(seg, err)
+	}
+	if s.options.Sync {
+		if err := seg.file.Sync(); err != nil {
+			return s.rollback(seg, err)
+		}
+	}
+
+	offset := seg.size
+	for i, entry := range entries {
+		s.setLocation(entry.Key, location{segment: seg.id, offset: offset, size: sizes[i]})
+		offset += sizes[i]
+	}
+	seg.size = offset
+	return nil
+}
+
+// rollback cuts off partially written records, so records written later are not lost behind them.
+func (s *Store) rollback(seg *segment, cause error) error {
+	if err := seg.file.Truncate(seg.size); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// roll makes segment with id active, caller must hold mu.
+func (s *Store) roll(id uint64) error {
+	seg, err := s.createSegment(id)
+	if err != nil {
+		return err
+	}
+	s.active = seg
+	return nil
+}
+
+// ForEach calls f for every key and value in key order. Writes wait until ForEach returns.
+func (s *Store) ForEach(f func(key string, value []byte) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	keys := make([]string, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, err := s.read(key, s.index[key])
+		if err != nil {
+			return err
+		}
+		if err := f(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len returns number of keys.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.index)
+}
+
+// Stats describes data files of Store.
+type Stats struct {
+	Segments  int
+	Size      int64 // bytes of all segments
+	DeadSize  int64 // bytes of overwritten values
+	KeysCount int
+}
+
+// Stats returns current Stats.
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := Stats{Segments: len(s.segments), KeysCount: len(s.index)}
+	for _, seg := range s.segments {
+		stats.Size += seg.size
+		stats.DeadSize += seg.dead
+	}
+	return stats
+}
+
+// Compact rewrites live values of all segments into new one if overwritten values take
+// Options.CompactRatio of data. Writes go to new active segment meanwhile.
+func (s *Store) Compact() error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	var size, dead int64
+	for _, seg := range s.segments {
+		size += seg.size
+		dead += seg.dead
+	}
+	if dead == 0 || float64(dead) < float64(size)*s.options.CompactRatio {
+		s.mu.Unlock()
+		return nil
+	}
+
+	// Output gets id between compacted segments and new active one, so replay order is kept.
+	outputID := s.active.id + 1
+	if err := s.roll(s.active.id + 2); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	compacted := make(map[uint64]*segment, len(s.segments)-1)
+	for id, seg := range s.segments {
+		if id < outputID {
+			compacted[id] = seg
+		}
+	}
+	type liveRecord struct {
+		key string
+		loc location
+	}
+	var live []liveRecord
+	for key, loc := range s.index {
+		if _, ok := compacted[loc.segment]; ok {
+			live = append(live, liveRecord{key: key, loc: loc})
+		}
+	}
+	s.mu.Unlock()
+
+	// Compacted segments are immutable and removed only by this compaction, so they are read without lock.
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].loc.segment != live[j].loc.segment {
+			return live[i].loc.segment < live[j].loc.segment
+		}
+		return live[i].loc.offset < live[j].loc.offset
+	})
+	locations := make([]location, len(live))
+	var output *segment
+	if len(live) > 0 {
+		tmpPath := segmentPath(s.dir, outputID) + ".tmp"
+		tmp, err := os.Create(tmpPath)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmpPath)
+
+		writer := bufio.NewWriter(tmp)
+		var offset int64
+		for i, record := range live {
+			buf := make([]byte, record.loc.size)
+			if _, err := compacted[record.loc.segment].file.ReadAt(buf, record.loc.offset); err != nil {
+				tmp.Close()
+				return err
+			}
+			if _, err := writer.Write(buf); err != nil {
+				tmp.Close()
+				return err
+			}
+			locations[i] = location{segment: outputID, offset: offset, size: record.loc.size}
+			offset += record.loc.size
+		}
+		if err := writer.Flush(); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, segmentPath(s.dir, outputID)); err != nil {
+			return err
+		}
+		if output, err = openSegment(s.dir, outputID); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if output != nil {
+		s.segments[outputID] = output
+		for i, record := range live {
+			// Key written during compaction keeps its newer value.
+			if s.index[record.key] == record.loc {
+				s.index[record.key] = locations[i]
+			} else {
+				output.dead += record.loc.size
+			}
+		}
+	}
+	var removeErr error
+	for id, seg := range compacted {
+		delete(s.segments, id)
+		removeErr = errors.Join(removeErr, seg.file.Close(), os.Remove(seg.file.Name()))
+	}
+	removeErr = errors.Join(removeErr, syncDir(s.dir))
+	if removeErr != nil {
+		return removeErr
+	}
+
+	s.logger.Infow("compacted log store", "dir", s.dir, "segments", len(compacted), "keys", len(live), "reclaimed", dead)
+	return nil
+}
+
+func (s *Store) compactLoop() {
+	defer s.waitGroup.Done()
+
+	ticker := time.NewTicker(s.options.CompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopChannel:
+			return
+		case <-ticker.C:
+			if err := s.Compact(); err != nil && !errors.Is(err, ErrClosed) {
+				s.logger.Errorw("failed to compact log store", "dir", s.dir, "error", err.Error())
+			}
+		}
+	}
+}
+
+// Close stops background compaction and closes segments.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stopChannel)
+	s.mu.Unlock()
+
+	s.waitGroup.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeSegments()
+}
+
+func (s *Store) closeSegments() error {
+	var err error
+	for _, seg := range s.segments {
+		err = errors.Join(err, seg.file.Close())
+	}
+	return err
+}
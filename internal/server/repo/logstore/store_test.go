@@ -0,0 +1,185 @@
+package logstore
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func openTestStore(t *testing.T, dir string, options Options) *Store {
+	t.Helper()
+	store, err := Open(dir, options, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func requireValue(t *testing.T, store *Store, key, want string) {
+	t.Helper()
+	value, found, err := store.Get(key)
+	require.NoError(t, err)
+	require.True(t, found, "key %s", key)
+	assert.Equal(t, want, string(value), "key %s", key)
+}
+
+func TestStoreReopen(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{MaxSegmentSize: 64, CompactRatio: 0.5}
+
+	store := openTestStore(t, dir, options)
+	require.NoError(t, store.Put("a", []byte("1")))
+	require.NoError(t, store.PutBatch([]Entry{{Key: "b", Value: []byte("2")}, {Key: "a", Value: []byte("3")}}))
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.Put(fmt.Sprintf("key%d", i), []byte("value")))
+	}
+	assert.Greater(t, store.Stats().Segments, 1, "segments are rolled over")
+	require.NoError(t, store.Close())
+
+	_, _, err := store.Get("a")
+	assert.ErrorIs(t, err, ErrClosed)
+
+	store = openTestStore(t, dir, options)
+	requireValue(t, store, "a", "3")
+	requireValue(t, store, "b", "2")
+	requireValue(t, store, "key9", "value")
+	assert.Equal(t, 12, store.Len())
+	_, found, err := store.Get("missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	var keys []string
+	require.NoError(t, store.ForEach(func(key string, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, 12, len(keys))
+	assert.Equal(t, "a", keys[0])
+}
+
+func TestStoreTornTail(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestStore(t, dir, Options{MaxSegmentSize: 1 << 20})
+	require.NoError(t, store.Put("a", []byte("1")))
+	require.NoError(t, store.Put("a", []byte("2")))
+	require.NoError(t, store.Close())
+
+	ids, err := segmentIDs(dir)
+	require.NoError(t, err)
+	path := segmentPath(dir, ids[len(ids)-1])
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-1))
+
+	store = openTestStore(t, dir, Options{MaxSegmentSize: 1 << 20})
+	requireValue(t, store, "a", "1")
+
+	// Torn record is cut off, so new records are readable after reopen.
+	require.NoError(t, store.Put("a", []byte("3")))
+	require.NoError(t, store.Close())
+	store = openTestStore(t, dir, Options{MaxSegmentSize: 1 << 20})
+	requireValue(t, store, "a", "3")
+}
+
+func TestStoreCompact(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{MaxSegmentSize: 256, CompactRatio: 0.5}
+	store := openTestStore(t, dir, options)
+
+	for round := 0; round < 20; round++ {
+		for key := 0; key < 5; key++ {
+			require.NoError(t, store.Put(fmt.Sprintf("key%d", key), []byte(fmt.Sprintf("value%d", round))))
+		}
+	}
+	before := store.Stats()
+	require.NoError(t, store.Compact())
+	after := store.Stats()
+	assert.Less(t, after.Size, before.Size)
+	assert.Zero(t, after.DeadSize)
+	assert.Less(t, after.Segments, before.Segments)
+
+	require.NoError(t, store.Put("key0", []byte("new")))
+	require.NoError(t, store.Close())
+	store = openTestStore(t, dir, options)
+	requireValue(t, store, "key0", "new")
+	requireValue(t, store, "key4", "value19")
+	assert.Equal(t, 5, store.Len())
+}
+
+func TestStoreCompactConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	options := Options{MaxSegmentSize: 512, CompactRatio: 0.1, CompactInterval: time.Millisecond}
+	store := openTestStore(t, dir, options)
+
+	const writers, writes = 4, 200
+	var wg sync.WaitGroup
+	for writer := 0; writer < writers; writer++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				assert.NoError(t, store.Put(fmt.Sprintf("writer%d", writer), []byte(fmt.Sprint(i))))
+				_, _, err := store.Get(fmt.Sprintf("writer%d", writer))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, store.Compact())
+
+	for writer := 0; writer < writers; writer++ {
+		requireValue(t, store, fmt.Sprintf("writer%d", writer), fmt.Sprint(writes-1))
+	}
+	require.NoError(t, store.Close())
+	store = openTestStore(t, dir, options)
+	for writer := 0; writer < writers; writer++ {
+		requireValue(t, store, fmt.Sprintf("writer%d", writer), fmt.Sprint(writes-1))
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		dir     string
+		options Options
+		ok      bool
+		wantErr bool
+	}{
+		{name: "postgres", dsn: "postgres://user@localhost/metrics"},
+		{name: "defaults", dsn: "logstore:///var/lib/metrics", dir: "/var/lib/metrics", options: DefaultOptions, ok: true},
+		{
+			name: "options",
+			dsn:  "logstore://data?max_segment_size=1024&compact_interval=10s&compact_ratio=0.25&sync=false",
+			dir:  "data",
+			options: Options{
+				MaxSegmentSize:  1024,
+				CompactInterval: 10 * time.Second,
+				CompactRatio:    0.25,
+			},
+			ok: true,
+		},
+		{name: "no dir", dsn: "logstore://", ok: true, wantErr: true},
+		{name: "unknown option", dsn: "logstore://data?size=1", ok: true, wantErr: true},
+		{name: "bad option", dsn: "logstore://data?sync=maybe", ok: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, options, ok, err := ParseDSN(tt.dsn)
+			assert.Equal(t, tt.ok, ok)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.dir, dir)
+			assert.Equal(t, tt.options, options)
+		})
+	}
+}
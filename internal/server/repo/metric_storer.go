@@ -13,8 +13,8 @@ type MetricStorer interface {
 	StoreSingle(ctx context.Context, metric models.Metric) (*models.Metric, error)
 	// StoreSlice sores slice of metrics.
 	StoreSlice(ctx context.Context, metrics []models.Metric) error
-	// Get return metric by ID, if it exists.
-	Get(ctx context.Context, ID string) (models.Metric, bool, error)
-	// All returns all stored metrics.
+	// Get return metric by key (see models.Metric.Key), if it exists.
+	Get(ctx context.Context, key string) (models.Metric, bool, error)
+	// All returns all stored metrics by their keys.
 	All(ctx context.Context) (map[string]models.Metric, error)
 }
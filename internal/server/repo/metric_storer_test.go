@@ -44,6 +44,9 @@ func TestMetricStorers(t *testing.T) {
 			testStoreGetCounter(t, stor.storer)
 			testInvalidMetric(t, stor.storer)
 			testStoreSlice(t, stor.storer)
+			testStoreLabels(t, stor.storer)
+			testSameNameDifferentTypes(t, stor.storer)
+			testStoreDistributions(t, stor.storer)
 		})
 	}
 }
@@ -55,7 +58,7 @@ func testStoreGetGauge(t *testing.T, stor repo.MetricStorer) {
 		t.Fatalf("returned an unexpected error: %v", err)
 	}
 
-	storedMetric, exists, getErr := stor.Get(context.Background(), utils.ValidGaugeMetric.ID)
+	storedMetric, exists, getErr := stor.Get(context.Background(), utils.ValidGaugeMetric.Key())
 
 	if getErr != nil {
 		t.Fatalf("returned an unexpected error: %v", getErr)
@@ -76,7 +79,7 @@ func testStoreGetCounter(t *testing.T, stor repo.MetricStorer) {
 		t.Fatalf("returned an unexpected error: %v", err)
 	}
 
-	storedMetric, exists, getErr := stor.Get(context.Background(), utils.ValidCounterMetric.ID)
+	storedMetric, exists, getErr := stor.Get(context.Background(), utils.ValidCounterMetric.Key())
 
 	if getErr != nil {
 		t.Fatalf("returned an unexpected error: %v", getErr)
@@ -116,7 +119,7 @@ func testAll(t *testing.T, stor repo.MetricStorer) {
 	}
 
 	for _, metric := range metrics {
-		storedMetric, exists := allMetrics[metric.ID]
+		storedMetric, exists := allMetrics[metric.Key()]
 		if !exists {
 			t.Errorf("metric %s not found", metric.ID)
 			continue
@@ -159,7 +162,7 @@ func testStoreSlice(t *testing.T, stor repo.MetricStorer) {
 	}
 
 	for _, metric := range metrics {
-		storedMetric, exists, getErr := stor.Get(context.Background(), metric.ID)
+		storedMetric, exists, getErr := stor.Get(context.Background(), metric.Key())
 
 		if getErr != nil {
 			t.Fatalf("returned an unexpected error: %v", getErr)
@@ -175,6 +178,100 @@ func testStoreSlice(t *testing.T, stor repo.MetricStorer) {
 	}
 }
 
+func testStoreLabels(t *testing.T, stor repo.MetricStorer) {
+	metrics := []models.Metric{
+		{ID: "labeled", MType: constants.Counter, Delta: int64Pointer(1), Labels: map[string]string{"host": "a"}},
+		{ID: "labeled", MType: constants.Counter, Delta: int64Pointer(2), Labels: map[string]string{"host": "b"}},
+		{ID: "labeled", MType: constants.Counter, Delta: int64Pointer(3), Labels: map[string]string{"host": "a"}},
+	}
+
+	for _, metric := range metrics {
+		if _, err := stor.StoreSingle(context.Background(), metric); err != nil {
+			t.Fatalf("returned an unexpected error: %v", err)
+		}
+	}
+
+	expected := map[string]int64{
+		`counter:labeled{host="a"}`: 4,
+		`counter:labeled{host="b"}`: 2,
+	}
+	for key, delta := range expected {
+		storedMetric, exists, getErr := stor.Get(context.Background(), key)
+		if getErr != nil {
+			t.Fatalf("returned an unexpected error: %v", getErr)
+		}
+		if !exists {
+			t.Fatalf("expected metric %s not found", key)
+		}
+		if *storedMetric.Delta != delta || storedMetric.Key() != key {
+			t.Errorf("expected metric %s with delta %d, got %v", key, delta, storedMetric)
+		}
+	}
+
+	if _, exists, _ := stor.Get(context.Background(), "counter:labeled"); exists {
+		t.Errorf("expected metric without labels to be absent")
+	}
+}
+
+func testSameNameDifferentTypes(t *testing.T, stor repo.MetricStorer) {
+	gauge := models.Metric{ID: "shared", MType: constants.Gauge, Value: float64Pointer(1.5)}
+	counter := models.Metric{ID: "shared", MType: constants.Counter, Delta: int64Pointer(3)}
+
+	for _, metric := range []models.Metric{gauge, counter, counter} {
+		if _, err := stor.StoreSingle(context.Background(), metric); err != nil {
+			t.Fatalf("returned an unexpected error: %v", err)
+		}
+	}
+
+	storedGauge, exists, getErr := stor.Get(context.Background(), gauge.Key())
+	if getErr != nil || !exists || !compareMetrics(storedGauge, gauge) {
+		t.Errorf("expected gauge %v, got %v (exists %v, err %v)", gauge, storedGauge, exists, getErr)
+	}
+
+	storedCounter, exists, getErr := stor.Get(context.Background(), counter.Key())
+	if getErr != nil || !exists || *storedCounter.Delta != 6 {
+		t.Errorf("expected counter with delta 6, got %v (exists %v, err %v)", storedCounter, exists, getErr)
+	}
+}
+
+func testStoreDistributions(t *testing.T, stor repo.MetricStorer) {
+	var metrics []models.Metric
+	for _, value := range []string{"0.2", "0.7", "3"} {
+		for _, mType := range []string{constants.Histogram, constants.Summary} {
+			metric, err := models.NewMetric("latency", mType, value)
+			if err != nil {
+				t.Fatalf("returned an unexpected error: %v", err)
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+
+	if err := stor.StoreSlice(context.Background(), metrics[:4]); err != nil {
+		t.Fatalf("returned an unexpected error: %v", err)
+	}
+	for _, metric := range metrics[4:] {
+		if _, err := stor.StoreSingle(context.Background(), metric); err != nil {
+			t.Fatalf("returned an unexpected error: %v", err)
+		}
+	}
+
+	histogram, exists, getErr := stor.Get(context.Background(), "histogram:latency")
+	if getErr != nil || !exists {
+		t.Fatalf("expected histogram, got exists %v, err %v", exists, getErr)
+	}
+	if histogram.Histogram.Count != 3 || histogram.Histogram.Sum != 3.9 {
+		t.Errorf("expected histogram with 3 observations, got %+v", histogram.Histogram)
+	}
+
+	summary, exists, getErr := stor.Get(context.Background(), "summary:latency")
+	if getErr != nil || !exists {
+		t.Fatalf("expected summary, got exists %v, err %v", exists, getErr)
+	}
+	if summary.Summary.Count != 3 || summary.Summary.Max != 3 {
+		t.Errorf("expected summary with 3 observations, got %+v", summary.Summary)
+	}
+}
+
 func compareMetrics(a, b models.Metric) bool {
 	if a.ID != b.ID || a.MType != b.MType {
 		return false
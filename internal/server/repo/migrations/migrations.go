@@ -0,0 +1,280 @@
+// Package migrations applies versioned schema migrations of Postgres metric storage.
+//
+// Migrations are embedded SQL files named NNNN_name.up.sql and NNNN_name.down.sql, applied in version order.
+// Applied versions are recorded in schema_migrations table. Migrator holds Postgres advisory lock while it works,
+// so several server replicas starting at once apply every migration exactly once.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is a key of advisory lock taken by Migrator, it is shared by all servers of the same database.
+const lockID int64 = 0x676f6d6574726963 // "gometric"
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one version of schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // empty if migration can't be rolled back
+}
+
+// Status of migration in database, AppliedAt is zero for pending migrations.
+type Status struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Pending reports whether migration is not applied yet.
+func (s Status) Pending() bool {
+	return s.AppliedAt.IsZero()
+}
+
+// Load returns embedded migrations ordered by version.
+func Load() ([]Migration, error) {
+	return load(files, "sql")
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations of database.
+type Migrator struct {
+	db         *sql.DB
+	logger     *zap.SugaredLogger
+	migrations []Migration
+}
+
+// NewMigrator creates Migrator with embedded migrations.
+func NewMigrator(db *sql.DB, logger *zap.SugaredLogger) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, logger: logger, migrations: migrations}, nil
+}
+
+// Up applies all pending migrations and returns number of applied ones.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		m.warnUnknown(applied)
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration.Version, migration.Name, migration.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			m.logger.Infow("applied migration", "version", migration.Version, "name", migration.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back last steps applied migrations and returns number of rolled back ones.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	byVersion := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("migration %d is unknown, it was applied by newer server", version)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s can't be rolled back", migration.Version, migration.Name)
+			}
+			if err := m.apply(ctx, conn, migration.Version, migration.Name, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1;`, migration.Version); err != nil {
+				return err
+			}
+			m.logger.Infow("rolled back migration", "version", migration.Version, "name", migration.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status returns all known migrations with time they were applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		m.warnUnknown(applied)
+
+		statuses = make([]Status, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			statuses = append(statuses, Status{Migration: migration, AppliedAt: applied[migration.Version]})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// apply runs migration script and updates schema_migrations with record query in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, version int64, name, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", version, name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withLock runs f on connection holding migration advisory lock. Session lock is bound to connection,
+// so all statements of f must use conn.
+func (m *Migrator) withLock(ctx context.Context, f func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, lockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, lockID); err != nil {
+			m.logger.Errorw("failed to release migration lock", "error", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version BIGINT PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    );`); err != nil {
+		return err
+	}
+	return f(conn)
+}
+
+// warnUnknown logs applied migrations missing in this build, database was migrated by newer server.
+func (m *Migrator) warnUnknown(applied map[int64]time.Time) {
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			m.logger.Errorw("database has unknown migration applied", "version", version)
+		}
+	}
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,181 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "versions must be sequential")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down, "migration %d_%s must be reversible", migration.Version, migration.Name)
+	}
+}
+
+func TestLoadFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  []Migration
+		err   bool
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"sql/0010_second.up.sql":  {Data: []byte("B")},
+				"sql/0002_first.up.sql":   {Data: []byte("A")},
+				"sql/0002_first.down.sql": {Data: []byte("a")},
+			},
+			want: []Migration{
+				{Version: 2, Name: "first", Up: "A", Down: "a"},
+				{Version: 10, Name: "second", Up: "B"},
+			},
+		},
+		{name: "bad name", files: fstest.MapFS{"sql/first.up.sql": {Data: []byte("A")}}, err: true},
+		{name: "no up", files: fstest.MapFS{"sql/0001_first.down.sql": {Data: []byte("a")}}, err: true},
+		{
+			name: "two names",
+			files: fstest.MapFS{
+				"sql/0001_first.up.sql":   {Data: []byte("A")},
+				"sql/0001_other.up.sql":   {Data: []byte("B")},
+				"sql/0001_first.down.sql": {Data: []byte("a")},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.files, "sql")
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, migrations)
+		})
+	}
+}
+
+// TestMigrator needs empty database in TEST_DATABASE_DSN.
+func TestMigrator(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := NewMigrator(db, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx, len(migrator.migrations))
+	require.NoError(t, err)
+
+	// Replicas started at once apply every migration once.
+	var wg sync.WaitGroup
+	counts := make([]int, 4)
+	for i := range counts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := migrator.Up(ctx)
+			assert.NoError(t, err)
+			counts[i] = count
+		}()
+	}
+	wg.Wait()
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	assert.Equal(t, len(migrator.migrations), total)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.False(t, status.Pending())
+	}
+
+	count, err := migrator.Down(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[len(statuses)-1].Pending())
+	assert.False(t, statuses[0].Pending())
+
+	count, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestMigratorMetricKeys(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := NewMigrator(db, zaptest.NewLogger(t).Sugar())
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx, len(migrator.migrations))
+	require.NoError(t, err)
+
+	// Rows of schema before labels are keyed by name.
+	for _, migration := range migrator.migrations[:3] {
+		_, err = db.ExecContext(ctx, migration.Up)
+		require.NoError(t, err)
+	}
+	_, err = db.ExecContext(ctx, `
+        INSERT INTO metrics (id, mtype, delta, value) VALUES ('requests', 'counter', 5, NULL), ('temp', 'gauge', NULL, 1.5);
+        INSERT INTO metric_history (id, mtype, delta, recorded_at) VALUES ('requests', 'counter', 5, now());`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `DROP TABLE IF EXISTS schema_migrations;`)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	var keys []string
+	rows, err := db.QueryContext(ctx, `SELECT key FROM metrics ORDER BY key;`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var key string
+		require.NoError(t, rows.Scan(&key))
+		keys = append(keys, key)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"counter:requests", "gauge:temp"}, keys)
+	var historyKey string
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT id FROM metric_history;`).Scan(&historyKey))
+	assert.Equal(t, "counter:requests", historyKey)
+
+	// Downgrade doesn't drop metrics that share name.
+	_, err = db.ExecContext(ctx, `INSERT INTO metrics (key, id, mtype, labels, delta) VALUES ('counter:requests{host="a"}', 'requests', 'counter', '{"host":"a"}', 1);`)
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx, 2)
+	assert.Error(t, err)
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT count(*) FROM metrics;`).Scan(&count))
+	assert.Equal(t, 3, count)
+	_, err = db.ExecContext(ctx, `DELETE FROM metrics WHERE labels <> '{}';`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS metrics (
+    id TEXT PRIMARY KEY,
+    mtype TEXT,
+    delta BIGINT,
+    value DOUBLE PRECISION
+);
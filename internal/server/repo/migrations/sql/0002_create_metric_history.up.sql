@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS metric_history (
+    id TEXT NOT NULL,
+    mtype TEXT NOT NULL,
+    delta BIGINT,
+    value DOUBLE PRECISION,
+    recorded_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS metric_history_id_recorded_at_idx ON metric_history (id, recorded_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS metric_history_1h;
+DROP TABLE IF EXISTS metric_history_1m;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS metric_history_1m (
+    id TEXT NOT NULL,
+    mtype TEXT NOT NULL,
+    bucket TIMESTAMPTZ NOT NULL,
+    delta BIGINT,
+    value DOUBLE PRECISION,
+    min DOUBLE PRECISION,
+    max DOUBLE PRECISION,
+    avg DOUBLE PRECISION,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (id, bucket)
+);
+CREATE TABLE IF NOT EXISTS metric_history_1h (LIKE metric_history_1m INCLUDING ALL);
@@ -0,0 +1,18 @@
+-- Metrics are identified by name again. Metrics that differ only by type or labels can't be kept
+-- under one name, so migration fails instead of dropping them. Labels are dropped.
+DO $$
+DECLARE
+    duplicate TEXT;
+BEGIN
+    SELECT id INTO duplicate FROM metrics GROUP BY id HAVING count(*) > 1 LIMIT 1;
+    IF duplicate IS NOT NULL THEN
+        RAISE EXCEPTION 'metrics with name % differ by type or labels, delete all but one of them before downgrade', duplicate;
+    END IF;
+END $$;
+UPDATE metric_history SET id = m.id FROM metrics AS m WHERE metric_history.id = m.key;
+UPDATE metric_history_1m SET id = m.id FROM metrics AS m WHERE metric_history_1m.id = m.key;
+UPDATE metric_history_1h SET id = m.id FROM metrics AS m WHERE metric_history_1h.id = m.key;
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics DROP COLUMN key;
+ALTER TABLE metrics DROP COLUMN labels;
+ALTER TABLE metrics ADD PRIMARY KEY (id);
@@ -0,0 +1,30 @@
+-- Metrics are identified by key, which includes type and labels, instead of name.
+-- Key is built the same way as models.MetricKey, e.g. gauge:HeapAlloc{host="a"}.
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS key TEXT;
+UPDATE metrics SET key = id WHERE key IS NULL;
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.key_column_usage
+        WHERE table_name = 'metrics' AND constraint_name = 'metrics_pkey' AND column_name = 'key'
+    ) THEN
+        ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+        ALTER TABLE metrics ADD PRIMARY KEY (key);
+    END IF;
+END $$;
+
+-- Rows written by older versions are keyed by name, or by name and labels without type.
+CREATE TEMPORARY TABLE metric_key_renames ON COMMIT DROP AS
+SELECT key AS old_key, mtype || ':' || id || COALESCE((
+    SELECT '{' || string_agg(
+        name || '="' || replace(replace(replace(value, '\', '\\'), '"', '\"'), E'\n', '\n') || '"',
+        ',' ORDER BY name COLLATE "C") || '}'
+    FROM jsonb_each_text(labels) AS l(name, value)
+), '') AS new_key
+FROM metrics;
+DELETE FROM metric_key_renames WHERE old_key = new_key;
+UPDATE metrics SET key = r.new_key FROM metric_key_renames AS r WHERE metrics.key = r.old_key;
+UPDATE metric_history SET id = r.new_key FROM metric_key_renames AS r WHERE metric_history.id = r.old_key;
+UPDATE metric_history_1m SET id = r.new_key FROM metric_key_renames AS r WHERE metric_history_1m.id = r.old_key;
+UPDATE metric_history_1h SET id = r.new_key FROM metric_key_renames AS r WHERE metric_history_1h.id = r.old_key;
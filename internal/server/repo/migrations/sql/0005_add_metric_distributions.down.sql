@@ -0,0 +1,3 @@
+DELETE FROM metrics WHERE mtype IN ('histogram', 'summary');
+ALTER TABLE metrics DROP COLUMN summary;
+ALTER TABLE metrics DROP COLUMN histogram;
@@ -3,35 +3,38 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/VOTONO/go-metrics/internal/constants"
 	"github.com/VOTONO/go-metrics/internal/helpers"
 	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo/migrations"
 )
 
 // PostgresMetricStorer implementation of MetricStorer interface. Stores all metrics in sql.DB.
+// Metrics are identified by models.Metric.Key, history tables keep the same key in id column.
 type PostgresMetricStorer struct {
 	logger *zap.SugaredLogger
 	db     *sql.DB
 }
 
+// NewPostgresMetricStorer applies pending schema migrations and creates storer.
 func NewPostgresMetricStorer(logger *zap.SugaredLogger, db *sql.DB) (*PostgresMetricStorer, error) {
-	createTableSQL := `
-    CREATE TABLE IF NOT EXISTS metrics (
-        id TEXT PRIMARY KEY,
-        mtype TEXT,
-        delta BIGINT,
-        value DOUBLE PRECISION
-    );`
-
-	_, execErr := db.Exec(createTableSQL)
-	if execErr != nil {
-		logger.Errorw("failed to create table", "err", execErr.Error())
-		return nil, execErr
+	migrator, err := migrations.NewMigrator(db, logger)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		logger.Errorw("failed to migrate schema", "err", err.Error())
+		return nil, err
 	}
 
 	return &PostgresMetricStorer{
@@ -42,33 +45,12 @@ func NewPostgresMetricStorer(logger *zap.SugaredLogger, db *sql.DB) (*PostgresMe
 
 // StoreSingle inserts or updates a metric in the database
 func (p PostgresMetricStorer) StoreSingle(ctx context.Context, newMetric models.Metric) (*models.Metric, error) {
-
-	tx, txErr := p.db.Begin()
-	if txErr != nil {
-		p.logger.Errorw("failed to begin transaction", "err", txErr.Error())
-		return nil, txErr
-	}
-
-	var updatedMetric *models.Metric
-	var err error
-	defer func() {
-		var deferErr error
-		if err != nil {
-			deferErr = tx.Rollback()
-		} else {
-			deferErr = tx.Commit()
-		}
-		if deferErr != nil {
-			p.logger.Errorw("failed to rollback or commit transaction", "err", deferErr.Error())
-		}
-	}()
-
-	updatedMetric, err = p.insertOrUpdateMetric(ctx, tx, newMetric)
+	stored, err := p.store(ctx, []models.Metric{newMetric})
 	if err != nil {
 		return nil, err
 	}
-
-	return updatedMetric, nil
+	updatedMetric := stored[newMetric.Key()]
+	return &updatedMetric, nil
 }
 
 func (p PostgresMetricStorer) StoreSlice(ctx context.Context, newMetrics []models.Metric) error {
@@ -76,135 +58,383 @@ func (p PostgresMetricStorer) StoreSlice(ctx context.Context, newMetrics []model
 	if filterErr != nil {
 		return filterErr
 	}
-	tx, txErr := p.db.Begin()
+	_, err := p.store(ctx, filteredMetrics)
+	return err
+}
+
+// maxBatchRows limits rows of one multi-row statement, Postgres allows at most 65535 parameters.
+const maxBatchRows = 1000
+
+// store writes metrics with unique keys in one transaction and returns stored metrics by key.
+// Gauges and counters are written by upsert that increments counters in SQL, so concurrent writes
+// don't lose increments. Histograms and summaries are merged in Go under row locks.
+// Rows are written in key order, so concurrent batches lock them in the same order.
+func (p PostgresMetricStorer) store(ctx context.Context, metrics []models.Metric) (map[string]models.Metric, error) {
+	var upserted, merged []models.Metric
+	for _, metric := range metrics {
+		switch metric.MType {
+		case constants.Gauge, constants.Counter:
+			upserted = append(upserted, metric)
+		case constants.Histogram, constants.Summary:
+			merged = append(merged, metric)
+		default:
+			err := fmt.Errorf("unsupported Metric type: %s", metric.MType)
+			p.logger.Errorw("error storing Metric", "metric_id", metric.ID, "error", err.Error())
+			return nil, err
+		}
+	}
+	sortByKey(upserted)
+	sortByKey(merged)
+
+	tx, txErr := p.db.BeginTx(ctx, nil)
 	if txErr != nil {
 		p.logger.Errorw("failed to begin transaction", "err", txErr.Error())
-		return txErr
+		return nil, txErr
 	}
-	var err error
-	defer func() {
-		var deferErr error
-		if err != nil {
-			deferErr = tx.Rollback()
-		} else {
-			deferErr = tx.Commit()
+	defer tx.Rollback()
+
+	if err := p.insertSamples(ctx, tx, metrics, time.Now()); err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]models.Metric, len(metrics))
+	for start := 0; start < len(upserted); start += maxBatchRows {
+		if err := p.upsertMetrics(ctx, tx, upserted[start:min(start+maxBatchRows, len(upserted))], stored); err != nil {
+			return nil, err
 		}
-		if deferErr != nil {
-			p.logger.Errorw("failed to rollback or commit transaction", "err", deferErr.Error())
+	}
+	for start := 0; start < len(merged); start += maxBatchRows {
+		if err := p.mergeMetrics(ctx, tx, merged[start:min(start+maxBatchRows, len(merged))], stored); err != nil {
+			return nil, err
 		}
-	}()
+	}
 
-	for _, newMetric := range filteredMetrics {
-		_, err = p.insertOrUpdateMetric(ctx, tx, newMetric)
-		if err != nil {
-			return err
-		}
+	if commitErr := tx.Commit(); commitErr != nil {
+		p.logger.Errorw("failed to commit transaction", "err", commitErr.Error())
+		return nil, commitErr
 	}
+	return stored, nil
+}
 
-	return nil
+// upsertMetrics replaces gauges and increments counters with a single statement.
+func (p PostgresMetricStorer) upsertMetrics(ctx context.Context, tx *sql.Tx, metrics []models.Metric, stored map[string]models.Metric) error {
+	args, argsErr := metricArgs(metrics)
+	if argsErr != nil {
+		return argsErr
+	}
+
+	rows, queryErr := tx.QueryContext(ctx, `
+            INSERT INTO metrics (key, id, mtype, labels, delta, value, histogram, summary)
+            VALUES `+valuesList(len(metrics), metricArgsCount)+`
+            ON CONFLICT (key) DO UPDATE
+            SET mtype = EXCLUDED.mtype, labels = EXCLUDED.labels,
+                delta = CASE WHEN EXCLUDED.mtype = 'counter' THEN COALESCE(metrics.delta, 0) + EXCLUDED.delta ELSE EXCLUDED.delta END,
+                value = EXCLUDED.value, histogram = EXCLUDED.histogram, summary = EXCLUDED.summary
+            RETURNING `+metricColumns+`;`, args...)
+	if queryErr != nil {
+		p.logger.Errorw("error storing metrics", "count", len(metrics), "error", queryErr.Error())
+		return queryErr
+	}
+	return p.scanStored(rows, stored)
 }
 
-func (p PostgresMetricStorer) insertOrUpdateMetric(ctx context.Context, tx *sql.Tx, newMetric models.Metric) (*models.Metric, error) {
+// mergeMetrics inserts new histograms and summaries and merges existing ones into rows locked with FOR UPDATE.
+func (p PostgresMetricStorer) mergeMetrics(ctx context.Context, tx *sql.Tx, metrics []models.Metric, stored map[string]models.Metric) error {
+	args, argsErr := metricArgs(metrics)
+	if argsErr != nil {
+		return argsErr
+	}
 
-	switch newMetric.MType {
-	case constants.Gauge:
-		metric, err := p.insertMetric(ctx, tx, newMetric)
-		if err != nil {
-			return nil, err
+	// Insert waits for concurrent insert of the same key, so existing rows are visible to the select below.
+	rows, queryErr := tx.QueryContext(ctx, `
+            INSERT INTO metrics (key, id, mtype, labels, delta, value, histogram, summary)
+            VALUES `+valuesList(len(metrics), metricArgsCount)+`
+            ON CONFLICT (key) DO NOTHING
+            RETURNING `+metricColumns+`;`, args...)
+	if queryErr != nil {
+		p.logger.Errorw("error storing metrics", "count", len(metrics), "error", queryErr.Error())
+		return queryErr
+	}
+	if err := p.scanStored(rows, stored); err != nil {
+		return err
+	}
+
+	existing := make([]any, 0, len(metrics))
+	for _, metric := range metrics {
+		if _, inserted := stored[metric.Key()]; !inserted {
+			existing = append(existing, metric.Key())
 		}
-		return metric, nil
-	case constants.Counter:
-		updatedMetric, err := p.insertCounterMetric(ctx, newMetric, tx)
-		if err != nil {
-			return nil, err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	rows, queryErr = tx.QueryContext(ctx, `
+            SELECT `+metricColumns+` FROM metrics
+            WHERE key IN `+valuesList(1, len(existing))+`
+            ORDER BY key FOR UPDATE;`, existing...)
+	if queryErr != nil {
+		p.logger.Errorw("error locking metrics", "count", len(existing), "error", queryErr.Error())
+		return queryErr
+	}
+	old := make(map[string]models.Metric, len(existing))
+	if err := p.scanStored(rows, old); err != nil {
+		return err
+	}
+
+	updateArgs := make([]any, 0, 3*len(old))
+	for _, metric := range metrics {
+		oldMetric, found := old[metric.Key()]
+		if !found {
+			continue
 		}
-		return updatedMetric, nil
+		updatedMetric, mergeErr := helpers.MergeMetric(oldMetric, metric)
+		if mergeErr != nil {
+			p.logger.Errorw("error storing Metric", "metric_id", metric.ID, "error", mergeErr.Error())
+			return mergeErr
+		}
+		histogram, encodeErr := encodeJSON(updatedMetric.Histogram)
+		if encodeErr != nil {
+			return encodeErr
+		}
+		summary, encodeErr := encodeJSON(updatedMetric.Summary)
+		if encodeErr != nil {
+			return encodeErr
+		}
+		updateArgs = append(updateArgs, metric.Key(), histogram, summary)
+		stored[metric.Key()] = updatedMetric
+	}
+	if len(updateArgs) == 0 {
+		return nil
+	}
 
-	default:
-		err := fmt.Errorf("unsupported Metric type: %s", newMetric.MType)
-		p.logger.Errorw("error storing Metric", "metric_id", newMetric.ID, "error", err.Error())
-		return nil, err
+	_, execErr := tx.ExecContext(ctx, `
+            UPDATE metrics AS m SET histogram = v.histogram::jsonb, summary = v.summary::jsonb
+            FROM (VALUES `+valuesList(len(updateArgs)/3, 3)+`) AS v (key, histogram, summary)
+            WHERE m.key = v.key;`, updateArgs...)
+	if execErr != nil {
+		p.logger.Errorw("error storing metrics", "count", len(old), "error", execErr.Error())
+		return execErr
 	}
+	return nil
 }
 
-func (p PostgresMetricStorer) insertCounterMetric(ctx context.Context, newMetric models.Metric, tx *sql.Tx) (*models.Metric, error) {
-	existingMetric, found, getErr := p.getMetricByID(ctx, newMetric.ID)
-	if getErr != nil {
-		return nil, getErr
+// scanStored reads metrics selected as metricColumns into stored by key and closes rows.
+func (p PostgresMetricStorer) scanStored(rows *sql.Rows, stored map[string]models.Metric) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		metric, scanErr := scanMetric(rows)
+		if scanErr != nil {
+			p.logger.Errorw("error scanning metric row", "error", scanErr.Error())
+			return scanErr
+		}
+		stored[metric.Key()] = metric
 	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		p.logger.Errorw("error during rows iteration", "error", rowsErr.Error())
+		return rowsErr
+	}
+	return nil
+}
+
+// metricArgsCount is number of arguments of every metric returned by metricArgs.
+const metricArgsCount = 8
 
-	var insertedMetric *models.Metric
-	var insertErr error
+// metricArgs returns arguments of metrics table columns key, id, mtype, labels, delta, value, histogram, summary.
+func metricArgs(metrics []models.Metric) ([]any, error) {
+	args := make([]any, 0, metricArgsCount*len(metrics))
+	for _, metric := range metrics {
+		labels, encodeErr := encodeLabels(metric.Labels)
+		if encodeErr != nil {
+			return nil, encodeErr
+		}
+		histogram, encodeErr := encodeJSON(metric.Histogram)
+		if encodeErr != nil {
+			return nil, encodeErr
+		}
+		summary, encodeErr := encodeJSON(metric.Summary)
+		if encodeErr != nil {
+			return nil, encodeErr
+		}
+		args = append(args, metric.Key(), metric.ID, metric.MType, labels, metric.Delta, metric.Value, histogram, summary)
+	}
+	return args, nil
+}
 
-	if !found {
-		insertedMetric, insertErr = p.insertMetric(ctx, tx, newMetric)
-		if insertErr != nil {
-			return nil, insertErr
+// valuesList returns placeholders of rows with given number of columns, e.g. "($1, $2), ($3, $4)".
+func valuesList(rows, columns int) string {
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			b.WriteString(", ")
 		}
-		return insertedMetric, nil
+		b.WriteByte('(')
+		for column := 0; column < columns; column++ {
+			if column > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(row*columns + column + 1))
+		}
+		b.WriteByte(')')
 	}
+	return b.String()
+}
 
-	updatedMetric, updateErr := helpers.UpdateCounterMetric(existingMetric, newMetric)
-	if updateErr != nil {
-		p.logger.Errorw("error storing Metric", "metric_id", newMetric.ID, "error", updateErr.Error())
-		return nil, updateErr
+func sortByKey(metrics []models.Metric) {
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Key() < metrics[j].Key() })
+}
+
+// insertSamples records history of metrics with a single statement per maxBatchRows samples.
+func (p PostgresMetricStorer) insertSamples(ctx context.Context, tx *sql.Tx, metrics []models.Metric, recordedAt time.Time) error {
+	args := make([]any, 0, 5*len(metrics))
+	for _, metric := range metrics {
+		if hasHistory(metric) {
+			args = append(args, metric.Key(), metric.MType, metric.Delta, metric.Value, recordedAt)
+		}
 	}
 
-	insertedMetric, insertErr = p.insertMetric(ctx, tx, updatedMetric)
-	if insertErr != nil {
-		return nil, insertErr
+	const columns = 5
+	for start := 0; start < len(args); start += columns * maxBatchRows {
+		batch := args[start:min(start+columns*maxBatchRows, len(args))]
+		_, execErr := tx.ExecContext(ctx,
+			`INSERT INTO metric_history (id, mtype, delta, value, recorded_at) VALUES `+valuesList(len(batch)/columns, columns)+`;`,
+			batch...)
+		if execErr != nil {
+			p.logger.Errorw("error storing samples", "count", len(batch)/columns, "error", execErr.Error())
+			return execErr
+		}
 	}
-	return insertedMetric, nil
+	return nil
 }
 
-func (p PostgresMetricStorer) insertMetric(ctx context.Context, tx *sql.Tx, metric models.Metric) (*models.Metric, error) {
-	stmt, prepErr := tx.PrepareContext(ctx,
-		`
-            INSERT INTO metrics (id, mtype, delta, value)
-            VALUES ($1, $2, $3, $4)
-            ON CONFLICT (id) DO UPDATE
-            SET mtype = EXCLUDED.mtype, delta = EXCLUDED.delta, value = EXCLUDED.value;`)
-	if prepErr != nil {
-		p.logger.Errorw("failed to prepare statement", "err", prepErr.Error())
-		return nil, prepErr
+// History returns samples of metric recorded in [from, to] from table of resolution chosen by step.
+func (p PostgresMetricStorer) History(ctx context.Context, key string, from, to time.Time, step time.Duration) ([]models.Sample, error) {
+	var query string
+	switch ResolutionForStep(step) {
+	case ResolutionHour:
+		query = `SELECT bucket, delta, value, min, max, avg, count FROM metric_history_1h
+                 WHERE id = $1 AND bucket BETWEEN $2 AND $3 ORDER BY bucket;`
+	case ResolutionMinute:
+		query = `SELECT bucket, delta, value, min, max, avg, count FROM metric_history_1m
+                 WHERE id = $1 AND bucket BETWEEN $2 AND $3 ORDER BY bucket;`
+	default:
+		query = `SELECT recorded_at, delta, value, NULL, NULL, NULL, 0 FROM metric_history
+                 WHERE id = $1 AND recorded_at BETWEEN $2 AND $3 ORDER BY recorded_at;`
 	}
 
-	rows, queryErr := stmt.QueryContext(ctx, metric.ID, metric.MType, metric.Delta, metric.Value)
+	rows, queryErr := p.db.QueryContext(ctx, query, key, from, to)
 	if queryErr != nil {
-		p.logger.Errorw("error storing Metric", "metric_id", metric.ID, "error", queryErr.Error())
+		p.logger.Errorw("error getting history", "key", key, "error", queryErr.Error())
 		return nil, queryErr
 	}
+	defer rows.Close()
+
+	samples := make([]models.Sample, 0)
+	for rows.Next() {
+		var sample models.Sample
+		if scanErr := rows.Scan(&sample.Timestamp, &sample.Delta, &sample.Value, &sample.Min, &sample.Max, &sample.Avg, &sample.Count); scanErr != nil {
+			p.logger.Errorw("error scanning sample row", "error", scanErr.Error())
+			return nil, scanErr
+		}
+		samples = append(samples, sample)
+	}
+
 	if rowsErr := rows.Err(); rowsErr != nil {
 		p.logger.Errorw("error during rows iteration", "error", rowsErr.Error())
 		return nil, rowsErr
 	}
-	return &metric, nil
+
+	return samples, nil
 }
 
-// Get retrieves a metric by its ID from the database
-func (p PostgresMetricStorer) Get(ctx context.Context, id string) (models.Metric, bool, error) {
-	metric, found, getErr := p.getMetricByID(ctx, id)
-	return metric, found, getErr
+// Rollup aggregates complete minutes of raw history and complete hours of minute buckets,
+// then deletes samples older than retention. Last bucket is recomputed on every call, so rollup is idempotent.
+func (p PostgresMetricStorer) Rollup(ctx context.Context, now time.Time, retention Retention) error {
+	tx, txErr := p.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		p.logger.Errorw("failed to begin transaction", "err", txErr.Error())
+		return txErr
+	}
+	defer tx.Rollback()
+
+	type statement struct {
+		query string
+		args  []interface{}
+	}
+
+	statements := []statement{
+		{
+			query: `
+            INSERT INTO metric_history_1m (id, mtype, bucket, delta, value, min, max, avg, count)
+            SELECT id, mtype, date_trunc('minute', recorded_at) AS b,
+                   SUM(delta), (array_agg(value ORDER BY recorded_at DESC))[1], MIN(value), MAX(value), AVG(value), COUNT(*)
+            FROM metric_history
+            WHERE recorded_at >= COALESCE((SELECT MAX(bucket) FROM metric_history_1m), '-infinity') AND recorded_at < $1
+            GROUP BY id, mtype, b
+            ON CONFLICT (id, bucket) DO UPDATE
+            SET delta = EXCLUDED.delta, value = EXCLUDED.value, min = EXCLUDED.min,
+                max = EXCLUDED.max, avg = EXCLUDED.avg, count = EXCLUDED.count;`,
+			args: []interface{}{now.Truncate(ResolutionMinute)},
+		},
+		{
+			query: `
+            INSERT INTO metric_history_1h (id, mtype, bucket, delta, value, min, max, avg, count)
+            SELECT id, mtype, date_trunc('hour', bucket) AS b,
+                   SUM(delta), (array_agg(value ORDER BY bucket DESC))[1], MIN(min), MAX(max),
+                   SUM(avg * count) / NULLIF(SUM(count) FILTER (WHERE avg IS NOT NULL), 0), SUM(count)
+            FROM metric_history_1m
+            WHERE bucket >= COALESCE((SELECT MAX(bucket) FROM metric_history_1h), '-infinity') AND bucket < $1
+            GROUP BY id, mtype, b
+            ON CONFLICT (id, bucket) DO UPDATE
+            SET delta = EXCLUDED.delta, value = EXCLUDED.value, min = EXCLUDED.min,
+                max = EXCLUDED.max, avg = EXCLUDED.avg, count = EXCLUDED.count;`,
+			args: []interface{}{now.Truncate(ResolutionHour)},
+		},
+	}
+	if retention.Raw > 0 {
+		statements = append(statements, statement{`DELETE FROM metric_history WHERE recorded_at < $1;`, []interface{}{now.Add(-retention.Raw)}})
+	}
+	if retention.Minute > 0 {
+		statements = append(statements, statement{`DELETE FROM metric_history_1m WHERE bucket < $1;`, []interface{}{now.Add(-retention.Minute)}})
+	}
+	if retention.Hour > 0 {
+		statements = append(statements, statement{`DELETE FROM metric_history_1h WHERE bucket < $1;`, []interface{}{now.Add(-retention.Hour)}})
+	}
+
+	for _, statement := range statements {
+		if _, execErr := tx.ExecContext(ctx, statement.query, statement.args...); execErr != nil {
+			p.logger.Errorw("failed to rollup history", "error", execErr.Error())
+			return execErr
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (p PostgresMetricStorer) getMetricByID(ctx context.Context, id string) (models.Metric, bool, error) {
-	var metric models.Metric
+// Get retrieves a metric by its key from the database
+func (p PostgresMetricStorer) Get(ctx context.Context, key string) (models.Metric, bool, error) {
+	metric, found, getErr := p.getMetricByKey(ctx, key)
+	return metric, found, getErr
+}
 
-	stmt, prepErr := p.db.PrepareContext(ctx, `SELECT id, mtype, delta, value FROM metrics WHERE id = $1;`)
+func (p PostgresMetricStorer) getMetricByKey(ctx context.Context, key string) (models.Metric, bool, error) {
+	stmt, prepErr := p.db.PrepareContext(ctx, `SELECT `+metricColumns+` FROM metrics WHERE key = $1;`)
 	if prepErr != nil {
 		p.logger.Errorw("failed to prepare statement", "err", prepErr.Error())
-		return metric, false, prepErr
+		return models.Metric{}, false, prepErr
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, id)
+	row := stmt.QueryRowContext(ctx, key)
 
-	scanErr := row.Scan(&metric.ID, &metric.MType, &metric.Delta, &metric.Value)
+	metric, scanErr := scanMetric(row)
 	if scanErr != nil {
 		if errors.Is(scanErr, sql.ErrNoRows) {
 			return metric, false, nil
 		}
-		p.logger.Errorw("error getting metric", "id", id, "error", scanErr.Error())
+		p.logger.Errorw("error getting metric", "key", key, "error", scanErr.Error())
 		return metric, false, scanErr
 	}
 	return metric, true, nil
@@ -212,7 +442,7 @@ func (p PostgresMetricStorer) getMetricByID(ctx context.Context, id string) (mod
 
 func (p PostgresMetricStorer) All(ctx context.Context) (map[string]models.Metric, error) {
 
-	stmt, prepErr := p.db.PrepareContext(ctx, `SELECT id, mtype, delta, value FROM metrics;`)
+	stmt, prepErr := p.db.PrepareContext(ctx, `SELECT `+metricColumns+` FROM metrics;`)
 	if prepErr != nil {
 		p.logger.Errorw("failed to prepare statement", "err", prepErr.Error())
 		return nil, prepErr
@@ -228,14 +458,13 @@ func (p PostgresMetricStorer) All(ctx context.Context) (map[string]models.Metric
 
 	metrics := make(map[string]models.Metric)
 	for rows.Next() {
-		var metric models.Metric
-
-		if scanErr := rows.Scan(&metric.ID, &metric.MType, &metric.Delta, &metric.Value); scanErr != nil {
+		metric, scanErr := scanMetric(rows)
+		if scanErr != nil {
 			p.logger.Errorw("error scanning metric row", "error", scanErr.Error())
 			return nil, scanErr
 		}
 
-		metrics[metric.ID] = metric
+		metrics[metric.Key()] = metric
 	}
 
 	if rowsErr := rows.Err(); rowsErr != nil {
@@ -245,3 +474,75 @@ func (p PostgresMetricStorer) All(ctx context.Context) (map[string]models.Metric
 
 	return metrics, nil
 }
+
+// scanner is implemented by sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// metricColumns are columns of metrics table read by scanMetric.
+const metricColumns = `id, mtype, labels, delta, value, histogram, summary`
+
+// scanMetric reads metric from row selected as metricColumns.
+func scanMetric(row scanner) (models.Metric, error) {
+	var metric models.Metric
+	var labels, histogram, summary []byte
+
+	if err := row.Scan(&metric.ID, &metric.MType, &labels, &metric.Delta, &metric.Value, &histogram, &summary); err != nil {
+		return models.Metric{}, err
+	}
+
+	decoded, err := decodeLabels(labels)
+	if err != nil {
+		return models.Metric{}, err
+	}
+	metric.Labels = decoded
+
+	if histogram != nil {
+		if err := json.Unmarshal(histogram, &metric.Histogram); err != nil {
+			return models.Metric{}, err
+		}
+	}
+	if summary != nil {
+		if err := json.Unmarshal(summary, &metric.Summary); err != nil {
+			return models.Metric{}, err
+		}
+	}
+	return metric, nil
+}
+
+// encodeJSON converts optional value to JSON, nil value is stored as NULL.
+func encodeJSON[T any](value *T) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	result := string(encoded)
+	return &result, nil
+}
+
+// encodeLabels converts labels to JSON object, metric without labels is stored as empty object.
+func encodeLabels(labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(labels)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func decodeLabels(data []byte) (map[string]string, error) {
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
@@ -0,0 +1,121 @@
+package repo_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+	"github.com/VOTONO/go-metrics/internal/server/router"
+)
+
+// newTestPostgresStorer connects to database from TEST_DATABASE_DSN, test is skipped if it's not set.
+func newTestPostgresStorer(t *testing.T) (*repo.PostgresMetricStorer, *sql.DB) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	storer, err := repo.NewPostgresMetricStorer(zaptest.NewLogger(t).Sugar(), db)
+	require.NoError(t, err)
+	return storer, db
+}
+
+// TestPostgresConcurrentUpdates sends batches of parallel agents to /updates/ and checks that no increments are lost.
+func TestPostgresConcurrentUpdates(t *testing.T) {
+	storer, db := newTestPostgresStorer(t)
+	ctx := context.Background()
+
+	server := httptest.NewServer(router.Router(storer, db, zaptest.NewLogger(t).Sugar(), ""))
+	defer server.Close()
+
+	const agents = 8
+	const requests = 25
+	suffix := time.Now().UnixNano()
+	counterID := fmt.Sprintf("concurrent_counter_%d", suffix)
+	histogramID := fmt.Sprintf("concurrent_histogram_%d", suffix)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, agents*requests)
+	for agent := 0; agent < agents; agent++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				one := int64(1)
+				value := float64(agent)
+				histogram := models.NewHistogram([]float64{1, 5})
+				histogram.Observe(value)
+				// Duplicates in a batch are summed before storing.
+				batch := []models.Metric{
+					{ID: counterID, MType: constants.Counter, Delta: &one},
+					{ID: counterID, MType: constants.Counter, Delta: &one},
+					{ID: fmt.Sprintf("concurrent_gauge_%d", suffix), MType: constants.Gauge, Value: &value},
+					{ID: histogramID, MType: constants.Histogram, Histogram: histogram},
+				}
+				body, err := json.Marshal(batch)
+				if err != nil {
+					errs <- err
+					return
+				}
+				resp, err := http.Post(server.URL+"/updates/", "application/json", bytes.NewReader(body))
+				if err != nil {
+					errs <- err
+					return
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					errs <- fmt.Errorf("status %d", resp.StatusCode)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	counter, found, err := storer.Get(ctx, models.MetricKey(constants.Counter, counterID, nil))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, int64(2*agents*requests), *counter.Delta)
+
+	histogram, found, err := storer.Get(ctx, models.MetricKey(constants.Histogram, histogramID, nil))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, uint64(agents*requests), histogram.Histogram.Count)
+}
+
+// TestPostgresStoreSingle checks that StoreSingle returns stored state of counter.
+func TestPostgresStoreSingle(t *testing.T) {
+	storer, _ := newTestPostgresStorer(t)
+	ctx := context.Background()
+
+	delta := int64(3)
+	metric := models.Metric{ID: fmt.Sprintf("single_counter_%d", time.Now().UnixNano()), MType: constants.Counter, Delta: &delta}
+	for _, want := range []int64{3, 6} {
+		stored, err := storer.StoreSingle(ctx, metric)
+		require.NoError(t, err)
+		assert.Equal(t, want, *stored.Delta)
+	}
+}
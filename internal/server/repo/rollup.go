@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// Resolutions of stored history.
+const (
+	ResolutionRaw    time.Duration = 0
+	ResolutionMinute               = time.Minute
+	ResolutionHour                 = time.Hour
+)
+
+// Retention defines how long samples of every resolution are kept. Zero keeps samples forever.
+type Retention struct {
+	Raw    time.Duration
+	Minute time.Duration
+	Hour   time.Duration
+}
+
+// ResolutionForStep returns the coarsest resolution which is not coarser than step.
+func ResolutionForStep(step time.Duration) time.Duration {
+	switch {
+	case step >= ResolutionHour:
+		return ResolutionHour
+	case step >= ResolutionMinute:
+		return ResolutionMinute
+	default:
+		return ResolutionRaw
+	}
+}
+
+// StartRollup starts goroutine that's periodically compacts history into 1-minute and 1-hour buckets.
+func StartRollup(ctx context.Context, storer HistoryStorer, logger *zap.SugaredLogger, interval int, retention Retention) {
+	if interval <= 0 {
+		logger.Infow("skip history rollup", "interval", interval)
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := storer.Rollup(ctx, now, retention); err != nil {
+					logger.Errorw("failed to rollup history", "error", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// rollupSamples groups chronologically ordered samples into buckets of given resolution.
+// Samples may be raw or already aggregated buckets of finer resolution.
+func rollupSamples(samples []models.Sample, resolution time.Duration) []models.Sample {
+	var buckets []models.Sample
+	var group []models.Sample
+	for _, sample := range samples {
+		start := sample.Timestamp.Truncate(resolution)
+		if len(group) > 0 && !group[0].Timestamp.Truncate(resolution).Equal(start) {
+			buckets = append(buckets, mergeSamples(group[0].Timestamp.Truncate(resolution), group))
+			group = group[:0]
+		}
+		group = append(group, sample)
+	}
+	if len(group) > 0 {
+		buckets = append(buckets, mergeSamples(group[0].Timestamp.Truncate(resolution), group))
+	}
+	return buckets
+}
+
+// mergeSamples aggregates samples into single bucket: gauges keep min/max/avg/last, counters keep sum.
+func mergeSamples(timestamp time.Time, samples []models.Sample) models.Sample {
+	bucket := models.Sample{Timestamp: timestamp}
+
+	var weightedAvg float64
+	var gaugeCount int64
+	for _, sample := range samples {
+		count := sample.Count
+		if count == 0 {
+			count = 1
+		}
+		bucket.Count += count
+
+		if sample.Delta != nil {
+			sum := *sample.Delta
+			if bucket.Delta != nil {
+				sum += *bucket.Delta
+			}
+			bucket.Delta = &sum
+		}
+
+		if sample.Value != nil {
+			minValue, maxValue, avgValue := *sample.Value, *sample.Value, *sample.Value
+			if sample.Min != nil {
+				minValue = *sample.Min
+			}
+			if sample.Max != nil {
+				maxValue = *sample.Max
+			}
+			if sample.Avg != nil {
+				avgValue = *sample.Avg
+			}
+
+			if bucket.Min == nil || minValue < *bucket.Min {
+				bucket.Min = &minValue
+			}
+			if bucket.Max == nil || maxValue > *bucket.Max {
+				bucket.Max = &maxValue
+			}
+			weightedAvg += avgValue * float64(count)
+			gaugeCount += count
+
+			last := *sample.Value
+			bucket.Value = &last
+		}
+	}
+
+	if gaugeCount > 0 {
+		avg := weightedAvg / float64(gaugeCount)
+		bucket.Avg = &avg
+	}
+	return bucket
+}
+
+// dropBefore removes samples older than border from chronologically ordered slice.
+func dropBefore(samples []models.Sample, border time.Time) []models.Sample {
+	i := 0
+	for i < len(samples) && samples[i].Timestamp.Before(border) {
+		i++
+	}
+	return samples[i:]
+}
+
+// between returns samples from chronologically ordered slice that are in [from, to].
+func between(samples []models.Sample, from, to time.Time) []models.Sample {
+	result := make([]models.Sample, 0)
+	for _, sample := range samples {
+		if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+			continue
+		}
+		result = append(result, sample)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func TestResolutionForStep(t *testing.T) {
+	tests := []struct {
+		step time.Duration
+		want time.Duration
+	}{
+		{0, ResolutionRaw},
+		{30 * time.Second, ResolutionRaw},
+		{time.Minute, ResolutionMinute},
+		{15 * time.Minute, ResolutionMinute},
+		{time.Hour, ResolutionHour},
+		{24 * time.Hour, ResolutionHour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ResolutionForStep(tt.step), tt.step.String())
+	}
+}
+
+func TestMergeSamples(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	value := func(v float64) *float64 { return &v }
+	delta := func(d int64) *int64 { return &d }
+
+	t.Run("Raw gauge samples", func(t *testing.T) {
+		bucket := mergeSamples(ts, []models.Sample{{Value: value(3)}, {Value: value(1)}, {Value: value(5)}, {Value: value(3)}})
+		assert.Equal(t, 3.0, *bucket.Value)
+		assert.Equal(t, 1.0, *bucket.Min)
+		assert.Equal(t, 5.0, *bucket.Max)
+		assert.Equal(t, 3.0, *bucket.Avg)
+		assert.Equal(t, int64(4), bucket.Count)
+		assert.Nil(t, bucket.Delta)
+	})
+
+	t.Run("Aggregated gauge buckets", func(t *testing.T) {
+		bucket := mergeSamples(ts, []models.Sample{
+			{Value: value(2), Min: value(1), Max: value(4), Avg: value(2), Count: 3},
+			{Value: value(6), Min: value(5), Max: value(7), Avg: value(6), Count: 1},
+		})
+		assert.Equal(t, 6.0, *bucket.Value)
+		assert.Equal(t, 1.0, *bucket.Min)
+		assert.Equal(t, 7.0, *bucket.Max)
+		assert.Equal(t, 3.0, *bucket.Avg)
+		assert.Equal(t, int64(4), bucket.Count)
+	})
+
+	t.Run("Counter samples", func(t *testing.T) {
+		bucket := mergeSamples(ts, []models.Sample{{Delta: delta(5)}, {Delta: delta(7), Count: 2}})
+		assert.Equal(t, int64(12), *bucket.Delta)
+		assert.Equal(t, int64(3), bucket.Count)
+		assert.Nil(t, bucket.Value)
+	})
+}
+
+func TestRingHistoryStorer_Rollup(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := NewRingHistoryStorer(NewLocalMetricStorer(false, "", logger), 1000)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	clock := start
+	storer.now = func() time.Time { return clock }
+
+	// Two samples every minute for 90 minutes.
+	for i := 0; i < 180; i++ {
+		clock = start.Add(time.Duration(i) * 30 * time.Second)
+		value := float64(i % 2)
+		one := int64(1)
+		require.NoError(t, storer.StoreSlice(context.Background(), []models.Metric{
+			{ID: "gauge", MType: constants.Gauge, Value: &value},
+			{ID: "counter", MType: constants.Counter, Delta: &one},
+		}))
+	}
+
+	now := start.Add(90 * time.Minute)
+	retention := Retention{Raw: 10 * time.Minute}
+	require.NoError(t, storer.Rollup(context.Background(), now, retention))
+	// Repeated rollup must not produce duplicate buckets.
+	require.NoError(t, storer.Rollup(context.Background(), now, retention))
+
+	minutes, err := storer.History(context.Background(), "gauge:gauge", start, now, ResolutionMinute)
+	require.NoError(t, err)
+	require.Len(t, minutes, 90)
+	assert.Equal(t, start, minutes[0].Timestamp)
+	assert.Equal(t, 0.0, *minutes[0].Min)
+	assert.Equal(t, 1.0, *minutes[0].Max)
+	assert.Equal(t, 0.5, *minutes[0].Avg)
+	assert.Equal(t, 1.0, *minutes[0].Value)
+	assert.Equal(t, int64(2), minutes[0].Count)
+
+	hours, err := storer.History(context.Background(), "counter:counter", start, now, 2*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, hours, 1, "incomplete hour must not be rolled up")
+	assert.Equal(t, int64(120), *hours[0].Delta)
+
+	raw, err := storer.History(context.Background(), "counter:counter", start, now, 0)
+	require.NoError(t, err)
+	assert.Len(t, raw, 20, "raw samples older than retention must be dropped")
+}
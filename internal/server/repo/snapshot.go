@@ -0,0 +1,251 @@
+package repo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// snapshotVersion is format version of snapshot files. Files written before versioning are plain
+// JSON maps of metrics, they are still restored but have no checksum. Formats are told apart by
+// top-level version field: it's a number in versioned file and an object in plain map, if any.
+const snapshotVersion = 1
+
+// snapshotFile is file format of snapshot, checksum detects truncated or damaged file on restore.
+type snapshotFile struct {
+	Version  int             `json:"version"`
+	Sequence uint64          `json:"sequence,omitempty"` // last WAL record in snapshot, see WALMetricStorerImpl
+	Checksum string          `json:"checksum,omitempty"` // hex SHA-256 of metrics
+	Metrics  json.RawMessage `json:"metrics"`
+}
+
+// snapshot is content of snapshot file.
+type snapshot struct {
+	Sequence uint64
+	Metrics  map[string]models.Metric
+}
+
+// errCorruptSnapshot means snapshot file can't be trusted.
+var errCorruptSnapshot = errors.New("corrupt snapshot")
+
+func encodeSnapshot(s snapshot) ([]byte, error) {
+	metrics, err := json.Marshal(s.Metrics)
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(metrics)
+	return json.Marshal(snapshotFile{
+		Version:  snapshotVersion,
+		Sequence: s.Sequence,
+		Checksum: hex.EncodeToString(checksum[:]),
+		Metrics:  metrics,
+	})
+}
+
+func decodeSnapshot(content []byte) (snapshot, error) {
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(content, &header); err != nil {
+		return snapshot{}, fmt.Errorf("%w: %w", errCorruptSnapshot, err)
+	}
+
+	var version int
+	if raw, ok := header["version"]; !ok || json.Unmarshal(raw, &version) != nil {
+		var metrics map[string]models.Metric
+		if err := json.Unmarshal(content, &metrics); err != nil {
+			return snapshot{}, fmt.Errorf("%w: %w", errCorruptSnapshot, err)
+		}
+		return snapshot{Metrics: metrics}, nil
+	}
+	if version != snapshotVersion {
+		return snapshot{}, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	var file snapshotFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return snapshot{}, fmt.Errorf("%w: %w", errCorruptSnapshot, err)
+	}
+	checksum := sha256.Sum256(file.Metrics)
+	if hex.EncodeToString(checksum[:]) != file.Checksum {
+		return snapshot{}, fmt.Errorf("%w: checksum mismatch", errCorruptSnapshot)
+	}
+
+	var metrics map[string]models.Metric
+	if err := json.Unmarshal(file.Metrics, &metrics); err != nil {
+		return snapshot{}, fmt.Errorf("%w: %w", errCorruptSnapshot, err)
+	}
+	if metrics == nil {
+		metrics = make(map[string]models.Metric)
+	}
+	return snapshot{Sequence: file.Sequence, Metrics: metrics}, nil
+}
+
+// restoreSnapshot reads newest valid snapshot: file itself or one of its backups file.1, file.2 and so on.
+// Empty snapshot is returned if there are no snapshot files.
+func restoreSnapshot(file string, logger *zap.SugaredLogger) (snapshot, error) {
+	backups, err := snapshotBackups(file)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	var firstErr error
+	found := false
+	for _, path := range append([]string{file}, backups...) {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		found = true
+		if err != nil {
+			logError(logger, "failed to open file", path, err)
+			firstErr = errors.Join(firstErr, err)
+			continue
+		}
+
+		s, err := decodeSnapshot(content)
+		if err != nil {
+			logError(logger, "failed to decode file", path, err)
+			firstErr = errors.Join(firstErr, err)
+			continue
+		}
+		if path != file {
+			logger.Errorw("restored metrics from snapshot backup", "file", file, "backup", path)
+		}
+		s.Metrics = rekeyMetrics(s.Metrics, path, logger)
+		return s, nil
+	}
+
+	if !found {
+		logger.Infow("file not found", "file", file)
+		return snapshot{Metrics: make(map[string]models.Metric)}, nil
+	}
+	return snapshot{}, firstErr
+}
+
+// writeSnapshot atomically replaces file with snapshot and keeps keep newest snapshots:
+// previous ones are shifted to file.1, file.2 and so on. Backups are untouched if keep is 1 or less.
+//
+// Snapshot is written to temporary file, synced and renamed over file, then directory is synced,
+// so crash leaves either old or new snapshot. Until rename file may be missing, restoreSnapshot
+// falls back to file.1 then.
+func writeSnapshot(file string, s snapshot, keep int) error {
+	content, err := encodeSnapshot(s)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(file)
+	tmp, err := os.CreateTemp(dir, filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	if _, err := writer.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if keep > 1 {
+		if err := rotateSnapshots(file, keep); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// rotateSnapshots shifts file to file.1, file.1 to file.2 and so on, and removes backups beyond keep.
+func rotateSnapshots(file string, keep int) error {
+	backups, err := snapshotBackups(file)
+	if err != nil {
+		return err
+	}
+	for _, path := range backups {
+		if number, _ := backupNumber(file, path); number >= uint64(keep-1) {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	for number := keep - 2; number >= 1; number-- {
+		err := os.Rename(backupPath(file, uint64(number)), backupPath(file, uint64(number+1)))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(file, backupPath(file, 1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// snapshotBackups returns paths of backups of file, newest first.
+func snapshotBackups(file string) ([]string, error) {
+	matches, err := filepath.Glob(file + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []uint64
+	for _, match := range matches {
+		if number, ok := backupNumber(file, match); ok {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	backups := make([]string, len(numbers))
+	for i, number := range numbers {
+		backups[i] = backupPath(file, number)
+	}
+	return backups, nil
+}
+
+func backupNumber(file, path string) (uint64, bool) {
+	number, err := strconv.ParseUint(strings.TrimPrefix(path, file+"."), 10, 64)
+	if err != nil || number == 0 {
+		return 0, false
+	}
+	return number, true
+}
+
+func backupPath(file string, number uint64) string {
+	return file + "." + strconv.FormatUint(number, 10)
+}
+
+// syncDir makes creation, removal and renames of files in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
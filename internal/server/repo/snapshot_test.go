@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func TestWriteSnapshotRotation(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	for delta := int64(1); delta <= 5; delta++ {
+		metric := counter("requests", delta)
+		require.NoError(t, WriteSnapshot(path, map[string]models.Metric{metric.Key(): metric}, 3, logger))
+	}
+
+	backups, err := snapshotBackups(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{path + ".1", path + ".2"}, backups)
+
+	for file, want := range map[string]int64{path: 5, path + ".1": 4, path + ".2": 3} {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		restored, err := decodeSnapshot(content)
+		require.NoError(t, err)
+		assert.Equal(t, want, *restored.Metrics[counter("requests", 0).Key()].Delta, file)
+	}
+
+	// Lower keep removes extra backups, keep 1 doesn't touch them.
+	metric := counter("requests", 6)
+	require.NoError(t, WriteSnapshot(path, map[string]models.Metric{metric.Key(): metric}, 2, logger))
+	backups, err = snapshotBackups(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{path + ".1"}, backups)
+
+	require.NoError(t, RewriteFile(path, map[string]models.Metric{metric.Key(): metric}, logger))
+	backups, err = snapshotBackups(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{path + ".1"}, backups)
+}
+
+func TestReadFileFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		damage  func(t *testing.T, path string)
+		want    int64
+		wantErr bool
+	}{
+		{name: "valid", damage: func(t *testing.T, path string) {}, want: 3},
+		{name: "truncated", damage: func(t *testing.T, path string) {
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, content[:len(content)/2], 0666))
+		}, want: 2},
+		{name: "empty", damage: func(t *testing.T, path string) {
+			require.NoError(t, os.WriteFile(path, nil, 0666))
+		}, want: 2},
+		{name: "checksum mismatch", damage: func(t *testing.T, path string) {
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, bytes.Replace(content, []byte(`"delta":3`), []byte(`"delta":9`), 1), 0666))
+		}, want: 2},
+		{name: "missing after rotation", damage: func(t *testing.T, path string) {
+			require.NoError(t, os.Remove(path))
+		}, want: 2},
+		{name: "all damaged", damage: func(t *testing.T, path string) {
+			for _, file := range []string{path, path + ".1", path + ".2"} {
+				require.NoError(t, os.WriteFile(file, []byte("{"), 0666))
+			}
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zaptest.NewLogger(t).Sugar()
+			path := filepath.Join(t.TempDir(), "metrics.json")
+			for delta := int64(1); delta <= 3; delta++ {
+				metric := counter("requests", delta)
+				require.NoError(t, WriteSnapshot(path, map[string]models.Metric{metric.Key(): metric}, 3, logger))
+			}
+
+			tt.damage(t, path)
+
+			metrics, err := ReadFile(path, logger)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *metrics[counter("requests", 0).Key()].Delta)
+
+			storer := NewLocalMetricStorer(true, path, logger)
+			metric, found, err := storer.Get(context.Background(), counter("requests", 0).Key())
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, tt.want, *metric.Delta)
+		})
+	}
+}
+
+func TestDecodeSnapshotLegacy(t *testing.T) {
+	// Keys of plain map may be the same as fields of versioned file.
+	metrics := map[string]models.Metric{
+		"metrics":  counter("metrics", 1),
+		"version":  counter("version", 2),
+		"checksum": gauge("checksum", 3),
+	}
+	content, err := json.Marshal(metrics)
+	require.NoError(t, err)
+
+	restored, err := decodeSnapshot(content)
+	require.NoError(t, err)
+	assert.Equal(t, metrics, restored.Metrics)
+
+	_, err = decodeSnapshot([]byte(`{"version": 2, "metrics": {}}`))
+	assert.ErrorContains(t, err, "unsupported snapshot version")
+}
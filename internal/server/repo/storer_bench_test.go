@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo/logstore"
+)
+
+const benchmarkMetrics = 100
+
+// benchmarkStorers returns constructors of compared storers, run with:
+//
+//	go test -run=^$ -bench=Storer ./internal/server/repo/
+func benchmarkStorers() []struct {
+	name string
+	new  func(b *testing.B) MetricStorer
+} {
+	logger := zap.NewNop().Sugar()
+	logStorer := func(sync bool) func(b *testing.B) MetricStorer {
+		return func(b *testing.B) MetricStorer {
+			options := logstore.DefaultOptions
+			options.Sync = sync
+			storer, err := NewLogMetricStorer(b.TempDir(), options, logger)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { storer.Close() })
+			return storer
+		}
+	}
+
+	return []struct {
+		name string
+		new  func(b *testing.B) MetricStorer
+	}{
+		{name: "local", new: func(b *testing.B) MetricStorer {
+			return NewLocalMetricStorer(false, "", logger)
+		}},
+		{name: "file", new: func(b *testing.B) MetricStorer {
+			return NewFileMetricStorer(filepath.Join(b.TempDir(), "metrics.json"), logger)
+		}},
+		{name: "wal", new: func(b *testing.B) MetricStorer {
+			storer, err := NewWALMetricStorer(filepath.Join(b.TempDir(), "metrics.json"), logger)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { storer.Close() })
+			return storer
+		}},
+		{name: "log", new: logStorer(true)},
+		{name: "log-nosync", new: logStorer(false)},
+	}
+}
+
+func benchmarkBatch() []models.Metric {
+	batch := make([]models.Metric, 0, benchmarkMetrics)
+	for i := 0; i < benchmarkMetrics/2; i++ {
+		batch = append(batch, counter(fmt.Sprintf("counter%d", i), 1), gauge(fmt.Sprintf("gauge%d", i), float64(i)))
+	}
+	return batch
+}
+
+func BenchmarkStorerStoreSingle(b *testing.B) {
+	ctx := context.Background()
+	batch := benchmarkBatch()
+	for _, bs := range benchmarkStorers() {
+		b.Run(bs.name, func(b *testing.B) {
+			storer := bs.new(b)
+			if err := storer.StoreSlice(ctx, batch); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := storer.StoreSingle(ctx, batch[i%len(batch)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStorerStoreSlice(b *testing.B) {
+	ctx := context.Background()
+	batch := benchmarkBatch()
+	for _, bs := range benchmarkStorers() {
+		b.Run(bs.name, func(b *testing.B) {
+			storer := bs.new(b)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := storer.StoreSlice(ctx, batch); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStorerGet(b *testing.B) {
+	ctx := context.Background()
+	batch := benchmarkBatch()
+	for _, bs := range benchmarkStorers() {
+		b.Run(bs.name, func(b *testing.B) {
+			storer := bs.new(b)
+			if err := storer.StoreSlice(ctx, batch); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := storer.Get(ctx, batch[i%len(batch)].Key()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
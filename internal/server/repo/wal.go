@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// WAL record is a header followed by JSON of stored metrics:
+//
+//	length uint32 | crc32c uint32 | sequence uint64 | payload
+//
+// All numbers are little endian, length and checksum cover sequence and payload.
+const (
+	walHeaderSize    = 8
+	walSequenceSize  = 8
+	walMaxRecordSize = 64 << 20
+)
+
+var walTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord means record is incomplete or corrupt, it is the end of valid log.
+var errTornRecord = errors.New("torn WAL record")
+
+type walRecord struct {
+	Sequence uint64
+	Metrics  []models.Metric
+}
+
+func encodeWALRecord(record walRecord) ([]byte, error) {
+	payload, err := json.Marshal(record.Metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, walHeaderSize+walSequenceSize+len(payload))
+	binary.LittleEndian.PutUint64(buf[walHeaderSize:], record.Sequence)
+	copy(buf[walHeaderSize+walSequenceSize:], payload)
+	body := buf[walHeaderSize:]
+	binary.LittleEndian.PutUint32(buf[0:], uint32(len(body)))
+	binary.LittleEndian.PutUint32(buf[4:], crc32.Checksum(body, walTable))
+	return buf, nil
+}
+
+// readWALRecord reads next record, io.EOF is returned at the end of log and errTornRecord
+// if the rest of log can't be trusted.
+func readWALRecord(r io.Reader) (walRecord, int, error) {
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return walRecord{}, 0, io.EOF
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return walRecord{}, 0, errTornRecord
+		}
+		return walRecord{}, 0, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:])
+	if length < walSequenceSize || length > walMaxRecordSize {
+		return walRecord{}, 0, errTornRecord
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return walRecord{}, 0, errTornRecord
+		}
+		return walRecord{}, 0, err
+	}
+	if crc32.Checksum(body, walTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return walRecord{}, 0, errTornRecord
+	}
+
+	record := walRecord{Sequence: binary.LittleEndian.Uint64(body)}
+	if err := json.Unmarshal(body[walSequenceSize:], &record.Metrics); err != nil {
+		return walRecord{}, 0, errTornRecord
+	}
+	return record, walHeaderSize + int(length), nil
+}
+
+// replayWALSegment calls apply for every valid record of segment and returns size of valid part.
+// Torn tail left by crash in the middle of append is not an error.
+func replayWALSegment(path string, apply func(walRecord) error) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var size int64
+	for {
+		record, n, err := readWALRecord(reader)
+		if errors.Is(err, io.EOF) || errors.Is(err, errTornRecord) {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		if err := apply(record); err != nil {
+			return size, err
+		}
+		size += int64(n)
+	}
+}
+
+// walSegments returns paths of WAL segments of snapshot ordered by number.
+func walSegments(snapshotPath string) ([]string, []uint64, error) {
+	matches, err := filepath.Glob(snapshotPath + ".wal.*")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var numbers []uint64
+	for _, match := range matches {
+		number, err := strconv.ParseUint(strings.TrimPrefix(match, snapshotPath+".wal."), 10, 64)
+		if err != nil {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	paths := make([]string, len(numbers))
+	for i, number := range numbers {
+		paths[i] = walSegmentPath(snapshotPath, number)
+	}
+	return paths, numbers, nil
+}
+
+func walSegmentPath(snapshotPath string, number uint64) string {
+	return fmt.Sprintf("%s.wal.%010d", snapshotPath, number)
+}
+
+// walSegment is a WAL file open for appending.
+type walSegment struct {
+	file   *os.File
+	number uint64
+	size   int64
+	err    error // segment is unusable after failed rollback of partial append
+}
+
+func createWALSegment(snapshotPath string, number uint64) (*walSegment, error) {
+	file, err := os.OpenFile(walSegmentPath(snapshotPath, number), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := syncDir(filepath.Dir(snapshotPath)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &walSegment{file: file, number: number}, nil
+}
+
+// Append writes record and waits until it reaches disk. Partially written record is cut off,
+// so records appended later are not hidden behind it during replay.
+func (s *walSegment) Append(record walRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	buf, err := encodeWALRecord(record)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(buf); err != nil {
+		return s.rollback(err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return s.rollback(err)
+	}
+	s.size += int64(len(buf))
+	return nil
+}
+
+func (s *walSegment) rollback(cause error) error {
+	if err := s.file.Truncate(s.size); err != nil {
+		s.err = fmt.Errorf("WAL segment is broken: %w", errors.Join(cause, err))
+		return s.err
+	}
+	if _, err := s.file.Seek(s.size, io.SeekStart); err != nil {
+		s.err = fmt.Errorf("WAL segment is broken: %w", errors.Join(cause, err))
+		return s.err
+	}
+	return cause
+}
+
+func (s *walSegment) Close() error {
+	return s.file.Close()
+}
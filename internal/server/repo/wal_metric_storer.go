@@ -0,0 +1,258 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/helpers"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// WALMetricStorerImpl implementation of MetricStorer interface. Stores all metrics in map,
+// every change is appended to write-ahead log before it is applied, so stored metrics survive crash.
+//
+// Log is split into numbered segment files next to snapshot. Compact writes snapshot with sequence
+// of last applied record and removes older segments, records already in snapshot are skipped on replay.
+type WALMetricStorerImpl struct {
+	mu           sync.RWMutex
+	compactMu    sync.Mutex // only one compaction writes snapshot at a time
+	snapshotPath string
+	segment      *walSegment
+	sequence     uint64 // sequence of last appended record
+	metrics      map[string]models.Metric
+	zapLogger    *zap.SugaredLogger
+}
+
+// NewWALMetricStorer restores metrics from snapshot and WAL segments next to it, and starts new segment.
+func NewWALMetricStorer(snapshotPath string, logger *zap.SugaredLogger) (*WALMetricStorerImpl, error) {
+	restored, err := restoreSnapshot(snapshotPath, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer := &WALMetricStorerImpl{
+		snapshotPath: snapshotPath,
+		sequence:     restored.Sequence,
+		metrics:      restored.Metrics,
+		zapLogger:    logger,
+	}
+
+	paths, numbers, err := walSegments(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	replayed := 0
+	for _, path := range paths {
+		size, err := replayWALSegment(path, func(record walRecord) error {
+			if record.Sequence <= storer.sequence {
+				return nil
+			}
+			storer.sequence = record.Sequence
+			replayed++
+			updated, err := storer.merge(record.Metrics)
+			if err != nil {
+				logger.Errorw("skipped WAL record", "file", path, "sequence", record.Sequence, "error", err.Error())
+				return nil
+			}
+			for key, metric := range updated {
+				storer.metrics[key] = metric
+			}
+			return nil
+		})
+		if err != nil {
+			logError(logger, "failed to replay WAL", path, err)
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && info.Size() > size {
+			logger.Infow("ignored torn WAL tail", "file", path, "offset", size, "bytes", info.Size()-size)
+		}
+	}
+	logger.Infow("restored metrics from WAL", "file", snapshotPath, "segments", len(paths), "records", replayed, "sequence", storer.sequence)
+
+	var number uint64
+	if len(numbers) > 0 {
+		number = numbers[len(numbers)-1] + 1
+	}
+	if storer.segment, err = createWALSegment(snapshotPath, number); err != nil {
+		logError(logger, "failed to create WAL segment", snapshotPath, err)
+		return nil, err
+	}
+	return storer, nil
+}
+
+// merge returns metrics changed by batch, stored metrics are not modified.
+func (s *WALMetricStorerImpl) merge(batch []models.Metric) (map[string]models.Metric, error) {
+	updated := make(map[string]models.Metric, len(batch))
+	for _, metric := range batch {
+		key := metric.Key()
+		if _, ok := updated[key]; !ok {
+			if existing, ok := s.metrics[key]; ok {
+				updated[key] = existing
+			}
+		}
+		if _, err := helpers.UpdateMetricInMap(updated, metric, s.zapLogger); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// store appends batch to WAL and applies it, batch that can't be applied is not logged.
+func (s *WALMetricStorerImpl) store(batch []models.Metric) error {
+	updated, err := s.merge(batch)
+	if err != nil {
+		return err
+	}
+
+	record := walRecord{Sequence: s.sequence + 1, Metrics: batch}
+	if err := s.segment.Append(record); err != nil {
+		logError(s.zapLogger, "failed to append WAL record", s.snapshotPath, err)
+		return err
+	}
+	s.sequence = record.Sequence
+	for key, metric := range updated {
+		s.metrics[key] = metric
+	}
+	return nil
+}
+
+func (s *WALMetricStorerImpl) StoreSingle(_ context.Context, newMetric models.Metric) (*models.Metric, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	valid := helpers.ValidateMetric(newMetric)
+	if !valid {
+		return &models.Metric{}, fmt.Errorf("invalide metric")
+	}
+
+	if err := s.store([]models.Metric{newMetric}); err != nil {
+		return nil, err
+	}
+
+	updatedMetric := s.metrics[newMetric.Key()]
+	helpers.LogMetric("new stored Metric", updatedMetric, s.zapLogger)
+	return &updatedMetric, nil
+}
+
+func (s *WALMetricStorerImpl) StoreSlice(_ context.Context, newMetrics []models.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(newMetrics) == 0 {
+		return nil
+	}
+	return s.store(newMetrics)
+}
+
+func (s *WALMetricStorerImpl) Get(_ context.Context, key string) (models.Metric, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metric, found := s.metrics[key]
+
+	return metric, found, nil
+}
+
+func (s *WALMetricStorerImpl) All(_ context.Context) (map[string]models.Metric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metricsCopy := make(map[string]models.Metric, len(s.metrics))
+	for k, v := range s.metrics {
+		metricsCopy[k] = v
+	}
+
+	return metricsCopy, nil
+}
+
+func (s *WALMetricStorerImpl) Ping() error {
+	return nil
+}
+
+// Compact writes snapshot of stored metrics and removes WAL segments it covers.
+// Writes are blocked only while new segment is started.
+func (s *WALMetricStorerImpl) Compact() error {
+	s.compactMu.Lock()
+	defer s.compactMu.Unlock()
+
+	s.mu.Lock()
+	segment, err := createWALSegment(s.snapshotPath, s.segment.number+1)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	previous := s.segment
+	s.segment = segment
+	state := snapshot{Sequence: s.sequence, Metrics: make(map[string]models.Metric, len(s.metrics))}
+	for k, v := range s.metrics {
+		state.Metrics[k] = v
+	}
+	s.mu.Unlock()
+
+	if err := previous.Close(); err != nil {
+		logError(s.zapLogger, "failed to close WAL segment", s.snapshotPath, err)
+	}
+
+	if err := writeSnapshot(s.snapshotPath, state, 1); err != nil {
+		logError(s.zapLogger, "failed to write snapshot", s.snapshotPath, err)
+		return err
+	}
+
+	paths, numbers, err := walSegments(s.snapshotPath)
+	if err != nil {
+		return err
+	}
+	var removeErr error
+	for i, path := range paths {
+		if numbers[i] < segment.number {
+			removeErr = errors.Join(removeErr, os.Remove(path))
+		}
+	}
+	if removeErr != nil {
+		logError(s.zapLogger, "failed to remove WAL segments", s.snapshotPath, removeErr)
+		return removeErr
+	}
+
+	s.zapLogger.Infow("compacted WAL", "file", s.snapshotPath, "metrics", len(state.Metrics), "sequence", state.Sequence)
+	return nil
+}
+
+// Close compacts WAL and closes current segment.
+func (s *WALMetricStorerImpl) Close() error {
+	compactErr := s.Compact()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(compactErr, s.segment.Close())
+}
+
+// StartCompaction starts goroutine that's periodically compacts WAL until ctx is done.
+// Storer is not closed by it, Close compacts WAL last time.
+func StartCompaction(ctx context.Context, storer *WALMetricStorerImpl, logger *zap.SugaredLogger, interval time.Duration) {
+	if interval <= 0 {
+		logger.Infow("skip periodical WAL compaction", "file", storer.snapshotPath, "interval", interval)
+		return
+	}
+
+	compactTicker := time.NewTicker(interval)
+
+	go func() {
+		defer compactTicker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-compactTicker.C:
+				if err := storer.Compact(); err != nil {
+					logger.Errorw("failed to compact WAL", "file", storer.snapshotPath, "error", err.Error())
+				}
+			}
+		}
+	}()
+}
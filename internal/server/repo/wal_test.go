@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+func counter(id string, delta int64) models.Metric {
+	return models.Metric{ID: id, MType: constants.Counter, Delta: &delta}
+}
+
+func gauge(id string, value float64) models.Metric {
+	return models.Metric{ID: id, MType: constants.Gauge, Value: &value}
+}
+
+func storeWAL(t *testing.T, storer *WALMetricStorerImpl, metrics ...models.Metric) {
+	t.Helper()
+	require.NoError(t, storer.StoreSlice(context.Background(), metrics))
+}
+
+func requireDelta(t *testing.T, storer *WALMetricStorerImpl, id string, want int64) {
+	t.Helper()
+	metric, found, err := storer.Get(context.Background(), counter(id, 0).Key())
+	require.NoError(t, err)
+	require.True(t, found, "metric %s", id)
+	assert.Equal(t, want, *metric.Delta, "metric %s", id)
+}
+
+func TestWALMetricStorerRecovery(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storer, err := NewWALMetricStorer(path, logger)
+	require.NoError(t, err)
+	storeWAL(t, storer, counter("requests", 1), gauge("temp", 1.5))
+	storeWAL(t, storer, counter("requests", 2))
+	_, err = storer.StoreSingle(context.Background(), counter("requests", 3))
+	require.NoError(t, err)
+
+	// Batch that can't be applied is not logged.
+	assert.Error(t, storer.StoreSlice(context.Background(), []models.Metric{counter("requests", 10), {ID: "bad", MType: "bad"}}))
+	requireDelta(t, storer, "requests", 6)
+
+	// Storer is not closed, as if server crashed.
+	restored, err := NewWALMetricStorer(path, logger)
+	require.NoError(t, err)
+	requireDelta(t, restored, "requests", 6)
+	metric, found, err := restored.Get(context.Background(), gauge("temp", 0).Key())
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 1.5, *metric.Value)
+
+	// New records go to new segment after replayed ones.
+	storeWAL(t, restored, counter("requests", 4))
+	restored, err = NewWALMetricStorer(path, logger)
+	require.NoError(t, err)
+	requireDelta(t, restored, "requests", 10)
+}
+
+func TestWALMetricStorerTornTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(content []byte, last int) []byte
+		want    int64
+	}{
+		{name: "partial record", corrupt: func(content []byte, last int) []byte { return content[:len(content)-3] }, want: 1},
+		{name: "partial header", corrupt: func(content []byte, last int) []byte { return content[:last+5] }, want: 1},
+		{name: "bad checksum", corrupt: func(content []byte, last int) []byte {
+			content[len(content)-2] ^= 0xff
+			return content
+		}, want: 1},
+		{name: "garbage", corrupt: func(content []byte, last int) []byte {
+			return append(content, 0xff, 0xff, 0xff, 0xff, 1, 2, 3, 4, 5)
+		}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zaptest.NewLogger(t).Sugar()
+			path := filepath.Join(t.TempDir(), "metrics.json")
+
+			storer, err := NewWALMetricStorer(path, logger)
+			require.NoError(t, err)
+			storeWAL(t, storer, counter("requests", 1))
+			last := int(storer.segment.size)
+			storeWAL(t, storer, counter("requests", 2))
+
+			segment := walSegmentPath(path, storer.segment.number)
+			content, err := os.ReadFile(segment)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(segment, tt.corrupt(content, last), 0666))
+
+			restored, err := NewWALMetricStorer(path, logger)
+			require.NoError(t, err)
+			requireDelta(t, restored, "requests", tt.want)
+
+			// Records written after recovery are not hidden by torn tail.
+			storeWAL(t, restored, counter("requests", 5))
+			restored, err = NewWALMetricStorer(path, logger)
+			require.NoError(t, err)
+			requireDelta(t, restored, "requests", tt.want+5)
+		})
+	}
+}
+
+func TestWALMetricStorerCompact(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storer, err := NewWALMetricStorer(path, logger)
+	require.NoError(t, err)
+	storeWAL(t, storer, counter("requests", 1), counter("errors", 1))
+	storeWAL(t, storer, counter("requests", 2))
+
+	// Segment left by crash after snapshot is written is skipped by sequence.
+	segment := walSegmentPath(path, storer.segment.number)
+	content, err := os.ReadFile(segment)
+	require.NoError(t, err)
+
+	require.NoError(t, storer.Compact())
+	segments, _, err := walSegments(path)
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+
+	storeWAL(t, storer, counter("requests", 4))
+	require.NoError(t, os.WriteFile(segment, content, 0666))
+
+	restored, err := NewWALMetricStorer(path, logger)
+	require.NoError(t, err)
+	requireDelta(t, restored, "requests", 7)
+	requireDelta(t, restored, "errors", 1)
+
+	require.NoError(t, restored.Close())
+	restored, err = NewWALMetricStorer(path, logger)
+	require.NoError(t, err)
+	requireDelta(t, restored, "requests", 7)
+}
+
+func TestWALMetricStorerPlainSnapshot(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	metric := counter("requests", 5)
+	require.NoError(t, RewriteFile(path, map[string]models.Metric{metric.Key(): metric}, logger))
+
+	storer, err := NewWALMetricStorer(path, logger)
+	require.NoError(t, err)
+	requireDelta(t, storer, "requests", 5)
+	storeWAL(t, storer, counter("requests", 1))
+	requireDelta(t, storer, "requests", 6)
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"crypto/rsa"
+
+	"github.com/VOTONO/go-metrics/internal/auth"
+	"github.com/VOTONO/go-metrics/internal/server/alerting"
+	"github.com/VOTONO/go-metrics/internal/subnet"
+)
+
+// Option configures optional parts of the router.
+type Option func(*options)
+
+type options struct {
+	alerts        *alerting.Engine
+	trustedSubnet *subnet.Checker
+	privateKey    *rsa.PrivateKey
+	keyring       *auth.Keyring
+	signature     auth.SignatureConfig
+}
+
+// WithAlerts registers alerts endpoint backed by given engine.
+func WithAlerts(engine *alerting.Engine) Option {
+	return func(o *options) {
+		o.alerts = engine
+	}
+}
+
+// WithTrustedSubnet rejects writes from agents outside of checker subnets. Nil checker allows any agent.
+func WithTrustedSubnet(checker *subnet.Checker) Option {
+	return func(o *options) {
+		o.trustedSubnet = checker
+	}
+}
+
+// WithDecryption makes router accept request bodies encrypted for key, see encryption.Decrypter.
+func WithDecryption(key *rsa.PrivateKey) Option {
+	return func(o *options) {
+		o.privateKey = key
+	}
+}
+
+// WithKeyring makes router verify and sign bodies with keyring instead of secret key, see auth.Keyring.
+func WithKeyring(keyring *auth.Keyring) Option {
+	return func(o *options) {
+		o.keyring = keyring
+	}
+}
+
+// WithSignatureConfig sets verification of request signatures, auth.DefaultSignatureConfig is used by default.
+func WithSignatureConfig(config auth.SignatureConfig) Option {
+	return func(o *options) {
+		o.signature = config
+	}
+}
@@ -11,28 +11,67 @@ import (
 
 	"github.com/VOTONO/go-metrics/internal/auth"
 	"github.com/VOTONO/go-metrics/internal/compressor"
+	"github.com/VOTONO/go-metrics/internal/encryption"
 	"github.com/VOTONO/go-metrics/internal/logger"
+	"github.com/VOTONO/go-metrics/internal/mtls"
 	"github.com/VOTONO/go-metrics/internal/server/handlers"
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
 	"github.com/VOTONO/go-metrics/internal/server/repo"
 )
 
-func Router(s repo.MetricStorer, db *sql.DB, zap *zap.SugaredLogger, secretKey string) chi.Router {
+func Router(s repo.MetricStorer, db *sql.DB, zap *zap.SugaredLogger, secretKey string, opts ...Option) chi.Router {
+	o := options{signature: auth.DefaultSignatureConfig}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	keyring := o.keyring
+	if keyring == nil {
+		keyring = auth.NewKeyring(secretKey)
+	}
+
 	router := chi.NewRouter()
 
 	router.Use(middleware.Recoverer)
-	router.Use(auth.HashChecker(secretKey))
+	router.Use(mtls.Middleware)
+	// Body is decrypted first, because agent signs and compresses it before encryption.
+	router.Use(encryption.Decrypter(o.privateKey))
+	router.Use(auth.SignatureChecker(keyring, o.signature))
 	router.Use(compressor.Compressor)
 	router.Use(compressor.Decompressor)
-	router.Use(auth.HashSigner(secretKey))
+	router.Use(auth.KeyringHashSigner(keyring))
 
 	// Your existing application routes
 	router.Get("/", logger.WithLogger(handlers.AllValueHandler(s, zap), zap))
 	router.Get("/ping", logger.WithLogger(handlers.Ping(db), zap))
-	router.Post("/update/", logger.WithLogger(handlers.UpdateHandlerJSON(s), zap))
-	router.Post("/updates/", logger.WithLogger(handlers.BatchUpdateHandler(s), zap))
 	router.Post("/value/", logger.WithLogger(handlers.ValueHandlerJSON(s), zap))
-	router.Post("/update/{metricType}/{metricName}/{metricValue}", logger.WithLogger(handlers.UpdateHandler(s), zap))
 	router.Get("/value/{metricType}/{metricName}", handlers.ValueHandler(s))
+	router.Get("/metrics", logger.WithLogger(handlers.MetricsHandler(s, zap), zap))
+
+	// Endpoints that change metrics are available only for trusted agents, and only for signed requests in strict mode.
+	router.Group(func(router chi.Router) {
+		if o.trustedSubnet != nil {
+			router.Use(o.trustedSubnet.Middleware)
+		}
+		if o.signature.Strict {
+			router.Use(auth.RequireSignature)
+		}
+
+		router.Post("/update/", logger.WithLogger(handlers.UpdateHandlerJSON(s), zap))
+		router.Post("/updates/", logger.WithLogger(handlers.BatchUpdateHandler(s), zap))
+		router.Post("/update/{metricType}/{metricName}/{metricValue}", logger.WithLogger(handlers.UpdateHandler(s), zap))
+		router.Post("/api/v1/write", logger.WithLogger(handlers.RemoteWriteHandler(s, ingest.NewCumulativeConverter()), zap))
+		router.Post("/write", logger.WithLogger(handlers.InfluxWriteHandler(s), zap))
+		router.Post("/v1/metrics", logger.WithLogger(handlers.OTLPMetricsHandler(s, ingest.NewCumulativeConverter(), ingest.NewDeltaAccumulator()), zap))
+	})
+
+	if historyStorer, ok := s.(repo.HistoryStorer); ok {
+		router.Get("/history/{metricType}/{metricName}", logger.WithLogger(handlers.HistoryHandler(historyStorer), zap))
+	}
+
+	if o.alerts != nil {
+		router.Get("/alerts", logger.WithLogger(handlers.AlertsHandler(o.alerts), zap))
+	}
 
 	// Mount pprof routes directly
 	router.Mount("/debug/pprof/", http.DefaultServeMux)
@@ -0,0 +1,289 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+// MalformedLinesMetric is a counter of lines that couldn't be parsed.
+const MalformedLinesMetric = "statsd_malformed_lines"
+
+const maxPacketSize = 65535
+
+// window aggregates lines received between flushes.
+type window struct {
+	counters  map[string]*counter
+	gauges    map[string]*gauge
+	timers    map[string]models.Metric
+	malformed int64
+}
+
+type counter struct {
+	metric models.Metric
+	sum    float64 // increments divided by sample rate
+}
+
+type gauge struct {
+	metric   models.Metric
+	value    float64
+	absolute bool // false if window has only relative changes, so value is added to stored gauge
+}
+
+func newWindow() *window {
+	return &window{
+		counters: make(map[string]*counter),
+		gauges:   make(map[string]*gauge),
+		timers:   make(map[string]models.Metric),
+	}
+}
+
+// merge adds lines of newer window to w and returns w.
+func (w *window) merge(newer *window) *window {
+	for key, c := range newer.counters {
+		if old, found := w.counters[key]; found {
+			old.sum += c.sum
+		} else {
+			w.counters[key] = c
+		}
+	}
+	for key, g := range newer.gauges {
+		if old, found := w.gauges[key]; found && !g.absolute {
+			old.value += g.value
+		} else {
+			w.gauges[key] = g
+		}
+	}
+	for key, timer := range newer.timers {
+		old, found := w.timers[key]
+		if !found {
+			w.timers[key] = timer
+			continue
+		}
+		// Sketches of windows have the same accuracy, so merge doesn't fail.
+		if merged, err := old.Summary.Merge(timer.Summary); err == nil {
+			old.Summary = merged
+			w.timers[key] = old
+		}
+	}
+	w.malformed += newer.malformed
+	return w
+}
+
+// Listener receives StatsD packets and periodically writes aggregated metrics to storer.
+// Counters are summed, gauges are last-write-wins, timers are stored as summary metrics.
+// Fraction of counter sum that doesn't make a whole increment is carried to the next flush.
+type Listener struct {
+	mu         sync.Mutex
+	address    string
+	storer     repo.MetricStorer
+	logger     *zap.SugaredLogger
+	interval   time.Duration
+	window     *window
+	increments *ingest.DeltaAccumulator
+	conn       net.PacketConn
+}
+
+// NewListener creates listener for UDP address. Flush interval is in seconds.
+func NewListener(address string, storer repo.MetricStorer, logger *zap.SugaredLogger, flushInterval int) *Listener {
+	return &Listener{
+		address:    address,
+		storer:     storer,
+		logger:     logger,
+		interval:   time.Duration(flushInterval) * time.Second,
+		window:     newWindow(),
+		increments: ingest.NewDeltaAccumulator(),
+	}
+}
+
+// Start binds UDP socket and starts goroutines that read packets and flush aggregated metrics until ctx is done.
+// Metrics received before ctx is done are flushed once more on stop.
+func (l *Listener) Start(ctx context.Context) error {
+	if l.interval <= 0 {
+		return errors.New("statsd flush interval must be positive")
+	}
+
+	conn, err := net.ListenPacket("udp", l.address)
+	if err != nil {
+		return err
+	}
+	l.conn = conn
+	l.logger.Infow("statsd listener started", "address", conn.LocalAddr().String(), "interval", l.interval)
+
+	go l.read()
+
+	ticker := time.NewTicker(l.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				l.flushAndLog(flushCtx)
+				cancel()
+				return
+			case <-ticker.C:
+				l.flushAndLog(ctx)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns address listener is bound to.
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+func (l *Listener) read() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			l.logger.Errorw("failed to read statsd packet", "error", err.Error())
+			continue
+		}
+		l.Handle(buf[:n])
+	}
+}
+
+// Handle adds all lines of packet to current window.
+func (l *Listener) Handle(packet []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, raw := range strings.Split(string(packet), "\n") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		line, err := ParseLine(raw)
+		if err != nil {
+			l.window.malformed++
+			l.logger.Debugw("malformed statsd line", "line", raw, "error", err.Error())
+			continue
+		}
+		l.add(line)
+	}
+}
+
+func (l *Listener) add(line Line) {
+	switch line.Type {
+	case TypeCounter:
+		metric := models.Metric{ID: line.Name, MType: constants.Counter, Labels: line.Labels}
+		c, found := l.window.counters[metric.Key()]
+		if !found {
+			c = &counter{metric: metric}
+			l.window.counters[metric.Key()] = c
+		}
+		c.sum += line.Value / line.Rate
+	case TypeGauge:
+		metric := models.Metric{ID: line.Name, MType: constants.Gauge, Labels: line.Labels}
+		g, found := l.window.gauges[metric.Key()]
+		if !found {
+			g = &gauge{metric: metric}
+			l.window.gauges[metric.Key()] = g
+		}
+		if line.Relative {
+			g.value += line.Value
+		} else {
+			g.value = line.Value
+			g.absolute = true
+		}
+	case TypeTimer, TypeHisto:
+		metric := models.Metric{ID: line.Name, MType: constants.Summary, Labels: line.Labels}
+		if stored, found := l.window.timers[metric.Key()]; found {
+			metric = stored
+		} else {
+			metric.Summary = models.NewSketch(models.DefaultSketchAccuracy)
+		}
+		// Sampled value stands for 1/rate values, like increment of counter.
+		metric.Summary.ObserveN(line.Value, uint64(math.Max(1, math.Round(1/line.Rate))))
+		l.window.timers[metric.Key()] = metric
+	}
+}
+
+// Flush stores metrics aggregated since previous flush. If storing fails, metrics are kept
+// for the next flush together with lines received meanwhile.
+func (l *Listener) Flush(ctx context.Context) error {
+	l.mu.Lock()
+	w := l.window
+	l.window = newWindow()
+	l.mu.Unlock()
+
+	if err := l.store(ctx, w); err != nil {
+		l.mu.Lock()
+		l.window = w.merge(l.window)
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// store writes metrics of window to storer in one batch.
+func (l *Listener) store(ctx context.Context, w *window) error {
+	metrics := make([]models.Metric, 0, len(w.counters)+len(w.gauges)+len(w.timers)+1)
+	points := make([]ingest.DeltaPoint, 0, len(w.counters))
+	for key, c := range w.counters {
+		points = append(points, ingest.DeltaPoint{Key: key, Value: c.sum})
+		metrics = append(metrics, c.metric)
+	}
+	increments, commit := l.increments.Increments(points)
+	for i := range points {
+		delta := increments[i]
+		metrics[i].Delta = &delta
+	}
+	for key, g := range w.gauges {
+		value := g.value
+		if !g.absolute {
+			stored, found, err := l.storer.Get(ctx, key)
+			if err != nil {
+				return err
+			}
+			if found && stored.Value != nil {
+				value += *stored.Value
+			}
+		}
+		g.metric.Value = &value
+		metrics = append(metrics, g.metric)
+	}
+	for _, timer := range w.timers {
+		metrics = append(metrics, timer)
+	}
+	if w.malformed > 0 {
+		malformed := w.malformed
+		metrics = append(metrics, models.Metric{ID: MalformedLinesMetric, MType: constants.Counter, Delta: &malformed})
+	}
+
+	if len(metrics) == 0 {
+		return nil
+	}
+	if err := l.storer.StoreSlice(ctx, metrics); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+func (l *Listener) flushAndLog(ctx context.Context) {
+	if err := l.Flush(ctx); err != nil {
+		l.logger.Errorw("failed to flush statsd metrics", "error", err.Error())
+	}
+}
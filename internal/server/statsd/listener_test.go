@@ -0,0 +1,140 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/mocks"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+func TestListenerFlush(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+
+	stored := 10.0
+	_, err := storer.StoreSingle(ctx, models.Metric{ID: "queue", MType: constants.Gauge, Value: &stored})
+	require.NoError(t, err)
+
+	listener := NewListener("", storer, logger, 10)
+	listener.Handle([]byte("requests:1|c\nrequests:1|c|@0.5\ntemp:20|g\ntemp:25|g\nqueue:+3|g\nqueue:-1|g\nlatency:10|ms\nlatency:30|ms\nlatency:20|ms|@0.25\nbroken\nusers:1|s"))
+	require.NoError(t, listener.Flush(ctx))
+
+	requests, found, err := storer.Get(ctx, models.MetricKey(constants.Counter, "requests", nil))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, int64(3), *requests.Delta)
+
+	temp, found, err := storer.Get(ctx, models.MetricKey(constants.Gauge, "temp", nil))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 25.0, *temp.Value)
+
+	queue, found, err := storer.Get(ctx, models.MetricKey(constants.Gauge, "queue", nil))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 12.0, *queue.Value)
+
+	latency, found, err := storer.Get(ctx, models.MetricKey(constants.Summary, "latency", nil))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, uint64(6), latency.Summary.Count)
+
+	malformed, found, err := storer.Get(ctx, models.MetricKey(constants.Counter, MalformedLinesMetric, nil))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, int64(2), *malformed.Delta)
+
+	// Counters of the next window are added to stored value.
+	listener.Handle([]byte("requests:2|c"))
+	require.NoError(t, listener.Flush(ctx))
+
+	requests, _, err = storer.Get(ctx, models.MetricKey(constants.Counter, "requests", nil))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *requests.Delta)
+}
+
+func TestListenerFractionalCounters(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+	listener := NewListener("", storer, logger, 10)
+	key := models.MetricKey(constants.Counter, "requests", nil)
+
+	// Sum of window below one is carried to the next flush instead of being rounded away.
+	for _, want := range []int64{0, 0, 1, 1, 2} {
+		listener.Handle([]byte("requests:0.4|c"))
+		require.NoError(t, listener.Flush(ctx))
+
+		requests, found, err := storer.Get(ctx, key)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, want, *requests.Delta)
+	}
+}
+
+func TestListenerFlushFailure(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storer := mocks.NewMockMetricStorer(ctrl)
+	listener := NewListener("", storer, zaptest.NewLogger(t).Sugar(), 10)
+
+	listener.Handle([]byte("requests:1|c\ntemp:20|g\nlatency:10|ms\nbroken"))
+	storer.EXPECT().StoreSlice(gomock.Any(), gomock.Any()).Return(errors.New("storage is down"))
+	require.Error(t, listener.Flush(ctx))
+
+	// Lines received after failed flush are stored together with the failed window.
+	listener.Handle([]byte("requests:2|c\ntemp:25|g\nlatency:30|ms"))
+	var stored []models.Metric
+	storer.EXPECT().StoreSlice(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, metrics []models.Metric) error {
+		stored = metrics
+		return nil
+	})
+	require.NoError(t, listener.Flush(ctx))
+
+	byKey := make(map[string]models.Metric, len(stored))
+	for _, metric := range stored {
+		byKey[metric.Key()] = metric
+	}
+	require.Len(t, byKey, 4)
+	assert.Equal(t, int64(3), *byKey[models.MetricKey(constants.Counter, "requests", nil)].Delta)
+	assert.Equal(t, 25.0, *byKey[models.MetricKey(constants.Gauge, "temp", nil)].Value)
+	assert.Equal(t, uint64(2), byKey[models.MetricKey(constants.Summary, "latency", nil)].Summary.Count)
+	assert.Equal(t, int64(1), *byKey[models.MetricKey(constants.Counter, MalformedLinesMetric, nil)].Delta)
+}
+
+func TestListenerUDP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+
+	listener := NewListener("127.0.0.1:0", storer, logger, 1)
+	require.NoError(t, listener.Start(ctx))
+
+	conn, err := net.Dial("udp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("requests:4|c|#host:a"))
+	require.NoError(t, err)
+
+	key := models.MetricKey(constants.Counter, "requests", map[string]string{"host": "a"})
+	assert.Eventually(t, func() bool {
+		metric, found, err := storer.Get(ctx, key)
+		return err == nil && found && *metric.Delta == 4
+	}, 3*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,100 @@
+// Package statsd receives metrics in StatsD line protocol over UDP and stores them in aggregated form.
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+)
+
+// StatsD metric types.
+const (
+	TypeCounter = "c"
+	TypeGauge   = "g"
+	TypeTimer   = "ms"
+	TypeHisto   = "h" // alias of timer
+)
+
+// Line is a single parsed StatsD line, e.g. "api.requests:1|c|@0.1|#host:a".
+type Line struct {
+	Name     string
+	Type     string
+	Value    float64
+	Relative bool    // gauge value is "+N" or "-N" and changes current value
+	Rate     float64 // sample rate in (0, 1]
+	Labels   map[string]string
+}
+
+// ParseLine parses line "name:value|type[|@rate][|#tag:value,...]". Tags are DogStatsD extension and become labels.
+func ParseLine(line string) (Line, error) {
+	name, rest, found := strings.Cut(line, ":")
+	if !found || name == "" {
+		return Line{}, errors.New("missing metric name")
+	}
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return Line{}, errors.New("missing metric type")
+	}
+
+	parsed := Line{Name: name, Type: parts[1], Rate: 1}
+	switch parsed.Type {
+	case TypeCounter, TypeGauge, TypeTimer, TypeHisto:
+	default:
+		return Line{}, fmt.Errorf("unsupported metric type %q", parsed.Type)
+	}
+
+	rawValue := parts[0]
+	if parsed.Type == TypeGauge && (strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-")) {
+		parsed.Relative = true
+	}
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return Line{}, fmt.Errorf("invalid value %q", rawValue)
+	}
+	parsed.Value = value
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, err := strconv.ParseFloat(part[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return Line{}, fmt.Errorf("invalid sample rate %q", part)
+			}
+			parsed.Rate = rate
+		case strings.HasPrefix(part, "#"):
+			labels, err := parseTags(part[1:])
+			if err != nil {
+				return Line{}, err
+			}
+			parsed.Labels = labels
+		default:
+			return Line{}, fmt.Errorf("unknown section %q", part)
+		}
+	}
+
+	return parsed, nil
+}
+
+// parseTags parses comma separated "name:value" tags. Tag without value gets empty value.
+func parseTags(tags string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, tag := range strings.Split(tags, ",") {
+		if tag == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(tag, ":")
+		if !models.ValidLabelName(name) {
+			return nil, fmt.Errorf("invalid tag name %q", name)
+		}
+		labels[name] = value
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
@@ -0,0 +1,56 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Line
+		wantErr bool
+	}{
+		{
+			name: "counter",
+			line: "requests:3|c",
+			want: Line{Name: "requests", Type: TypeCounter, Value: 3, Rate: 1},
+		},
+		{
+			name: "counter with rate and tags",
+			line: "requests:1|c|@0.5|#host:a,env:prod",
+			want: Line{Name: "requests", Type: TypeCounter, Value: 1, Rate: 0.5, Labels: map[string]string{"host": "a", "env": "prod"}},
+		},
+		{
+			name: "relative gauge",
+			line: "queue:-2|g",
+			want: Line{Name: "queue", Type: TypeGauge, Value: -2, Relative: true, Rate: 1},
+		},
+		{
+			name: "timer",
+			line: "latency:12.5|ms",
+			want: Line{Name: "latency", Type: TypeTimer, Value: 12.5, Rate: 1},
+		},
+		{name: "missing type", line: "requests:1", wantErr: true},
+		{name: "missing name", line: ":1|c", wantErr: true},
+		{name: "unsupported type", line: "users:1|s", wantErr: true},
+		{name: "invalid value", line: "requests:abc|c", wantErr: true},
+		{name: "invalid rate", line: "requests:1|c|@2", wantErr: true},
+		{name: "invalid tag name", line: "requests:1|c|#1host:a", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLine(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package tcpreceiver receives metrics in line-based formats over TCP connections.
+package tcpreceiver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+// maxBatchSize is the maximum number of metrics stored at once.
+const maxBatchSize = 1000
+
+// Receiver accepts TCP connections and decodes every received line with decoder.
+// Metrics are stored when connection has no more buffered data or batch is full.
+// Malformed lines are logged and skipped, because clients can't be notified about them.
+type Receiver struct {
+	address  string
+	decoder  ingest.Decoder
+	storer   repo.MetricStorer
+	logger   *zap.SugaredLogger
+	listener net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewReceiver creates receiver of TCP address.
+func NewReceiver(address string, decoder ingest.Decoder, storer repo.MetricStorer, logger *zap.SugaredLogger) *Receiver {
+	return &Receiver{
+		address: address,
+		decoder: decoder,
+		storer:  storer,
+		logger:  logger,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Start binds TCP socket and starts accepting connections until ctx is done.
+func (r *Receiver) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", r.address)
+	if err != nil {
+		return err
+	}
+	r.listener = listener
+	r.logger.Infow("tcp receiver started", "address", listener.Addr().String())
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for conn := range r.conns {
+			conn.Close()
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				r.logger.Errorw("failed to accept connection", "error", err.Error())
+				continue
+			}
+
+			r.mu.Lock()
+			r.conns[conn] = struct{}{}
+			r.mu.Unlock()
+
+			go r.serve(ctx, conn)
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns address receiver is bound to.
+func (r *Receiver) Addr() net.Addr {
+	return r.listener.Addr()
+}
+
+func (r *Receiver) serve(ctx context.Context, conn net.Conn) {
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, conn)
+		r.mu.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	var batch []models.Metric
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			metrics, err := r.decoder.Decode(line)
+			if err != nil {
+				r.logger.Errorw("malformed line", "remote", conn.RemoteAddr().String(), "line", line, "error", err.Error())
+			}
+			batch = append(batch, metrics...)
+		}
+
+		if readErr != nil || reader.Buffered() == 0 || len(batch) >= maxBatchSize {
+			r.store(ctx, batch)
+			batch = batch[:0]
+		}
+
+		if readErr != nil {
+			if !errors.Is(readErr, io.EOF) && !errors.Is(readErr, net.ErrClosed) {
+				r.logger.Errorw("failed to read connection", "remote", conn.RemoteAddr().String(), "error", readErr.Error())
+			}
+			return
+		}
+	}
+}
+
+func (r *Receiver) store(ctx context.Context, metrics []models.Metric) {
+	if len(metrics) == 0 {
+		return
+	}
+
+	// Metrics received before shutdown are still stored.
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err := r.storer.StoreSlice(storeCtx, metrics); err != nil {
+		r.logger.Errorw("failed to store metrics", "count", len(metrics), "error", err.Error())
+	}
+}
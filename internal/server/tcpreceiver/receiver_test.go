@@ -0,0 +1,43 @@
+package tcpreceiver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/VOTONO/go-metrics/internal/constants"
+	"github.com/VOTONO/go-metrics/internal/models"
+	"github.com/VOTONO/go-metrics/internal/server/ingest"
+	"github.com/VOTONO/go-metrics/internal/server/repo"
+)
+
+func TestReceiver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := zaptest.NewLogger(t).Sugar()
+	storer := repo.NewLocalMetricStorer(false, "", logger)
+
+	receiver := NewReceiver("127.0.0.1:0", ingest.GraphiteDecoder{}, storer, logger)
+	require.NoError(t, receiver.Start(ctx))
+
+	conn, err := net.Dial("tcp", receiver.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("servers.web1.load 1.5 1700000000\nmalformed\nservers.web1.up 1 1700000000\n"))
+	require.NoError(t, err)
+
+	for _, id := range []string{"servers.web1.load", "servers.web1.up"} {
+		key := models.MetricKey(constants.Gauge, id, nil)
+		assert.Eventually(t, func() bool {
+			_, found, err := storer.Get(ctx, key)
+			return err == nil && found
+		}, 3*time.Second, 20*time.Millisecond, id)
+	}
+}
@@ -0,0 +1,99 @@
+// Package subnet restricts requests to agents from trusted subnets.
+package subnet
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIPHeader is header where agent puts its own IP.
+const RealIPHeader = "X-Real-IP"
+
+// RealIPMetadataKey is gRPC metadata key where agent puts its own IP.
+const RealIPMetadataKey = "x-real-ip"
+
+// Sources of agent IP.
+const (
+	SourceHeader = "header" // RealIPHeader of request
+	SourcePeer   = "peer"   // address of connection, for agents connecting directly
+)
+
+// Checker decides whether agent IP belongs to trusted subnets.
+type Checker struct {
+	subnets []*net.IPNet
+	source  string
+}
+
+// NewChecker parses comma separated IPv4 and IPv6 CIDRs. Nil checker is returned for empty cidrs,
+// which means that there are no restrictions.
+func NewChecker(cidrs string, source string) (*Checker, error) {
+	if source != SourceHeader && source != SourcePeer {
+		return nil, fmt.Errorf("unknown IP source %q", source)
+	}
+
+	var subnets []*net.IPNet
+	for _, cidr := range strings.Split(cidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, subnet)
+	}
+
+	if len(subnets) == 0 {
+		return nil, nil
+	}
+	return &Checker{subnets: subnets, source: source}, nil
+}
+
+// Source returns where checker takes agent IP from.
+func (c *Checker) Source() string {
+	return c.source
+}
+
+// Trusted reports whether ip belongs to any of subnets. Invalid ip is not trusted.
+func (c *Checker) Trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, subnet := range c.subnets {
+		if subnet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects requests from untrusted agents with 403.
+func (c *Checker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.Trusted(c.requestIP(r)) {
+			http.Error(w, "agent is not in trusted subnet", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *Checker) requestIP(r *http.Request) string {
+	if c.source == SourceHeader {
+		return r.Header.Get(RealIPHeader)
+	}
+	return HostIP(r.RemoteAddr)
+}
+
+// HostIP returns host part of "host:port" address, or address itself if it has no port.
+func HostIP(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
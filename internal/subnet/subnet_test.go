@@ -0,0 +1,82 @@
+package subnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChecker(t *testing.T) {
+	checker, err := NewChecker("", SourceHeader)
+	require.NoError(t, err)
+	assert.Nil(t, checker)
+
+	_, err = NewChecker("10.0.0.0/33", SourceHeader)
+	assert.Error(t, err)
+
+	_, err = NewChecker("10.0.0.0/8", "cookie")
+	assert.Error(t, err)
+}
+
+func TestCheckerTrusted(t *testing.T) {
+	checker, err := NewChecker("10.0.0.0/8, 192.168.1.0/24,fd00::/8", SourceHeader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		ip      string
+		trusted bool
+	}{
+		{ip: "10.20.30.40", trusted: true},
+		{ip: "192.168.1.7", trusted: true},
+		{ip: "192.168.2.7", trusted: false},
+		{ip: "fd12::1", trusted: true},
+		{ip: "2001:db8::1", trusted: false},
+		{ip: "", trusted: false},
+		{ip: "not-ip", trusted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.trusted, checker.Trusted(tt.ip))
+		})
+	}
+}
+
+func TestCheckerMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name         string
+		source       string
+		realIP       string
+		remoteAddr   string
+		expectedCode int
+	}{
+		{name: "trusted header", source: SourceHeader, realIP: "10.0.0.1", remoteAddr: "8.8.8.8:1234", expectedCode: http.StatusOK},
+		{name: "untrusted header", source: SourceHeader, realIP: "8.8.8.8", remoteAddr: "10.0.0.1:1234", expectedCode: http.StatusForbidden},
+		{name: "missing header", source: SourceHeader, remoteAddr: "10.0.0.1:1234", expectedCode: http.StatusForbidden},
+		{name: "trusted peer", source: SourcePeer, realIP: "8.8.8.8", remoteAddr: "10.0.0.1:1234", expectedCode: http.StatusOK},
+		{name: "untrusted IPv6 peer", source: SourcePeer, remoteAddr: "[2001:db8::1]:1234", expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := NewChecker("10.0.0.0/8", tt.source)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/update/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set(RealIPHeader, tt.realIP)
+			}
+			res := httptest.NewRecorder()
+
+			checker.Middleware(next).ServeHTTP(res, req)
+
+			assert.Equal(t, tt.expectedCode, res.Code)
+		})
+	}
+}